@@ -0,0 +1,80 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+
+	"github.com/getground/tech-tasks/backend/internal/auth"
+	"github.com/getground/tech-tasks/backend/pkg/database"
+)
+
+func usage() {
+	fmt.Fprintf(flag.CommandLine.Output(), "Usage: %s [flags] create name admin|host|door | list | revoke id\n", os.Args[0])
+	flag.PrintDefaults()
+}
+
+func main() {
+	dsn := flag.String("dsn", "username:password@tcp(mysql:3306)/getground", "MySQL data source name")
+	flag.Usage = usage
+	flag.Parse()
+
+	if flag.NArg() < 1 {
+		usage()
+		os.Exit(2)
+	}
+
+	dbClient, err := database.NewClient(*dsn)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer dbClient.Close()
+
+	service := auth.NewService(dbClient)
+	ctx := context.Background()
+	switch flag.Arg(0) {
+	case "create":
+		if flag.NArg() != 3 {
+			usage()
+			os.Exit(2)
+		}
+		apiKey, key, err := service.CreateKey(ctx, flag.Arg(1), flag.Arg(2))
+		if err != nil {
+			log.Fatal(err)
+		}
+		log.Printf("Created %s key %d for %s, it won't be shown again", apiKey.Role, apiKey.ID, apiKey.Name)
+		fmt.Println(key)
+	case "list":
+		keys, err := service.GetKeys(ctx)
+		if err != nil {
+			log.Fatal(err)
+		}
+		for _, apiKey := range keys {
+			state := "active"
+			if apiKey.TimeRevoked != nil {
+				state = "revoked " + apiKey.TimeRevoked.Format("2006-01-02 15:04:05")
+			}
+			fmt.Printf("%d\t%s\t%s\t%s\t%s\n", apiKey.ID, apiKey.Name, apiKey.Role, apiKey.Prefix, state)
+		}
+	case "revoke":
+		if flag.NArg() != 2 {
+			usage()
+			os.Exit(2)
+		}
+		id, err := strconv.Atoi(flag.Arg(1))
+		if err != nil {
+			log.Fatalf("Invalid API key id %s", flag.Arg(1))
+		}
+		err = service.RevokeKey(ctx, id)
+		if err != nil {
+			log.Fatal(err)
+		}
+		log.Printf("Revoked API key %d", id)
+	default:
+		usage()
+		os.Exit(2)
+	}
+}
@@ -1,27 +1,149 @@
 package main
 
 import (
+	"context"
+	"flag"
+	"fmt"
 	"log"
+	"net"
 	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
+	"github.com/getground/tech-tasks/backend/internal/auth"
+	"github.com/getground/tech-tasks/backend/internal/entity"
 	"github.com/getground/tech-tasks/backend/internal/guest_list"
+	"github.com/getground/tech-tasks/backend/internal/webhook"
 	"github.com/getground/tech-tasks/backend/pkg/database"
 	_ "github.com/go-sql-driver/mysql"
 	"github.com/gorilla/mux"
 )
 
 func main() {
-	// Initiate DB
-	dbClient, err := database.NewClient("username:password@tcp(mysql:3306)/getground")
+	backend := flag.String("db", "mysql", "database backend, either mysql or memory")
+	dsn := flag.String("dsn", "username:password@tcp(mysql:3306)/getground", "MySQL data source name")
+	addr := flag.String("addr", ":3000", "HTTP listen address")
+	queryTimeout := flag.Duration("query-timeout", database.DefaultQueryTimeout, "default deadline for each DB query")
+	seating := flag.String("seating", guest_list.AssignBestFit, "strategy seating guests added without a table: best-fit, first-fit, keep-parties-together or fill-evenly")
+	eventLog := flag.String("event-log", "", "file to append the events of the guest list to as JSON lines")
+	requireAuth := flag.Bool("auth", true, "require an API key on every request, mint keys with the apikey command")
+	jwtKeys := flag.String("jwt-keys", "", "directory of the keys signing access tokens, named <kid>.hs256 or <kid>.pem, tokens are disabled when empty")
+	jwtKid := flag.String("jwt-kid", "", "kid of the key signing new access tokens, needed when -jwt-keys holds several")
+	tokenTTL := flag.Duration("token-ttl", auth.DefaultTokenTTL, "lifetime of access tokens")
+	shutdownTimeout := flag.Duration("shutdown-timeout", 10*time.Second, "time allowed for in-flight requests on shutdown")
+	flag.Parse()
+
+	tableAssigner, err := guest_list.TableAssignerByName(*seating)
 	if err != nil {
 		log.Fatal(err)
 	}
+
+	// Initiate DB
+	var dbClient database.Client
+	switch *backend {
+	case "mysql":
+		dbClient, err = database.NewClient(*dsn, database.WithQueryTimeout(*queryTimeout))
+		if err != nil {
+			log.Fatal(err)
+		}
+	case "memory":
+		log.Printf("Using in-memory database, data will be lost on exit")
+		dbClient = database.NewMemoryClient()
+	default:
+		log.Fatalf("Unknown database backend %s", *backend)
+	}
 	defer dbClient.Close()
 
+	// Cancelling baseCtx aborts the DB work of every in-flight request
+	baseCtx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// Deliver webhooks in the background until shutdown
+	webhookService := webhook.NewService(dbClient)
+	webhookWorker := webhook.NewWorker(dbClient)
+	workerCtx, stopWorker := context.WithCancel(context.Background())
+	defer stopWorker()
+	go webhookWorker.Run(workerCtx)
+
+	// Relay the events of the guest list to the live stream, the webhooks
+	// and optionally a log file
+	bus := guest_list.NewBus(guest_list.DefaultStreamHistory)
+	sinks := []guest_list.Sink{guest_list.BusSink(bus), webhook.NewSink(webhookService, webhookWorker)}
+	if *eventLog != "" {
+		file, err := os.OpenFile(*eventLog, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer file.Close()
+		sinks = append(sinks, guest_list.LogSink(file))
+	}
+	relay := guest_list.NewRelay(dbClient, sinks)
+	go relay.Run(workerCtx)
+
 	// Start server
 	r := mux.NewRouter()
-	guestListService := guest_list.NewGuestListService(dbClient)
+	guestListService := guest_list.NewGuestListService(dbClient,
+		guest_list.WithBus(bus),
+		guest_list.WithRelay(relay),
+		guest_list.WithTableAssigner(tableAssigner),
+		guest_list.WithPromotionHook(func(entry entity.WaitlistEntry) {
+			log.Printf("Promoted %s from the waitlist of event %d to table %d", entry.Name, entry.EventID, *entry.TableID)
+		}))
+	if *requireAuth {
+		authOpts := []auth.Option{auth.WithTokenTTL(*tokenTTL)}
+		if *jwtKeys != "" {
+			keySet, err := auth.LoadKeySet(*jwtKeys, *jwtKid)
+			if err != nil {
+				log.Fatal(err)
+			}
+			authOpts = append(authOpts, auth.WithKeySet(keySet))
+		}
+		authService := auth.NewService(dbClient, authOpts...)
+		if *backend == "memory" {
+			// The apikey command can't reach an in-memory database. The key
+			// is shown once on stderr and kept out of the log
+			_, key, err := authService.CreateKey(context.Background(), "admin", entity.RoleAdmin)
+			if err != nil {
+				log.Fatal(err)
+			}
+			fmt.Fprintf(os.Stderr, "DEV ONLY: admin API key of the in-memory database, shown once: %s\n", key)
+		}
+		r.Use(auth.Middleware(authService))
+		auth.RegisterHandlers(r, authService)
+	} else {
+		log.Printf("Authentication is disabled, anyone reaching %s may change the guest list", *addr)
+	}
+	r.Use(guest_list.ActorMiddleware)
 	guest_list.RegisterHandlers(r, guestListService)
-	http.Handle("/", r)
-	http.ListenAndServe(":3000", nil)
+	guest_list.RegisterDoorHandlers(r, guestListService)
+	webhook.RegisterHandlers(r, webhookService)
+
+	srv := &http.Server{
+		Addr:        *addr,
+		Handler:     r,
+		BaseContext: func(net.Listener) context.Context { return baseCtx },
+	}
+	// Shutdown waits for open streams, so end them first
+	srv.RegisterOnShutdown(bus.Close)
+
+	go func() {
+		if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			log.Fatal(err)
+		}
+	}()
+
+	// Wait for a termination signal and shut down gracefully
+	stop := make(chan os.Signal, 1)
+	signal.Notify(stop, os.Interrupt, syscall.SIGTERM)
+	<-stop
+
+	log.Printf("Shutting down server")
+	ctx, cancelShutdown := context.WithTimeout(context.Background(), *shutdownTimeout)
+	defer cancelShutdown()
+	if err := srv.Shutdown(ctx); err != nil {
+		log.Printf("Error %s when shutting down server", err)
+		cancel()
+	}
 }
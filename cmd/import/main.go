@@ -0,0 +1,91 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+
+	"github.com/getground/tech-tasks/backend/internal/entity"
+	"github.com/getground/tech-tasks/backend/internal/guest_list"
+	"github.com/getground/tech-tasks/backend/pkg/database"
+)
+
+func usage() {
+	fmt.Fprintf(flag.CommandLine.Output(), "Usage: %s [flags] file|-\n", os.Args[0])
+	flag.PrintDefaults()
+}
+
+func main() {
+	dsn := flag.String("dsn", "username:password@tcp(mysql:3306)/getground", "MySQL data source name")
+	event := flag.Int("event", entity.DefaultEventID, "id of the event to import the guests into")
+	mode := flag.String("mode", entity.ImportAtomic, "atomic adds nobody unless every row succeeds, best-effort keeps the rows that succeed")
+	format := flag.String("format", "", "csv or ndjson, guessed from the file extension when empty")
+	seating := flag.String("seating", guest_list.AssignBestFit, "strategy seating guests without a table")
+	flag.Usage = usage
+	flag.Parse()
+
+	if flag.NArg() != 1 {
+		usage()
+		os.Exit(2)
+	}
+
+	var input io.Reader = os.Stdin
+	if path := flag.Arg(0); path != "-" {
+		file, err := os.Open(path)
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer file.Close()
+		input = file
+
+		if *format == "" {
+			*format = guest_list.ImportNDJSON
+			if filepath.Ext(path) == ".csv" {
+				*format = guest_list.ImportCSV
+			}
+		}
+	}
+	if *format == "" {
+		log.Fatal("-format is required when reading from stdin")
+	}
+
+	tableAssigner, err := guest_list.TableAssignerByName(*seating)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	rows, err := guest_list.ParseImport(input, *format)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	dbClient, err := database.NewClient(*dsn)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer dbClient.Close()
+
+	// The outbox entries of the import are left for the server to relay to
+	// its sinks
+	service := guest_list.NewGuestListService(dbClient, guest_list.WithTableAssigner(tableAssigner))
+	result, err := service.ImportGuests(context.Background(), *event, rows, *mode)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	for _, row := range result.Rows {
+		if row.Error != nil {
+			fmt.Printf("line %d\t%s\terror: %s\n", row.Line, row.Name, *row.Error)
+		} else if row.Table != nil {
+			fmt.Printf("line %d\t%s\ttable %d\n", row.Line, row.Name, *row.Table)
+		}
+	}
+	log.Printf("Imported %d guests, %d rows failed", result.Imported, result.Failed)
+	if result.Failed > 0 {
+		os.Exit(1)
+	}
+}
@@ -5,6 +5,7 @@ go 1.16
 require (
 	github.com/go-sql-driver/mysql v1.6.0
 	github.com/gorilla/mux v1.8.0
-	github.com/jmoiron/sqlx v1.3.5 // indirect
-	github.com/stretchr/testify v1.8.4 // indirect
+	github.com/gorilla/websocket v1.5.0
+	github.com/jmoiron/sqlx v1.3.5
+	github.com/stretchr/testify v1.8.4
 )
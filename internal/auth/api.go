@@ -0,0 +1,68 @@
+package auth
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/getground/tech-tasks/backend/internal/entity"
+	"github.com/getground/tech-tasks/backend/pkg/problem"
+	"github.com/getground/tech-tasks/backend/pkg/validate"
+	"github.com/gorilla/mux"
+)
+
+// RegisterHandlers registers the routes issuing and revoking tokens. They
+// authenticate requests by their body, so Middleware lets them through.
+func RegisterHandlers(r *mux.Router, service Service) {
+	h := handler{service}
+	r.HandleFunc("/auth/token", h.createToken).Methods(http.MethodPost)
+	r.HandleFunc("/auth/revoke", h.revokeToken).Methods(http.MethodPost)
+}
+
+type handler struct {
+	service Service
+}
+
+func (h handler) createToken(w http.ResponseWriter, r *http.Request) {
+	var requestBody entity.CreateTokenRequestBody
+	err := validate.Decode(r, &requestBody)
+	if err != nil {
+		validate.Error(w, r, err)
+		return
+	}
+
+	var response *entity.CreateTokenResponseBody
+	switch requestBody.GrantType {
+	case entity.GrantAPIKey:
+		response, err = h.service.IssueToken(r.Context(), requestBody.APIKey)
+	case entity.GrantRefreshToken:
+		response, err = h.service.RefreshToken(r.Context(), requestBody.RefreshToken)
+	default:
+		problem.Error(w, r, http.StatusBadRequest, "grant_type must be either api_key or refresh_token")
+		return
+	}
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	json.NewEncoder(w).Encode(response)
+}
+
+func (h handler) revokeToken(w http.ResponseWriter, r *http.Request) {
+	var requestBody entity.RevokeTokenRequestBody
+	err := validate.Decode(r, &requestBody)
+	if err != nil {
+		validate.Error(w, r, err)
+		return
+	}
+
+	err = h.service.RevokeToken(r.Context(), requestBody.RefreshToken)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
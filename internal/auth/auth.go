@@ -0,0 +1,202 @@
+// Package auth authenticates the clients of the API with API keys or the
+// access tokens issued for them, and checks the routes their role may call.
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/getground/tech-tasks/backend/internal/entity"
+	"github.com/getground/tech-tasks/backend/pkg/database"
+)
+
+// keyPrefix starts every API key so leaked keys are easy to search for.
+const keyPrefix = "gl_"
+
+// Sizes in bytes of the random parts of a key, hex encoded in the key.
+const (
+	lookupSize = 6
+	secretSize = 24
+)
+
+// maxNameLength is the size of the name column, names become the actor of
+// audit entries.
+const maxNameLength = 255
+
+// maxKeyAttempts bounds the retries when a new key collides with the lookup
+// prefix of an existing one.
+const maxKeyAttempts = 3
+
+type Service interface {
+	CreateKey(ctx context.Context, name string, role string) (*entity.APIKey, string, error)
+	GetKeys(ctx context.Context) ([]entity.APIKey, error)
+	RevokeKey(ctx context.Context, id int) error
+	Authenticate(ctx context.Context, key string) (*entity.APIKey, error)
+	IssueToken(ctx context.Context, key string) (*entity.CreateTokenResponseBody, error)
+	RefreshToken(ctx context.Context, token string) (*entity.CreateTokenResponseBody, error)
+	RevokeToken(ctx context.Context, token string) error
+	ValidateToken(token string) (*entity.Claims, error)
+}
+
+// Clock tells the current time.
+type Clock interface {
+	Now() time.Time
+}
+
+type systemClock struct{}
+
+func (systemClock) Now() time.Time {
+	return time.Now()
+}
+
+type service struct {
+	dbClient   database.Client
+	clock      Clock
+	keys       *KeySet
+	tokenTTL   time.Duration
+	refreshTTL time.Duration
+}
+
+// Option configures the service returned by NewService.
+type Option func(*service)
+
+// WithClock makes the service read the current time from clock.
+func WithClock(clock Clock) Option {
+	return func(s *service) {
+		s.clock = clock
+	}
+}
+
+func NewService(dbClient database.Client, opts ...Option) Service {
+	s := &service{dbClient: dbClient, clock: systemClock{}, tokenTTL: DefaultTokenTTL, refreshTTL: DefaultRefreshTTL}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// now returns the current time at the precision stored in DATETIME columns.
+func (s *service) now() time.Time {
+	return s.clock.Now().UTC().Truncate(time.Second)
+}
+
+// CreateKey mints a key with role for the client called name. The key is
+// returned along with its record and can't be recovered afterwards.
+func (s *service) CreateKey(ctx context.Context, name string, role string) (*entity.APIKey, string, error) {
+	if name == "" || len(name) > maxNameLength {
+		return nil, "", newError(ErrInvalidName, "API key names must be 1 to %d bytes long", maxNameLength)
+	}
+	if !entity.Attributes(entity.Roles).Has(role) {
+		return nil, "", newError(ErrInvalidRole, "role must be one of %s", strings.Join(entity.Roles, ", "))
+	}
+
+	for attempt := 0; attempt < maxKeyAttempts; attempt++ {
+		prefix, key, err := generateKey()
+		if err != nil {
+			return nil, "", err
+		}
+
+		apiKey := entity.APIKey{Name: name, Role: role, Prefix: prefix, Hash: hashKey(key), TimeCreated: s.now()}
+		columns := []string{"name", "role", "prefix", "hash", "time_created"}
+		values := []interface{}{apiKey.Name, apiKey.Role, apiKey.Prefix, apiKey.Hash, apiKey.TimeCreated}
+		apiKey.ID, err = s.dbClient.Create(ctx, "api_key", columns, values...)
+		if errors.Is(err, database.ErrDuplicate) {
+			exists, err := s.dbClient.Exists(ctx, "api_key", database.By("name", name))
+			if err != nil {
+				return nil, "", err
+			}
+			if exists {
+				return nil, "", newError(ErrAPIKeyExists, "an API key called `%s` already exists", name)
+			}
+			continue
+		} else if err != nil {
+			return nil, "", err
+		}
+
+		return &apiKey, key, nil
+	}
+	return nil, "", fmt.Errorf("no unused API key prefix after %d attempts", maxKeyAttempts)
+}
+
+// generateKey returns a new key along with the prefix identifying it.
+func generateKey() (string, string, error) {
+	b := make([]byte, lookupSize+secretSize)
+	_, err := rand.Read(b)
+	if err != nil {
+		return "", "", err
+	}
+	prefix := hex.EncodeToString(b[:lookupSize])
+	return prefix, keyPrefix + prefix + "_" + hex.EncodeToString(b[lookupSize:]), nil
+}
+
+// hashKey returns the hex SHA-256 hash of key. Keys are long and random so a
+// slow password hash would add nothing.
+func hashKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+// GetKeys returns every key, revoked ones included, in creation order.
+func (s *service) GetKeys(ctx context.Context) ([]entity.APIKey, error) {
+	keys := []entity.APIKey{}
+	query := database.Query{}.OrderBy(database.Asc("id"))
+	err := s.dbClient.FindMany(ctx, &keys, "api_key", query)
+	if err != nil {
+		return nil, err
+	}
+
+	return keys, nil
+}
+
+// RevokeKey stops the key with the given id from authenticating. The record
+// is kept so the audit log can still be traced back to it.
+func (s *service) RevokeKey(ctx context.Context, id int) error {
+	return s.dbClient.Transaction(ctx, func(tx database.Tx) error {
+		var apiKey entity.APIKey
+		err := tx.FindUniqueForUpdate(ctx, &apiKey, "api_key", database.By("id", id))
+		if errors.Is(err, database.ErrNotFound) {
+			return newError(ErrAPIKeyNotFound, "found no API key with id %d", id)
+		} else if err != nil {
+			return err
+		}
+		if apiKey.TimeRevoked != nil {
+			return nil
+		}
+
+		return tx.Update(ctx, "api_key", database.By("id", id), []string{"time_revoked"}, s.now())
+	})
+}
+
+// Authenticate returns the record of key, refusing unknown and revoked keys
+// alike.
+func (s *service) Authenticate(ctx context.Context, key string) (*entity.APIKey, error) {
+	rest := strings.TrimPrefix(key, keyPrefix)
+	separator := strings.IndexByte(rest, '_')
+	if rest == key || separator < 0 {
+		return nil, newError(ErrInvalidAPIKey, "malformed API key")
+	}
+
+	var apiKey entity.APIKey
+	err := s.dbClient.FindUnique(ctx, &apiKey, "api_key", database.By("prefix", rest[:separator]))
+	if errors.Is(err, database.ErrNotFound) {
+		return nil, newError(ErrInvalidAPIKey, "unknown API key")
+	} else if err != nil {
+		return nil, err
+	}
+
+	if subtle.ConstantTimeCompare([]byte(hashKey(key)), []byte(apiKey.Hash)) != 1 {
+		return nil, newError(ErrInvalidAPIKey, "unknown API key")
+	}
+	if apiKey.TimeRevoked != nil {
+		return nil, newError(ErrInvalidAPIKey, "API key was revoked")
+	}
+
+	return &apiKey, nil
+}
@@ -0,0 +1,168 @@
+package auth
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/getground/tech-tasks/backend/internal/entity"
+	"github.com/getground/tech-tasks/backend/internal/guest_list"
+	"github.com/getground/tech-tasks/backend/pkg/database"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+)
+
+var ctx = context.Background()
+
+// newTestClient connects to the MySQL database in TEST_MYSQL_DSN when set and
+// falls back to the in-memory backend otherwise.
+func newTestClient() database.Client {
+	if dsn := os.Getenv("TEST_MYSQL_DSN"); dsn != "" {
+		dbClient, err := database.NewClient(dsn)
+		if err != nil {
+			log.Fatalf("Error while connecting to the DB, %v", err)
+		}
+		return dbClient
+	}
+	return database.NewMemoryClient()
+}
+
+func cleanupKeys(dbClient database.Client) {
+	err := dbClient.DeleteAll(ctx, "api_key")
+	if err != nil {
+		log.Fatalf("Error while cleaning table api_key, %v", err)
+	}
+}
+
+type fixedClock struct {
+	now time.Time
+}
+
+func (c fixedClock) Now() time.Time {
+	return c.now
+}
+
+func TestKeys(t *testing.T) {
+	dbClient := newTestClient()
+	defer dbClient.Close()
+	cleanupKeys(dbClient)
+
+	now := time.Date(2022, 6, 1, 18, 0, 0, 0, time.UTC)
+	service := NewService(dbClient, WithClock(fixedClock{now}))
+
+	apiKey, key, err := service.CreateKey(ctx, "front-door", entity.RoleDoor)
+	assert.Nil(t, err, "Error while creating key, %v", err)
+	assert.Regexp(t, "^gl_"+apiKey.Prefix+"_[0-9a-f]{48}$", key)
+	assert.NotContains(t, apiKey.Hash, key)
+
+	_, _, err = service.CreateKey(ctx, "front-door", entity.RoleHost)
+	assert.ErrorIs(t, err, ErrAPIKeyExists)
+	_, _, err = service.CreateKey(ctx, "bouncer", "bouncer")
+	assert.ErrorIs(t, err, ErrInvalidRole)
+	_, _, err = service.CreateKey(ctx, "", entity.RoleHost)
+	assert.ErrorIs(t, err, ErrInvalidName)
+
+	// Test authentication
+	authenticated, err := service.Authenticate(ctx, key)
+	assert.Nil(t, err, "Error while authenticating, %v", err)
+	assert.Equal(t, "front-door", authenticated.Name)
+	assert.Equal(t, entity.RoleDoor, authenticated.Role)
+
+	for _, wrong := range []string{"", "gl_", key[:len(key)-1] + "x", "gl_000000000000_" + key[len(key)-48:], key[3:]} {
+		_, err = service.Authenticate(ctx, wrong)
+		assert.ErrorIs(t, err, ErrInvalidAPIKey, "key %q", wrong)
+	}
+
+	// Test revoked keys are refused but still listed
+	err = service.RevokeKey(ctx, apiKey.ID)
+	assert.Nil(t, err, "Error while revoking key, %v", err)
+	_, err = service.Authenticate(ctx, key)
+	assert.ErrorIs(t, err, ErrInvalidAPIKey)
+	err = service.RevokeKey(ctx, apiKey.ID+1)
+	assert.ErrorIs(t, err, ErrAPIKeyNotFound)
+
+	keys, err := service.GetKeys(ctx)
+	assert.Nil(t, err, "Error while listing keys, %v", err)
+	assert.Equal(t, 1, len(keys))
+	assert.Equal(t, now, *keys[0].TimeRevoked)
+}
+
+func TestAllowed(t *testing.T) {
+	tests := []struct {
+		method, template  string
+		admin, host, door bool
+	}{
+		{http.MethodGet, "/guest_list", true, true, true},
+		{http.MethodPost, "/guest_list/{name}", true, true, false},
+		{http.MethodPut, "/guests/{name}", true, true, true},
+		{http.MethodDelete, "/events/{eventID:[0-9]+}/guests/{name}", true, true, true},
+		{http.MethodDelete, "/tables/{id:[0-9]+}", true, true, false},
+		{http.MethodGet, "/events/{eventID:[0-9]+}/door", true, true, true},
+		{http.MethodPost, "/events", true, false, false},
+		{http.MethodGet, "/events", true, true, true},
+		{http.MethodGet, "/webhooks", true, false, false},
+		{http.MethodPost, "/events/{eventID:[0-9]+}/webhooks", true, false, false},
+	}
+	for _, tc := range tests {
+		assert.Equal(t, tc.admin, Allowed(entity.RoleAdmin, tc.method, tc.template), "admin %s %s", tc.method, tc.template)
+		assert.Equal(t, tc.host, Allowed(entity.RoleHost, tc.method, tc.template), "host %s %s", tc.method, tc.template)
+		assert.Equal(t, tc.door, Allowed(entity.RoleDoor, tc.method, tc.template), "door %s %s", tc.method, tc.template)
+		assert.False(t, Allowed("", tc.method, tc.template))
+	}
+}
+
+func TestMiddleware(t *testing.T) {
+	dbClient := newTestClient()
+	defer dbClient.Close()
+	cleanupKeys(dbClient)
+
+	service := NewService(dbClient)
+	_, doorKey, err := service.CreateKey(ctx, "front-door", entity.RoleDoor)
+	assert.Nil(t, err, "Error while creating key, %v", err)
+
+	// The handler reports the actor the audit log would record
+	r := mux.NewRouter()
+	r.Use(Middleware(service))
+	r.Use(guest_list.ActorMiddleware)
+	actor := func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(guest_list.ActorFromContext(r.Context())))
+	}
+	r.HandleFunc("/guests", actor).Methods(http.MethodGet)
+	r.HandleFunc("/tables", actor).Methods(http.MethodPost)
+
+	tests := []struct {
+		name           string
+		method, url    string
+		authorization  string
+		expectedStatus int
+	}{
+		{"Missing key", http.MethodGet, "/guests", "", http.StatusUnauthorized},
+		{"Wrong scheme", http.MethodGet, "/guests", "Basic " + doorKey, http.StatusUnauthorized},
+		{"Unknown key", http.MethodGet, "/guests", "Bearer gl_0_0", http.StatusUnauthorized},
+		{"Allowed route", http.MethodGet, "/guests", "Bearer " + doorKey, http.StatusOK},
+		{"Forbidden route", http.MethodPost, "/tables", "bearer " + doorKey, http.StatusForbidden},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(tc.method, tc.url, nil)
+			if tc.authorization != "" {
+				req.Header.Set("Authorization", tc.authorization)
+			}
+			req.Header.Set(guest_list.ActorHeader, "someone else")
+			res := httptest.NewRecorder()
+			r.ServeHTTP(res, req)
+
+			assert.Equal(t, tc.expectedStatus, res.Code)
+			if tc.expectedStatus == http.StatusUnauthorized {
+				assert.NotEmpty(t, res.Header().Get("WWW-Authenticate"))
+			}
+			if tc.expectedStatus == http.StatusOK {
+				assert.Equal(t, "front-door", res.Body.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,65 @@
+package auth
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+
+	"github.com/getground/tech-tasks/backend/pkg/problem"
+)
+
+var (
+	ErrInvalidAPIKey  = errors.New("invalid API key")
+	ErrAPIKeyNotFound = errors.New("API key not found")
+	ErrAPIKeyExists   = errors.New("API key already exists")
+	ErrInvalidName    = errors.New("invalid API key name")
+	ErrInvalidRole    = errors.New("invalid role")
+	ErrInvalidToken   = errors.New("invalid token")
+	ErrInvalidGrant   = errors.New("invalid grant")
+	ErrTokensDisabled = errors.New("tokens are disabled")
+)
+
+// statusCodes maps each service error onto the HTTP status it is reported as.
+var statusCodes = map[error]int{
+	ErrInvalidAPIKey:  http.StatusUnauthorized,
+	ErrInvalidToken:   http.StatusUnauthorized,
+	ErrInvalidGrant:   http.StatusBadRequest,
+	ErrTokensDisabled: http.StatusNotImplemented,
+}
+
+// serviceError carries a descriptive message while still matching one of the
+// sentinel errors above with errors.Is.
+type serviceError struct {
+	kind error
+	msg  string
+}
+
+func (e *serviceError) Error() string {
+	return e.msg
+}
+
+func (e *serviceError) Unwrap() error {
+	return e.kind
+}
+
+func newError(kind error, format string, args ...interface{}) error {
+	return &serviceError{kind, fmt.Sprintf(format, args...)}
+}
+
+// writeError responds with the problem details matching err. Unexpected
+// errors are logged and reported as a 500 without leaking their message.
+func writeError(w http.ResponseWriter, r *http.Request, err error) {
+	for kind, status := range statusCodes {
+		if errors.Is(err, kind) {
+			if status == http.StatusUnauthorized {
+				w.Header().Set("WWW-Authenticate", `Bearer realm="guest list"`)
+			}
+			problem.Error(w, r, status, err.Error())
+			return
+		}
+	}
+
+	log.Printf("Error %s when handling %s %s", err, r.Method, r.URL.Path)
+	problem.Error(w, r, http.StatusInternalServerError, "internal server error")
+}
@@ -0,0 +1,161 @@
+package auth
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Algorithms access tokens are signed with.
+const (
+	AlgHS256 = "HS256"
+	AlgEdDSA = "EdDSA"
+)
+
+// Extensions of the key files, the rest of the file name is the kid.
+const (
+	extHS256 = ".hs256"
+	extPEM   = ".pem"
+)
+
+// minHS256SecretLength matches the size of the SHA-256 output, shorter
+// secrets weaken the signature.
+const minHS256SecretLength = 32
+
+// signingKey signs and verifies tokens with one algorithm. Ed25519 keys
+// loaded from a public key only verify tokens.
+type signingKey struct {
+	alg     string
+	secret  []byte
+	private ed25519.PrivateKey
+	public  ed25519.PublicKey
+}
+
+func (k signingKey) canSign() bool {
+	return k.alg == AlgHS256 || k.private != nil
+}
+
+func (k signingKey) sign(input []byte) []byte {
+	if k.alg == AlgHS256 {
+		mac := hmac.New(sha256.New, k.secret)
+		mac.Write(input)
+		return mac.Sum(nil)
+	}
+	return ed25519.Sign(k.private, input)
+}
+
+func (k signingKey) verify(input []byte, signature []byte) bool {
+	if k.alg == AlgHS256 {
+		return hmac.Equal(k.sign(input), signature)
+	}
+	return ed25519.Verify(k.public, input, signature)
+}
+
+// KeySet holds the keys of access tokens by kid. Tokens are signed with the
+// active key and verified with the key named in their kid header, so keys
+// are rotated by adding a key, making it active, and removing the previous
+// one once the tokens it signed expired.
+type KeySet struct {
+	keys   map[string]signingKey
+	active string
+}
+
+// LoadKeySet loads the keys in dir. Files named <kid>.hs256 hold an HS256
+// secret, files named <kid>.pem a PKCS #8 Ed25519 private key or, for keys
+// that only verify, a PKIX public key. Other files are ignored. active names
+// the signing key and may be empty when dir holds a single private key.
+func LoadKeySet(dir string, active string) (*KeySet, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	ks := &KeySet{keys: map[string]signingKey{}, active: active}
+	for _, entry := range entries {
+		ext := filepath.Ext(entry.Name())
+		if entry.IsDir() || (ext != extHS256 && ext != extPEM) {
+			continue
+		}
+		kid := strings.TrimSuffix(entry.Name(), ext)
+		data, err := os.ReadFile(filepath.Join(dir, entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+
+		var key signingKey
+		if ext == extHS256 {
+			key, err = parseHS256Key(data)
+		} else {
+			key, err = parseEd25519Key(data)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("key %s: %w", entry.Name(), err)
+		}
+		ks.keys[kid] = key
+	}
+
+	if ks.active == "" {
+		var signing []string
+		for kid, key := range ks.keys {
+			if key.canSign() {
+				signing = append(signing, kid)
+			}
+		}
+		sort.Strings(signing)
+		if len(signing) != 1 {
+			return nil, fmt.Errorf("found %d signing keys in %s, name the active one", len(signing), dir)
+		}
+		ks.active = signing[0]
+	}
+	if key, ok := ks.keys[ks.active]; !ok || !key.canSign() {
+		return nil, fmt.Errorf("found no signing key %s in %s", ks.active, dir)
+	}
+	return ks, nil
+}
+
+func parseHS256Key(data []byte) (signingKey, error) {
+	secret := bytes.TrimSpace(data)
+	if len(secret) < minHS256SecretLength {
+		return signingKey{}, fmt.Errorf("HS256 secrets must be at least %d bytes long", minHS256SecretLength)
+	}
+	return signingKey{alg: AlgHS256, secret: secret}, nil
+}
+
+func parseEd25519Key(data []byte) (signingKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return signingKey{}, fmt.Errorf("found no PEM block")
+	}
+
+	switch block.Type {
+	case "PRIVATE KEY":
+		parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+		if err != nil {
+			return signingKey{}, err
+		}
+		private, ok := parsed.(ed25519.PrivateKey)
+		if !ok {
+			return signingKey{}, fmt.Errorf("private key is not an Ed25519 key")
+		}
+		return signingKey{alg: AlgEdDSA, private: private, public: private.Public().(ed25519.PublicKey)}, nil
+	case "PUBLIC KEY":
+		parsed, err := x509.ParsePKIXPublicKey(block.Bytes)
+		if err != nil {
+			return signingKey{}, err
+		}
+		public, ok := parsed.(ed25519.PublicKey)
+		if !ok {
+			return signingKey{}, fmt.Errorf("public key is not an Ed25519 key")
+		}
+		return signingKey{alg: AlgEdDSA, public: public}, nil
+	}
+	return signingKey{}, fmt.Errorf("unsupported PEM block %s", block.Type)
+}
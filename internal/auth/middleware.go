@@ -0,0 +1,133 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/getground/tech-tasks/backend/internal/entity"
+	"github.com/getground/tech-tasks/backend/internal/guest_list"
+	"github.com/getground/tech-tasks/backend/pkg/problem"
+	"github.com/gorilla/mux"
+)
+
+// eventPrefix starts the routes scoped to an event, which need the same
+// permissions as the routes of the default event.
+const eventPrefix = "/events/{eventID:[0-9]+}"
+
+// doorRoutes are the changes door staff may make on top of reading.
+var doorRoutes = map[string]bool{
+	http.MethodPut + " /guests/{name}":    true,
+	http.MethodDelete + " /guests/{name}": true,
+}
+
+// Allowed reports whether role may call the route with the given method and
+// path template.
+func Allowed(role string, method string, template string) bool {
+	path := strings.TrimPrefix(template, eventPrefix)
+	switch role {
+	case entity.RoleAdmin:
+		return true
+	case entity.RoleHost:
+		return !adminOnly(method, path)
+	case entity.RoleDoor:
+		if adminOnly(method, path) {
+			return false
+		}
+		return method == http.MethodGet || method == http.MethodHead || doorRoutes[method+" "+path]
+	}
+	return false
+}
+
+// adminOnly reports whether the route manages webhooks or events.
+func adminOnly(method string, path string) bool {
+	return strings.HasPrefix(path, "/webhooks") || (method == http.MethodPost && path == "/events")
+}
+
+// publicRoutes authenticate requests themselves.
+var publicRoutes = map[string]bool{
+	"/auth/token":  true,
+	"/auth/revoke": true,
+}
+
+type claimsKey struct{}
+
+// ClaimsFromContext returns the claims of the client that made the request
+// of ctx. Clients using an API key get claims naming their key and role.
+func ClaimsFromContext(ctx context.Context) (*entity.Claims, bool) {
+	claims, ok := ctx.Value(claimsKey{}).(*entity.Claims)
+	return claims, ok
+}
+
+// Middleware refuses requests without a valid API key or access token in
+// their Authorization header, or whose role may not call the matched route.
+// The claims of the requests let through are put in their context and
+// their changes are attributed to the subject of the claims.
+func Middleware(service Service) mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			template, err := mux.CurrentRoute(r).GetPathTemplate()
+			if err == nil && publicRoutes[template] {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			token, ok := bearerToken(r)
+			if !ok {
+				unauthorized(w, r, "an API key or access token is required as a bearer token")
+				return
+			}
+
+			claims, err := authenticate(r.Context(), service, token)
+			if errors.Is(err, ErrInvalidAPIKey) || errors.Is(err, ErrInvalidToken) {
+				unauthorized(w, r, err.Error())
+				return
+			} else if err != nil {
+				log.Printf("Error %s when authenticating %s %s", err, r.Method, r.URL.Path)
+				problem.Error(w, r, http.StatusInternalServerError, "internal server error")
+				return
+			}
+
+			if template == "" || !Allowed(claims.Role, r.Method, template) {
+				problem.Error(w, r, http.StatusForbidden, fmt.Sprintf("the %s role may not %s %s", claims.Role, r.Method, r.URL.Path))
+				return
+			}
+
+			ctx := context.WithValue(r.Context(), claimsKey{}, claims)
+			ctx = guest_list.ContextWithActor(ctx, claims.Subject)
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// authenticate returns the claims of a bearer token, which is either an API
+// key or an access token.
+func authenticate(ctx context.Context, service Service, token string) (*entity.Claims, error) {
+	if !strings.HasPrefix(token, keyPrefix) {
+		return service.ValidateToken(token)
+	}
+
+	apiKey, err := service.Authenticate(ctx, token)
+	if err != nil {
+		return nil, err
+	}
+	return &entity.Claims{Subject: apiKey.Name, Role: apiKey.Role}, nil
+}
+
+// bearerToken returns the token in the Authorization header of r.
+func bearerToken(r *http.Request) (string, bool) {
+	header := r.Header.Get("Authorization")
+	const scheme = "Bearer "
+	if len(header) <= len(scheme) || !strings.EqualFold(header[:len(scheme)], scheme) {
+		return "", false
+	}
+	return strings.TrimSpace(header[len(scheme):]), true
+}
+
+func unauthorized(w http.ResponseWriter, r *http.Request, detail string) {
+	w.Header().Set("WWW-Authenticate", `Bearer realm="guest list"`)
+	problem.Error(w, r, http.StatusUnauthorized, detail)
+}
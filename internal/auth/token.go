@@ -0,0 +1,299 @@
+package auth
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/getground/tech-tasks/backend/internal/entity"
+	"github.com/getground/tech-tasks/backend/pkg/database"
+)
+
+// Issuer is the iss claim of the access tokens.
+const Issuer = "guest-list"
+
+// refreshPrefix starts every refresh token.
+const refreshPrefix = "glr_"
+
+// Default lifetimes of the tokens.
+const (
+	DefaultTokenTTL   = 15 * time.Minute
+	DefaultRefreshTTL = 30 * 24 * time.Hour
+)
+
+// WithKeySet enables access tokens signed with the keys of ks.
+func WithKeySet(ks *KeySet) Option {
+	return func(s *service) {
+		s.keys = ks
+	}
+}
+
+// WithTokenTTL sets how long access tokens are valid for.
+func WithTokenTTL(ttl time.Duration) Option {
+	return func(s *service) {
+		s.tokenTTL = ttl
+	}
+}
+
+// WithRefreshTTL sets how long refresh tokens are valid for.
+func WithRefreshTTL(ttl time.Duration) Option {
+	return func(s *service) {
+		s.refreshTTL = ttl
+	}
+}
+
+type jwtHeader struct {
+	Alg string `json:"alg"`
+	Typ string `json:"typ"`
+	Kid string `json:"kid"`
+}
+
+var encoding = base64.RawURLEncoding
+
+// sign returns claims as a JWT signed with the active key.
+func (ks *KeySet) sign(claims entity.Claims) (string, error) {
+	key := ks.keys[ks.active]
+	header, err := json.Marshal(jwtHeader{Alg: key.alg, Typ: "JWT", Kid: ks.active})
+	if err != nil {
+		return "", err
+	}
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+
+	input := encoding.EncodeToString(header) + "." + encoding.EncodeToString(payload)
+	return input + "." + encoding.EncodeToString(key.sign([]byte(input))), nil
+}
+
+// verify returns the claims of token once its signature checks out against
+// the key in its kid header. The key's algorithm must be the one in the
+// header so a token can't pick a weaker check.
+func (ks *KeySet) verify(token string) (*entity.Claims, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, newError(ErrInvalidToken, "malformed token")
+	}
+
+	var header jwtHeader
+	err := decodeSegment(parts[0], &header)
+	if err != nil {
+		return nil, err
+	}
+	key, ok := ks.keys[header.Kid]
+	if !ok {
+		return nil, newError(ErrInvalidToken, "unknown token key `%s`", header.Kid)
+	}
+	if header.Alg != key.alg {
+		return nil, newError(ErrInvalidToken, "token key `%s` doesn't sign with %s", header.Kid, header.Alg)
+	}
+
+	signature, err := encoding.DecodeString(parts[2])
+	if err != nil || !key.verify([]byte(parts[0]+"."+parts[1]), signature) {
+		return nil, newError(ErrInvalidToken, "invalid token signature")
+	}
+
+	var claims entity.Claims
+	err = decodeSegment(parts[1], &claims)
+	if err != nil {
+		return nil, err
+	}
+	return &claims, nil
+}
+
+func decodeSegment(segment string, v interface{}) error {
+	data, err := encoding.DecodeString(segment)
+	if err != nil {
+		return newError(ErrInvalidToken, "malformed token")
+	}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	err = decoder.Decode(v)
+	if err != nil {
+		return newError(ErrInvalidToken, "malformed token")
+	}
+	return nil
+}
+
+// ValidateToken returns the claims of an access token issued by the service
+// that hasn't expired yet. Tokens stay valid until they expire even when
+// their API key is revoked, which is why they are short-lived.
+func (s *service) ValidateToken(token string) (*entity.Claims, error) {
+	if s.keys == nil {
+		return nil, newError(ErrInvalidToken, "tokens are disabled")
+	}
+
+	claims, err := s.keys.verify(token)
+	if err != nil {
+		return nil, err
+	}
+	if claims.Issuer != Issuer {
+		return nil, newError(ErrInvalidToken, "token was issued by `%s`", claims.Issuer)
+	}
+	if s.clock.Now().Unix() >= claims.ExpiresAt {
+		return nil, newError(ErrInvalidToken, "token expired")
+	}
+	if !entity.Attributes(entity.Roles).Has(claims.Role) {
+		return nil, newError(ErrInvalidToken, "token has an unknown role")
+	}
+	return claims, nil
+}
+
+// IssueToken exchanges an API key for an access token and a refresh token.
+func (s *service) IssueToken(ctx context.Context, key string) (*entity.CreateTokenResponseBody, error) {
+	if s.keys == nil {
+		return nil, newError(ErrTokensDisabled, "tokens are disabled")
+	}
+
+	apiKey, err := s.Authenticate(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+
+	var response *entity.CreateTokenResponseBody
+	err = s.dbClient.Transaction(ctx, func(tx database.Tx) error {
+		var err error
+		response, err = s.issue(ctx, tx, apiKey)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return response, nil
+}
+
+// RefreshToken exchanges a refresh token for a new access token and a new
+// refresh token, revoking the one used. Presenting a revoked refresh token
+// again means it leaked, so every refresh token of its API key is revoked.
+func (s *service) RefreshToken(ctx context.Context, token string) (*entity.CreateTokenResponseBody, error) {
+	if s.keys == nil {
+		return nil, newError(ErrTokensDisabled, "tokens are disabled")
+	}
+
+	var response *entity.CreateTokenResponseBody
+	reused := false
+	err := s.dbClient.Transaction(ctx, func(tx database.Tx) error {
+		var refresh entity.RefreshToken
+		err := tx.FindUniqueForUpdate(ctx, &refresh, "refresh_token", database.By("hash", hashKey(token)))
+		if errors.Is(err, database.ErrNotFound) {
+			return newError(ErrInvalidGrant, "unknown refresh token")
+		} else if err != nil {
+			return err
+		}
+
+		if refresh.TimeRevoked != nil {
+			reused = true
+			return s.revokeRefreshTokens(ctx, tx, refresh.APIKeyID)
+		}
+		if !s.now().Before(refresh.Expires) {
+			return newError(ErrInvalidGrant, "refresh token expired")
+		}
+
+		var apiKey entity.APIKey
+		err = tx.FindUnique(ctx, &apiKey, "api_key", database.By("id", refresh.APIKeyID))
+		if err != nil {
+			return err
+		}
+		if apiKey.TimeRevoked != nil {
+			return newError(ErrInvalidGrant, "API key was revoked")
+		}
+
+		err = tx.Update(ctx, "refresh_token", database.By("id", refresh.ID), []string{"time_revoked"}, s.now())
+		if err != nil {
+			return err
+		}
+		response, err = s.issue(ctx, tx, &apiKey)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	if reused {
+		return nil, newError(ErrInvalidGrant, "refresh token was already used")
+	}
+	return response, nil
+}
+
+// RevokeToken revokes a refresh token. Unknown and revoked tokens are
+// ignored so revoking is idempotent.
+func (s *service) RevokeToken(ctx context.Context, token string) error {
+	return s.dbClient.Transaction(ctx, func(tx database.Tx) error {
+		var refresh entity.RefreshToken
+		err := tx.FindUniqueForUpdate(ctx, &refresh, "refresh_token", database.By("hash", hashKey(token)))
+		if errors.Is(err, database.ErrNotFound) {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		if refresh.TimeRevoked != nil {
+			return nil
+		}
+		return tx.Update(ctx, "refresh_token", database.By("id", refresh.ID), []string{"time_revoked"}, s.now())
+	})
+}
+
+// revokeRefreshTokens revokes every active refresh token of an API key.
+func (s *service) revokeRefreshTokens(ctx context.Context, tx database.Tx, apiKeyID int) error {
+	tokens := []entity.RefreshToken{}
+	query := database.Where(database.Eq("api_key_id", apiKeyID), database.IsNull("time_revoked"))
+	err := tx.FindMany(ctx, &tokens, "refresh_token", query)
+	if err != nil {
+		return err
+	}
+	for _, token := range tokens {
+		err = tx.Update(ctx, "refresh_token", database.By("id", token.ID), []string{"time_revoked"}, s.now())
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// issue stores a new refresh token for apiKey within tx and returns it along
+// with a new access token.
+func (s *service) issue(ctx context.Context, tx database.Tx, apiKey *entity.APIKey) (*entity.CreateTokenResponseBody, error) {
+	random := make([]byte, 32)
+	_, err := rand.Read(random)
+	if err != nil {
+		return nil, err
+	}
+	refreshToken := refreshPrefix + hex.EncodeToString(random)
+
+	now := s.now()
+	columns := []string{"api_key_id", "hash", "expires", "time_created"}
+	values := []interface{}{apiKey.ID, hashKey(refreshToken), now.Add(s.refreshTTL), now}
+	_, err = tx.Create(ctx, "refresh_token", columns, values...)
+	if err != nil {
+		return nil, err
+	}
+
+	jti := make([]byte, 16)
+	_, err = rand.Read(jti)
+	if err != nil {
+		return nil, err
+	}
+	claims := entity.Claims{
+		Issuer:    Issuer,
+		Subject:   apiKey.Name,
+		Role:      apiKey.Role,
+		ID:        hex.EncodeToString(jti),
+		IssuedAt:  now.Unix(),
+		ExpiresAt: now.Add(s.tokenTTL).Unix(),
+	}
+	accessToken, err := s.keys.sign(claims)
+	if err != nil {
+		return nil, err
+	}
+
+	return &entity.CreateTokenResponseBody{
+		AccessToken:  accessToken,
+		TokenType:    "Bearer",
+		ExpiresIn:    int(s.tokenTTL.Seconds()),
+		RefreshToken: refreshToken,
+	}, nil
+}
@@ -0,0 +1,236 @@
+package auth
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/getground/tech-tasks/backend/internal/entity"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+)
+
+// writeKeys writes an HS256 secret named old, an Ed25519 private key named
+// new and the public key of another Ed25519 key named retired into a
+// temporary directory.
+func writeKeys(t *testing.T) string {
+	dir := t.TempDir()
+	writeFile := func(name string, data []byte) {
+		err := os.WriteFile(filepath.Join(dir, name), data, 0600)
+		if err != nil {
+			t.Fatalf("Error while writing key, %v", err)
+		}
+	}
+	writeFile("old.hs256", []byte(strings.Repeat("s", minHS256SecretLength)+"\n"))
+
+	_, private, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("Error while generating key, %v", err)
+	}
+	der, err := x509.MarshalPKCS8PrivateKey(private)
+	if err != nil {
+		t.Fatalf("Error while encoding key, %v", err)
+	}
+	writeFile("new.pem", pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}))
+
+	public, _, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("Error while generating key, %v", err)
+	}
+	der, err = x509.MarshalPKIXPublicKey(public)
+	if err != nil {
+		t.Fatalf("Error while encoding key, %v", err)
+	}
+	writeFile("retired.pem", pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}))
+	writeFile("README", []byte("not a key"))
+	return dir
+}
+
+func TestLoadKeySet(t *testing.T) {
+	dir := writeKeys(t)
+
+	ks, err := LoadKeySet(dir, "new")
+	assert.Nil(t, err, "Error while loading keys, %v", err)
+	assert.Equal(t, 3, len(ks.keys))
+	assert.Equal(t, AlgHS256, ks.keys["old"].alg)
+	assert.Equal(t, AlgEdDSA, ks.keys["new"].alg)
+	assert.False(t, ks.keys["retired"].canSign())
+
+	// Test the active key must be named among several signing keys
+	_, err = LoadKeySet(dir, "")
+	assert.NotNil(t, err)
+	_, err = LoadKeySet(dir, "retired")
+	assert.NotNil(t, err)
+
+	err = os.WriteFile(filepath.Join(dir, "short.hs256"), []byte("secret"), 0600)
+	assert.Nil(t, err)
+	_, err = LoadKeySet(dir, "new")
+	assert.NotNil(t, err)
+}
+
+func TestTokens(t *testing.T) {
+	dbClient := newTestClient()
+	defer dbClient.Close()
+	cleanupKeys(dbClient)
+
+	dir := writeKeys(t)
+	oldKeys, err := LoadKeySet(dir, "old")
+	assert.Nil(t, err, "Error while loading keys, %v", err)
+	newKeys, err := LoadKeySet(dir, "new")
+	assert.Nil(t, err, "Error while loading keys, %v", err)
+
+	clock := &fixedClock{time.Date(2022, 6, 1, 18, 0, 0, 0, time.UTC)}
+	service := NewService(dbClient, WithClock(clock), WithKeySet(oldKeys))
+	_, key, err := service.CreateKey(ctx, "front-door", entity.RoleDoor)
+	assert.Nil(t, err, "Error while creating key, %v", err)
+
+	response, err := service.IssueToken(ctx, key)
+	assert.Nil(t, err, "Error while issuing token, %v", err)
+	assert.Equal(t, "Bearer", response.TokenType)
+	assert.Equal(t, int(DefaultTokenTTL.Seconds()), response.ExpiresIn)
+
+	claims, err := service.ValidateToken(response.AccessToken)
+	assert.Nil(t, err, "Error while validating token, %v", err)
+	assert.Equal(t, "front-door", claims.Subject)
+	assert.Equal(t, entity.RoleDoor, claims.Role)
+	assert.Equal(t, Issuer, claims.Issuer)
+
+	_, err = service.IssueToken(ctx, key+"0")
+	assert.ErrorIs(t, err, ErrInvalidAPIKey)
+
+	// Test tampered tokens are refused
+	parts := strings.Split(response.AccessToken, ".")
+	payload, err := encoding.DecodeString(parts[1])
+	assert.Nil(t, err)
+	forged := encoding.EncodeToString([]byte(strings.Replace(string(payload), `"role":"door"`, `"role":"admin"`, 1)))
+	assert.NotEqual(t, parts[1], forged)
+	for _, token := range []string{
+		"",
+		"a.b",
+		parts[0] + "." + parts[1] + ".",
+		parts[0] + "." + forged + "." + parts[2],
+		encoding.EncodeToString([]byte(`{"alg":"EdDSA","typ":"JWT","kid":"old"}`)) + "." + parts[1] + "." + parts[2],
+		encoding.EncodeToString([]byte(`{"alg":"HS256","typ":"JWT","kid":"unknown"}`)) + "." + parts[1] + "." + parts[2],
+	} {
+		_, err = service.ValidateToken(token)
+		assert.ErrorIs(t, err, ErrInvalidToken, "token %q", token)
+	}
+
+	// Test tokens signed before a rotation stay valid until they expire
+	rotated := NewService(dbClient, WithClock(clock), WithKeySet(newKeys))
+	_, err = rotated.ValidateToken(response.AccessToken)
+	assert.Nil(t, err, "Error while validating token, %v", err)
+
+	clock.now = clock.now.Add(DefaultTokenTTL)
+	_, err = rotated.ValidateToken(response.AccessToken)
+	assert.ErrorIs(t, err, ErrInvalidToken)
+
+	// Test refreshing revokes the refresh token used
+	refreshed, err := rotated.RefreshToken(ctx, response.RefreshToken)
+	assert.Nil(t, err, "Error while refreshing token, %v", err)
+	assert.NotEqual(t, response.RefreshToken, refreshed.RefreshToken)
+	header, err := encoding.DecodeString(strings.Split(refreshed.AccessToken, ".")[0])
+	assert.Nil(t, err)
+	assert.JSONEq(t, `{"alg":"EdDSA","typ":"JWT","kid":"new"}`, string(header))
+	_, err = rotated.ValidateToken(refreshed.AccessToken)
+	assert.Nil(t, err, "Error while validating token, %v", err)
+
+	// Test reusing a refresh token revokes its successors
+	_, err = rotated.RefreshToken(ctx, response.RefreshToken)
+	assert.ErrorIs(t, err, ErrInvalidGrant)
+	_, err = rotated.RefreshToken(ctx, refreshed.RefreshToken)
+	assert.ErrorIs(t, err, ErrInvalidGrant)
+
+	// Test revoked and expired refresh tokens are refused
+	response, err = rotated.IssueToken(ctx, key)
+	assert.Nil(t, err, "Error while issuing token, %v", err)
+	err = rotated.RevokeToken(ctx, response.RefreshToken)
+	assert.Nil(t, err, "Error while revoking token, %v", err)
+	err = rotated.RevokeToken(ctx, "glr_unknown")
+	assert.Nil(t, err, "Error while revoking token, %v", err)
+	_, err = rotated.RefreshToken(ctx, response.RefreshToken)
+	assert.ErrorIs(t, err, ErrInvalidGrant)
+
+	response, err = rotated.IssueToken(ctx, key)
+	assert.Nil(t, err, "Error while issuing token, %v", err)
+	clock.now = clock.now.Add(DefaultRefreshTTL)
+	_, err = rotated.RefreshToken(ctx, response.RefreshToken)
+	assert.ErrorIs(t, err, ErrInvalidGrant)
+
+	// Test tokens are disabled without keys
+	_, err = NewService(dbClient).IssueToken(ctx, key)
+	assert.ErrorIs(t, err, ErrTokensDisabled)
+}
+
+func TestTokenAPI(t *testing.T) {
+	dbClient := newTestClient()
+	defer dbClient.Close()
+	cleanupKeys(dbClient)
+
+	keys, err := LoadKeySet(writeKeys(t), "new")
+	assert.Nil(t, err, "Error while loading keys, %v", err)
+	service := NewService(dbClient, WithKeySet(keys))
+	apiKey, key, err := service.CreateKey(ctx, "host", entity.RoleHost)
+	assert.Nil(t, err, "Error while creating key, %v", err)
+
+	r := mux.NewRouter()
+	r.Use(Middleware(service))
+	RegisterHandlers(r, service)
+	r.HandleFunc("/tables", func(w http.ResponseWriter, r *http.Request) {
+		claims, _ := ClaimsFromContext(r.Context())
+		json.NewEncoder(w).Encode(claims)
+	}).Methods(http.MethodPost)
+
+	post := func(url string, body string, token string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, url, strings.NewReader(body))
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		res := httptest.NewRecorder()
+		r.ServeHTTP(res, req)
+		return res
+	}
+
+	res := post("/auth/token", `{"grant_type": "password"}`, "")
+	assert.Equal(t, http.StatusBadRequest, res.Code)
+	res = post("/auth/token", `{"grant_type": "api_key", "api_key": "gl_0_0"}`, "")
+	assert.Equal(t, http.StatusUnauthorized, res.Code)
+
+	res = post("/auth/token", `{"grant_type": "api_key", "api_key": "`+key+`"}`, "")
+	assert.Equal(t, http.StatusOK, res.Code)
+	assert.Equal(t, "no-store", res.Header().Get("Cache-Control"))
+	var tokens entity.CreateTokenResponseBody
+	err = json.NewDecoder(res.Body).Decode(&tokens)
+	assert.Nil(t, err, "Error while decoding tokens, %v", err)
+
+	// Test the claims of the access token reach the handlers
+	res = post("/tables", `{}`, tokens.AccessToken)
+	assert.Equal(t, http.StatusOK, res.Code)
+	var claims entity.Claims
+	err = json.NewDecoder(res.Body).Decode(&claims)
+	assert.Nil(t, err, "Error while decoding claims, %v", err)
+	assert.Equal(t, apiKey.Name, claims.Subject)
+	assert.Equal(t, entity.RoleHost, claims.Role)
+
+	res = post("/tables", `{}`, tokens.AccessToken[:len(tokens.AccessToken)-2])
+	assert.Equal(t, http.StatusUnauthorized, res.Code)
+
+	res = post("/auth/token", `{"grant_type": "refresh_token", "refresh_token": "`+tokens.RefreshToken+`"}`, "")
+	assert.Equal(t, http.StatusOK, res.Code)
+	err = json.NewDecoder(res.Body).Decode(&tokens)
+	assert.Nil(t, err, "Error while decoding tokens, %v", err)
+
+	res = post("/auth/revoke", `{"refresh_token": "`+tokens.RefreshToken+`"}`, "")
+	assert.Equal(t, http.StatusNoContent, res.Code)
+	res = post("/auth/token", `{"grant_type": "refresh_token", "refresh_token": "`+tokens.RefreshToken+`"}`, "")
+	assert.Equal(t, http.StatusBadRequest, res.Code)
+}
@@ -0,0 +1,27 @@
+package entity
+
+import "time"
+
+// Roles of API keys. Admins may do anything, hosts anything but managing
+// webhooks and events, and door staff may only read and check guests in and
+// out.
+const (
+	RoleAdmin = "admin"
+	RoleHost  = "host"
+	RoleDoor  = "door"
+)
+
+// Roles lists every role of API keys.
+var Roles = []string{RoleAdmin, RoleHost, RoleDoor}
+
+// APIKey identifies a client of the API. The key itself is only shown when
+// it is created, Prefix is the part of it kept to tell keys apart.
+type APIKey struct {
+	ID          int        `json:"id"           db:"id"`
+	Name        string     `json:"name"         db:"name"`
+	Role        string     `json:"role"         db:"role"`
+	Prefix      string     `json:"prefix"       db:"prefix"`
+	Hash        string     `json:"-"            db:"hash"`
+	TimeCreated time.Time  `json:"time_created" db:"time_created"`
+	TimeRevoked *time.Time `json:"time_revoked" db:"time_revoked"`
+}
@@ -0,0 +1,67 @@
+package entity
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Actions recorded in the audit log.
+const (
+	AuditTableCreated      = "table_created"
+	AuditTableUpdated      = "table_updated"
+	AuditTableDeleted      = "table_deleted"
+	AuditGuestAdded        = "guest_added"
+	AuditGuestUpdated      = "guest_updated"
+	AuditGuestRemoved      = "guest_removed"
+	AuditGuestCheckedIn    = "guest_checked_in"
+	AuditGuestCheckedOut   = "guest_checked_out"
+	AuditEventCreated      = "event_created"
+	AuditWaitlistJoined    = "waitlist_joined"
+	AuditWaitlistLeft      = "waitlist_left"
+	AuditWaitlistPromoted  = "waitlist_promoted"
+	AuditConstraintCreated = "constraint_created"
+	AuditConstraintDeleted = "constraint_deleted"
+)
+
+// Kinds of entity an audit entry describes.
+const (
+	AuditEntityGuest      = "guest"
+	AuditEntityTable      = "table"
+	AuditEntityEvent      = "event"
+	AuditEntityWaitlist   = "waitlist"
+	AuditEntityConstraint = "constraint"
+)
+
+// AuditActorAnonymous is the actor of changes made without naming one.
+const AuditActorAnonymous = "anonymous"
+
+// AuditEntry records who changed an entity of an event and how. Before is
+// null for created entities and After for deleted ones. Guest, waitlist and
+// constraint entries hold the guest they concern, guest and waitlist entries
+// the table the guest sat at, so filtering by table finds them too.
+type AuditEntry struct {
+	ID          int             `json:"id"           db:"id"`
+	EventID     int             `json:"event_id"     db:"event_id"`
+	Actor       string          `json:"actor"        db:"actor"`
+	Action      string          `json:"action"       db:"action"`
+	EntityType  string          `json:"entity_type"  db:"entity_type"`
+	Guest       *string         `json:"guest"        db:"guest"`
+	TableID     *int            `json:"table_id"     db:"table_id"`
+	Before      json.RawMessage `json:"before"       db:"before_state"`
+	After       json.RawMessage `json:"after"        db:"after_state"`
+	TimeCreated time.Time       `json:"time_created" db:"time_created"`
+}
+
+// AuditFilter narrows the audit log down. Nil fields match every entry and
+// the time range includes Since but not Until.
+type AuditFilter struct {
+	Guest *string
+	Table *int
+	Actor *string
+	Since *time.Time
+	Until *time.Time
+}
+
+type GetAuditLogResponseBody struct {
+	Entries []AuditEntry `json:"entries"`
+}
@@ -0,0 +1,35 @@
+package entity
+
+import "github.com/getground/tech-tasks/backend/pkg/problem"
+
+// Commands door devices send over the door WebSocket.
+const (
+	DoorCheckIn  = "check_in"
+	DoorCheckout = "checkout"
+)
+
+// Types of the messages sent to door devices. Results and errors answer the
+// device's own commands while events report changes made by anyone.
+const (
+	DoorMessageResult = "result"
+	DoorMessageError  = "error"
+	DoorMessageEvent  = "event"
+)
+
+// DoorCommand asks to check a guest in or out. ID is chosen by the device
+// and echoed in the answer so it can match answers to commands.
+type DoorCommand struct {
+	ID                 string `json:"id"                  validate:"max=255"`
+	Type               string `json:"type"                validate:"required,oneof=check_in checkout"`
+	Guest              string `json:"guest"               validate:"required,max=255"`
+	AccompanyingGuests int    `json:"accompanying_guests" validate:"min=0"`
+}
+
+type DoorMessage struct {
+	Type    string           `json:"type"`
+	ID      string           `json:"id,omitempty"`
+	Command string           `json:"command,omitempty"`
+	Guest   string           `json:"guest,omitempty"`
+	Error   *problem.Details `json:"error,omitempty"`
+	Event   *StreamEvent     `json:"event,omitempty"`
+}
@@ -0,0 +1,26 @@
+package entity
+
+import "time"
+
+// DefaultEventID is the event used by the routes that are not scoped to an
+// event. It is created by the schema migrations.
+const DefaultEventID = 1
+
+type Event struct {
+	ID        int        `json:"id"         db:"id"`
+	Name      string     `json:"name"       db:"name"`
+	Venue     string     `json:"venue"      db:"venue"`
+	StartTime *time.Time `json:"start_time" db:"start_time"`
+	EndTime   *time.Time `json:"end_time"   db:"end_time"`
+}
+
+type CreateEventRequestBody struct {
+	Name      string     `json:"name"       validate:"required,max=255"`
+	Venue     string     `json:"venue"      validate:"max=255"`
+	StartTime *time.Time `json:"start_time"`
+	EndTime   *time.Time `json:"end_time"`
+}
+
+type GetAllEventsResponseBody struct {
+	Events []Event `json:"events"`
+}
@@ -1,50 +1,166 @@
 package entity
 
+import "time"
+
+// Visit states of a guest. Departed guests may come back, which makes them
+// arrived again.
+const (
+	GuestStatusExpected = "expected"
+	GuestStatusArrived  = "arrived"
+	GuestStatusDeparted = "departed"
+)
+
 type Guest struct {
-	ID                 int     `json:"id"                  db:"id"`
-	Name               string  `json:"name"                db:"name"`
-	AccompanyingGuests int     `json:"accompanying_guests" db:"accompanying_guests"`
-	TableID            int     `json:"table_id"            db:"table_id"`
-	TimeArrived        *string `json:"time_arrived"        db:"time_arrived"`
+	ID                 int        `json:"id"                  db:"id"`
+	EventID            int        `json:"event_id"            db:"event_id"`
+	Name               string     `json:"name"                db:"name"`
+	AccompanyingGuests int        `json:"accompanying_guests" db:"accompanying_guests"`
+	TableID            int        `json:"table_id"            db:"table_id"`
+	Status             string     `json:"status"              db:"status"`
+	TimeArrived        *time.Time `json:"time_arrived"        db:"time_arrived"`
+	TimeLeft           *time.Time `json:"time_left"           db:"time_left"`
 }
 
+// AddGuestRequestBody leaves Table nil to have the service pick a table.
 type AddGuestRequestBody struct {
-	Table              int `json:"table"`
-	AccompanyingGuests int `json:"accompanying_guests"`
+	Table              *int `json:"table"               validate:"min=1"`
+	AccompanyingGuests int  `json:"accompanying_guests" validate:"min=0"`
 }
 
 type AddGuestResponseBody struct {
-	Name string `json:"name"`
+	Name  string `json:"name"`
+	Table int    `json:"table"`
+}
+
+type UpdateGuestRequestBody struct {
+	Table              *int `json:"table"               validate:"min=1"`
+	AccompanyingGuests *int `json:"accompanying_guests" validate:"min=0"`
+}
+
+type UpdateGuestResponseBody struct {
+	Name               string `json:"name"`
+	Table              int    `json:"table"`
+	AccompanyingGuests int    `json:"accompanying_guests"`
 }
 
 type CheckInGuestRequestBody struct {
-	AccompanyingGuests int `json:"accompanying_guests"`
+	AccompanyingGuests int `json:"accompanying_guests" validate:"min=0"`
 }
 
 type CheckInGuestResponseBody struct {
 	Name string `json:"name"`
 }
 
+// Orders guest listings can be sorted in, reversed by a leading "-".
+const (
+	GuestSortName    = "name"
+	GuestSortArrival = "arrival"
+)
+
+// Sizes of the pages guest listings are split into.
+const (
+	DefaultGuestPageSize = 100
+	MaxGuestPageSize     = 1000
+)
+
+// GuestFilter selects the guests of a listing and pages through them. Nil
+// fields match every guest.
+type GuestFilter struct {
+	Table        *int
+	NamePrefix   *string
+	ArrivedSince *time.Time
+	ArrivedUntil *time.Time
+	CheckedIn    *bool
+	// Sort is one of the guest sort orders, by name when empty.
+	Sort string
+	// Cursor continues a listing after the page it was returned with.
+	Cursor string
+	// Limit is the size of the page, DefaultGuestPageSize when zero.
+	Limit int
+}
+
 type GetAllGuestsElement struct {
 	Name               string `json:"name"                db:"name"`
 	AccompanyingGuests int    `json:"accompanying_guests" db:"accompanying_guests"`
 	TableID            int    `json:"table_id"            db:"table_id"`
 }
 
+// GetAllGuestsResponseBody holds a page of guests. NextCursor is nil on the
+// last page.
 type GetAllGuestsResponseBody struct {
-	Guests []GetAllGuestsElement `json:"guests"`
+	Guests     []GetAllGuestsElement `json:"guests"`
+	NextCursor *string               `json:"next_cursor"`
 }
 
 type GetAllCheckedInGuestsElement struct {
-	Name               string `json:"name"                db:"name"`
-	AccompanyingGuests int    `json:"accompanying_guests" db:"accompanying_guests"`
-	TimeArrived        string `json:"time_arrived"        db:"time_arrived"`
+	Name               string    `json:"name"                db:"name"`
+	AccompanyingGuests int       `json:"accompanying_guests" db:"accompanying_guests"`
+	TimeArrived        time.Time `json:"time_arrived"        db:"time_arrived"`
 }
 
+// GetAllCheckedInGuestsResponseBody holds a page of checked in guests.
+// NextCursor is nil on the last page.
 type GetAllCheckedInGuestsResponseBody struct {
-	Guests []GetAllCheckedInGuestsElement `json:"guests"`
+	Guests     []GetAllCheckedInGuestsElement `json:"guests"`
+	NextCursor *string                        `json:"next_cursor"`
 }
 
 type CountEmptySeatsResponseBody struct {
 	SeatsEmpty int `json:"seats_empty"`
 }
+
+// Visit is a stay of a guest at the event, from a check-in until the next
+// checkout. TimeLeft is nil while the guest is still there.
+type Visit struct {
+	ID                 int        `json:"id"                  db:"id"`
+	EventID            int        `json:"event_id"            db:"event_id"`
+	Name               string     `json:"name"                db:"name"`
+	TableID            int        `json:"table_id"            db:"table_id"`
+	AccompanyingGuests int        `json:"accompanying_guests" db:"accompanying_guests"`
+	TimeArrived        time.Time  `json:"time_arrived"        db:"time_arrived"`
+	TimeLeft           *time.Time `json:"time_left"           db:"time_left"`
+}
+
+type GuestHistoryElement struct {
+	Name               string    `json:"name"                db:"name"`
+	AccompanyingGuests int       `json:"accompanying_guests" db:"accompanying_guests"`
+	TableID            int       `json:"table_id"            db:"table_id"`
+	TimeArrived        time.Time `json:"time_arrived"        db:"time_arrived"`
+	TimeLeft           time.Time `json:"time_left"           db:"time_left"`
+}
+
+type GetGuestHistoryResponseBody struct {
+	Guests []GuestHistoryElement `json:"guests"`
+}
+
+// Ways of handling rows that fail during a bulk import. Atomic imports add
+// no guest at all unless every row succeeds, best-effort imports keep the
+// rows that succeed.
+const (
+	ImportAtomic     = "atomic"
+	ImportBestEffort = "best-effort"
+)
+
+// ImportGuestRow is one parsed row of a bulk import. Table is nil to have the
+// service pick a table, Error is set when the row could not be parsed.
+type ImportGuestRow struct {
+	Line               int
+	Name               string
+	Table              *int
+	AccompanyingGuests int
+	Error              error
+}
+
+type ImportGuestResult struct {
+	Line  int     `json:"line"`
+	Name  string  `json:"name"`
+	Table *int    `json:"table"`
+	Error *string `json:"error"`
+}
+
+type ImportGuestsResponseBody struct {
+	Mode     string              `json:"mode"`
+	Imported int                 `json:"imported"`
+	Failed   int                 `json:"failed"`
+	Rows     []ImportGuestResult `json:"rows"`
+}
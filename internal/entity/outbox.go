@@ -0,0 +1,63 @@
+package entity
+
+import (
+	"database/sql/driver"
+	"time"
+)
+
+// States of an outbox entry. Delivered entries reached every sink.
+const (
+	OutboxStatusPending   = "pending"
+	OutboxStatusDelivered = "delivered"
+)
+
+// OutboxEntry is a stream event waiting in the outbox. DeliveredTo names the
+// sinks that already accepted it so retries skip them.
+type OutboxEntry struct {
+	ID            int        `json:"id"             db:"id"`
+	EventID       int        `json:"event_id"       db:"event_id"`
+	Type          string     `json:"type"           db:"type"`
+	Guest         *string    `json:"guest"          db:"guest"`
+	TableID       *int       `json:"table_id"       db:"table_id"`
+	EmptySeats    int        `json:"empty_seats"    db:"empty_seats"`
+	Status        string     `json:"status"         db:"status"`
+	Attempts      int        `json:"attempts"       db:"attempts"`
+	DeliveredTo   SinkNames  `json:"delivered_to"   db:"delivered_to"`
+	LastError     *string    `json:"last_error"     db:"last_error"`
+	NextAttempt   *time.Time `json:"next_attempt"   db:"next_attempt"`
+	TimeCreated   time.Time  `json:"time_created"   db:"time_created"`
+	TimeDelivered *time.Time `json:"time_delivered" db:"time_delivered"`
+}
+
+// StreamEvent returns the event the entry holds, numbered by its id.
+func (e OutboxEntry) StreamEvent() StreamEvent {
+	return StreamEvent{
+		ID:         int64(e.ID),
+		Type:       e.Type,
+		EventID:    e.EventID,
+		Guest:      e.Guest,
+		Table:      e.TableID,
+		EmptySeats: e.EmptySeats,
+		Time:       e.TimeCreated,
+	}
+}
+
+// SinkNames are the names of outbox sinks, stored comma separated like
+// table attributes.
+type SinkNames []string
+
+func (n SinkNames) Has(name string) bool {
+	return Attributes(n).Has(name)
+}
+
+func (n SinkNames) Value() (driver.Value, error) {
+	return Attributes(n).Value()
+}
+
+func (n *SinkNames) Scan(src interface{}) error {
+	return (*Attributes)(n).Scan(src)
+}
+
+func (n SinkNames) MarshalJSON() ([]byte, error) {
+	return Attributes(n).MarshalJSON()
+}
@@ -0,0 +1,67 @@
+package entity
+
+// Kinds of seating constraints. Together and apart relate two guests while
+// requires asks for a table with the given attribute.
+const (
+	ConstraintTogether = "together"
+	ConstraintApart    = "apart"
+	ConstraintRequires = "requires"
+)
+
+type SeatingConstraint struct {
+	ID           int     `json:"id"             db:"id"`
+	EventID      int     `json:"event_id"       db:"event_id"`
+	Kind         string  `json:"kind"           db:"kind"`
+	GuestID      int     `json:"guest_id"       db:"guest_id"`
+	OtherGuestID *int    `json:"other_guest_id" db:"other_guest_id"`
+	Attribute    *string `json:"attribute"      db:"attribute"`
+}
+
+type CreateSeatingConstraintRequestBody struct {
+	Kind       string  `json:"kind"        validate:"required,oneof=together apart requires"`
+	Guest      string  `json:"guest"       validate:"required,max=255"`
+	OtherGuest *string `json:"other_guest" validate:"max=255"`
+	Attribute  *string `json:"attribute"   validate:"max=255"`
+}
+
+// SeatingConstraintElement is a seating constraint naming its guests.
+type SeatingConstraintElement struct {
+	ID         int     `json:"id"`
+	Kind       string  `json:"kind"`
+	Guest      string  `json:"guest"`
+	OtherGuest *string `json:"other_guest"`
+	Attribute  *string `json:"attribute"`
+}
+
+type GetAllSeatingConstraintsResponseBody struct {
+	Constraints []SeatingConstraintElement `json:"constraints"`
+}
+
+type SeatingAssignment struct {
+	Name               string `json:"name"`
+	AccompanyingGuests int    `json:"accompanying_guests"`
+	Table              int    `json:"table"`
+	PreviousTable      int    `json:"previous_table"`
+}
+
+// SeatingPlan is the outcome of the seating optimizer. Violations lists the
+// constraints the plan could not satisfy and Moves counts the guests whose
+// table changes.
+type SeatingPlan struct {
+	Assignments []SeatingAssignment        `json:"assignments"`
+	Violations  []SeatingConstraintElement `json:"violations"`
+	Moves       int                        `json:"moves"`
+	Applied     bool                       `json:"applied"`
+}
+
+// SeatingChartTable is a table along with the guests seated at it.
+type SeatingChartTable struct {
+	Table
+	Guests []SeatingChartGuest `json:"guests"`
+}
+
+type SeatingChartGuest struct {
+	Name               string `json:"name"`
+	AccompanyingGuests int    `json:"accompanying_guests"`
+	Status             string `json:"status"`
+}
@@ -0,0 +1,26 @@
+package entity
+
+import "time"
+
+// Types of the events published on the live occupancy stream.
+const (
+	StreamGuestAdded      = "guest_added"
+	StreamGuestCheckedIn  = "guest_checked_in"
+	StreamGuestCheckedOut = "guest_checked_out"
+	StreamTableChanged    = "table_changed"
+)
+
+// StreamEventTypes lists every type of stream event.
+var StreamEventTypes = []string{StreamGuestAdded, StreamGuestCheckedIn, StreamGuestCheckedOut, StreamTableChanged}
+
+// StreamEvent is a change to the guest list of an event. EmptySeats is the
+// count of empty seats of the event right after the change.
+type StreamEvent struct {
+	ID         int64     `json:"id"`
+	Type       string    `json:"type"`
+	EventID    int       `json:"event_id"`
+	Guest      *string   `json:"guest,omitempty"`
+	Table      *int      `json:"table,omitempty"`
+	EmptySeats int       `json:"empty_seats"`
+	Time       time.Time `json:"time"`
+}
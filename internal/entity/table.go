@@ -1,16 +1,96 @@
 package entity
 
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+)
+
 type Table struct {
-	ID            int `json:"id"             db:"id"`
-	Capacity      int `json:"capacity"       db:"capacity"`
-	ReservedSeats int `json:"reserved_seats" db:"reserved_seats"`
+	ID            int        `json:"id"             db:"id"`
+	EventID       int        `json:"event_id"       db:"event_id"`
+	Capacity      int        `json:"capacity"       db:"capacity"`
+	ReservedSeats int        `json:"reserved_seats" db:"reserved_seats"`
+	Attributes    Attributes `json:"attributes"     db:"attributes"`
+}
+
+// Attributes are the features of a table, such as "quiet" or "accessible",
+// which seating constraints can require. They are stored comma separated.
+type Attributes []string
+
+// Has reports whether attribute is one of a.
+func (a Attributes) Has(attribute string) bool {
+	for _, other := range a {
+		if other == attribute {
+			return true
+		}
+	}
+	return false
+}
+
+func (a Attributes) Value() (driver.Value, error) {
+	sorted := append([]string(nil), a...)
+	sort.Strings(sorted)
+	return strings.Join(sorted, ","), nil
+}
+
+func (a *Attributes) Scan(src interface{}) error {
+	var s string
+	switch v := src.(type) {
+	case nil:
+	case string:
+		s = v
+	case []byte:
+		s = string(v)
+	default:
+		return fmt.Errorf("cannot scan %T into Attributes", src)
+	}
+
+	*a = nil
+	if s != "" {
+		*a = strings.Split(s, ",")
+	}
+	return nil
+}
+
+func (a Attributes) MarshalJSON() ([]byte, error) {
+	if a == nil {
+		return []byte("[]"), nil
+	}
+	return json.Marshal([]string(a))
 }
 
 type CreateTableRequestBody struct {
-	Capacity int `json:"capacity"`
+	Capacity   int        `json:"capacity"   validate:"min=1"`
+	Attributes Attributes `json:"attributes"`
 }
 
 type CreateTableResponseBody struct {
-	ID       int `json:"id"`
-	Capacity int `json:"capacity"`
+	ID         int        `json:"id"`
+	Capacity   int        `json:"capacity"`
+	Attributes Attributes `json:"attributes"`
+}
+
+type GetAllTablesResponseBody struct {
+	Tables []Table `json:"tables"`
+}
+
+type UpdateTableRequestBody struct {
+	Capacity   *int        `json:"capacity"   validate:"min=1"`
+	Attributes *Attributes `json:"attributes"`
+}
+
+// Ways of dealing with the guests expected or seated at a table that is being
+// deleted. Departed guests keep the table they sat at.
+const (
+	DeleteTableCascade  = "cascade"
+	DeleteTableReassign = "reassign"
+)
+
+type DeleteTableOptions struct {
+	// Guests is empty, DeleteTableCascade or DeleteTableReassign.
+	Guests     string
+	ReassignTo int
 }
@@ -0,0 +1,65 @@
+package entity
+
+import (
+	"time"
+
+	"github.com/getground/tech-tasks/backend/pkg/validate"
+)
+
+// Grant types accepted when requesting a token.
+const (
+	GrantAPIKey       = "api_key"
+	GrantRefreshToken = "refresh_token"
+)
+
+// Claims are the claims of the access tokens, Subject is the name of the API
+// key the token was issued for.
+type Claims struct {
+	Issuer    string `json:"iss"`
+	Subject   string `json:"sub"`
+	Role      string `json:"role"`
+	ID        string `json:"jti"`
+	IssuedAt  int64  `json:"iat"`
+	ExpiresAt int64  `json:"exp"`
+}
+
+// RefreshToken is a server-side record of a refresh token, of which only
+// the hash is kept.
+type RefreshToken struct {
+	ID          int        `json:"id"           db:"id"`
+	APIKeyID    int        `json:"api_key_id"   db:"api_key_id"`
+	Hash        string     `json:"-"            db:"hash"`
+	Expires     time.Time  `json:"expires"      db:"expires"`
+	TimeCreated time.Time  `json:"time_created" db:"time_created"`
+	TimeRevoked *time.Time `json:"time_revoked" db:"time_revoked"`
+}
+
+// CreateTokenRequestBody exchanges an API key or a refresh token, depending
+// on GrantType, for a new access token.
+type CreateTokenRequestBody struct {
+	GrantType    string `json:"grant_type"    validate:"required,oneof=api_key refresh_token"`
+	APIKey       string `json:"api_key"`
+	RefreshToken string `json:"refresh_token"`
+}
+
+// Validate asks for the credential matching the grant type.
+func (b CreateTokenRequestBody) Validate() validate.Errors {
+	if b.GrantType == GrantAPIKey && b.APIKey == "" {
+		return validate.Errors{{Field: "api_key", Detail: "is required"}}
+	}
+	if b.GrantType == GrantRefreshToken && b.RefreshToken == "" {
+		return validate.Errors{{Field: "refresh_token", Detail: "is required"}}
+	}
+	return nil
+}
+
+type CreateTokenResponseBody struct {
+	AccessToken  string `json:"access_token"`
+	TokenType    string `json:"token_type"`
+	ExpiresIn    int    `json:"expires_in"`
+	RefreshToken string `json:"refresh_token"`
+}
+
+type RevokeTokenRequestBody struct {
+	RefreshToken string `json:"refresh_token" validate:"required"`
+}
@@ -0,0 +1,32 @@
+package entity
+
+import "time"
+
+// States of a waitlist entry. Promoted entries are kept so the promotion can
+// be looked up after the guest was seated.
+const (
+	WaitlistStatusWaiting  = "waiting"
+	WaitlistStatusPromoted = "promoted"
+)
+
+// WaitlistEntry is a party queued for a table. TableID is nil while the party
+// accepts any table, and holds the table they were seated at once promoted.
+type WaitlistEntry struct {
+	ID                 int        `json:"id"                  db:"id"`
+	EventID            int        `json:"event_id"            db:"event_id"`
+	Name               string     `json:"name"                db:"name"`
+	AccompanyingGuests int        `json:"accompanying_guests" db:"accompanying_guests"`
+	TableID            *int       `json:"table_id"            db:"table_id"`
+	Status             string     `json:"status"              db:"status"`
+	TimeJoined         time.Time  `json:"time_joined"         db:"time_joined"`
+	TimePromoted       *time.Time `json:"time_promoted"       db:"time_promoted"`
+}
+
+type JoinWaitlistRequestBody struct {
+	Table              *int `json:"table"               validate:"min=1"`
+	AccompanyingGuests int  `json:"accompanying_guests" validate:"min=0"`
+}
+
+type GetWaitlistResponseBody struct {
+	Waitlist []WaitlistEntry `json:"waitlist"`
+}
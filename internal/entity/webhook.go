@@ -0,0 +1,96 @@
+package entity
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"time"
+)
+
+// States of a webhook delivery. Failed deliveries ran out of attempts.
+const (
+	DeliveryStatusPending   = "pending"
+	DeliveryStatusDelivered = "delivered"
+	DeliveryStatusFailed    = "failed"
+)
+
+// Webhook subscribes a URL to some of the stream events of an event. The
+// secret signs the deliveries and is only shown when the webhook is created.
+type Webhook struct {
+	ID          int        `json:"id"           db:"id"`
+	EventID     int        `json:"event_id"     db:"event_id"`
+	URL         string     `json:"url"          db:"url"`
+	Secret      string     `json:"-"            db:"secret"`
+	EventTypes  EventTypes `json:"event_types"  db:"event_types"`
+	TimeCreated time.Time  `json:"time_created" db:"time_created"`
+}
+
+// EventTypes are the stream event types a webhook subscribes to. They are
+// stored comma separated like table attributes.
+type EventTypes []string
+
+func (t EventTypes) Has(eventType string) bool {
+	return Attributes(t).Has(eventType)
+}
+
+func (t EventTypes) Value() (driver.Value, error) {
+	return Attributes(t).Value()
+}
+
+func (t *EventTypes) Scan(src interface{}) error {
+	return (*Attributes)(t).Scan(src)
+}
+
+func (t EventTypes) MarshalJSON() ([]byte, error) {
+	return Attributes(t).MarshalJSON()
+}
+
+// CreateWebhookRequestBody subscribes URL to the given event types. A secret
+// is generated when none is given.
+type CreateWebhookRequestBody struct {
+	URL        string     `json:"url"         validate:"required,max=2048"`
+	Secret     *string    `json:"secret"      validate:"max=255"`
+	EventTypes EventTypes `json:"event_types" validate:"required"`
+}
+
+type CreateWebhookResponseBody struct {
+	Webhook
+	Secret string `json:"secret"`
+}
+
+type GetWebhooksResponseBody struct {
+	Webhooks []Webhook `json:"webhooks"`
+}
+
+// WebhookDelivery is a stream event on its way to a webhook. NextAttempt is
+// nil once the delivery succeeded or failed for good.
+type WebhookDelivery struct {
+	ID            int             `json:"id"             db:"id"`
+	WebhookID     int             `json:"webhook_id"     db:"webhook_id"`
+	EventType     string          `json:"event_type"     db:"event_type"`
+	Payload       json.RawMessage `json:"payload"        db:"payload"`
+	Status        string          `json:"status"         db:"status"`
+	Attempts      int             `json:"attempts"       db:"attempts"`
+	NextAttempt   *time.Time      `json:"next_attempt"   db:"next_attempt"`
+	TimeCreated   time.Time       `json:"time_created"   db:"time_created"`
+	TimeDelivered *time.Time      `json:"time_delivered" db:"time_delivered"`
+}
+
+// WebhookAttempt records a single try of a delivery. StatusCode is nil when
+// the receiver could not be reached.
+type WebhookAttempt struct {
+	ID            int       `json:"id"             db:"id"`
+	DeliveryID    int       `json:"delivery_id"    db:"delivery_id"`
+	StatusCode    *int      `json:"status_code"    db:"status_code"`
+	Error         *string   `json:"error"          db:"error"`
+	DurationMS    int       `json:"duration_ms"    db:"duration_ms"`
+	TimeAttempted time.Time `json:"time_attempted" db:"time_attempted"`
+}
+
+type WebhookDeliveryElement struct {
+	WebhookDelivery
+	AttemptLog []WebhookAttempt `json:"attempt_log"`
+}
+
+type GetWebhookDeliveriesResponseBody struct {
+	Deliveries []WebhookDeliveryElement `json:"deliveries"`
+}
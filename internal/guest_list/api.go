@@ -2,40 +2,168 @@ package guest_list
 
 import (
 	"encoding/json"
+	"fmt"
+	"mime"
 	"net/http"
+	"net/url"
+	"strconv"
+	"time"
 
 	"github.com/getground/tech-tasks/backend/internal/entity"
+	"github.com/getground/tech-tasks/backend/pkg/problem"
+	"github.com/getground/tech-tasks/backend/pkg/validate"
 	"github.com/gorilla/mux"
 )
 
 func RegisterHandlers(r *mux.Router, service GuestListService) {
 	h := handler{service}
+	r.HandleFunc("/events", h.createEvent).Methods(http.MethodPost)
+	r.HandleFunc("/events", h.getAllEvents).Methods(http.MethodGet)
+	r.HandleFunc("/events/stream", h.streamEvents).Methods(http.MethodGet)
+	r.HandleFunc("/events/{eventID:[0-9]+}", h.getEvent).Methods(http.MethodGet)
+
+	// The unscoped routes manage the default event
+	registerEventRoutes(r, h)
+
+	events := r.PathPrefix("/events/{eventID:[0-9]+}").Subrouter()
+	events.Use(h.requireEvent)
+	events.HandleFunc("/stream", h.streamEvents).Methods(http.MethodGet)
+	registerEventRoutes(events, h)
+}
+
+// registerEventRoutes registers the routes managing the tables and guests of
+// a single event.
+func registerEventRoutes(r *mux.Router, h handler) {
 	r.HandleFunc("/tables", h.createTable).Methods(http.MethodPost)
+	r.HandleFunc("/tables", h.getAllTables).Methods(http.MethodGet)
+	r.HandleFunc("/tables/{id:[0-9]+}", h.getTable).Methods(http.MethodGet)
+	r.HandleFunc("/tables/{id:[0-9]+}", h.updateTable).Methods(http.MethodPatch)
+	r.HandleFunc("/tables/{id:[0-9]+}", h.deleteTable).Methods(http.MethodDelete)
 	r.HandleFunc("/guest_list", h.getAllGuests).Methods(http.MethodGet)
+	r.HandleFunc("/guest_list", h.importGuests).Methods(http.MethodPost)
 	r.HandleFunc("/guest_list/{name}", h.addGuest).Methods(http.MethodPost)
+	r.HandleFunc("/guest_list/{name}", h.updateGuest).Methods(http.MethodPatch)
+	r.HandleFunc("/guest_list/{name}", h.removeGuest).Methods(http.MethodDelete)
 	r.HandleFunc("/guests/{name}", h.checkInGuest).Methods(http.MethodPut)
 	r.HandleFunc("/guests", h.getAllCheckedInGuests).Methods(http.MethodGet)
 	r.HandleFunc("/seats_empty", h.countEmptySeat).Methods(http.MethodGet)
 	r.HandleFunc("/guests/{name}", h.checkoutGuest).Methods(http.MethodDelete)
+	r.HandleFunc("/guests/history", h.getGuestHistory).Methods(http.MethodGet)
+	r.HandleFunc("/waitlist", h.getWaitlist).Methods(http.MethodGet)
+	r.HandleFunc("/waitlist/{name}", h.joinWaitlist).Methods(http.MethodPost)
+	r.HandleFunc("/waitlist/{name}", h.leaveWaitlist).Methods(http.MethodDelete)
+	r.HandleFunc("/seating_constraints", h.createSeatingConstraint).Methods(http.MethodPost)
+	r.HandleFunc("/seating_constraints", h.getSeatingConstraints).Methods(http.MethodGet)
+	r.HandleFunc("/seating_constraints/{id:[0-9]+}", h.deleteSeatingConstraint).Methods(http.MethodDelete)
+	r.HandleFunc("/seating_plan/optimize", h.optimizeSeating).Methods(http.MethodPost)
+	r.HandleFunc("/seating_plan/apply", h.applySeatingPlan).Methods(http.MethodPost)
+	r.HandleFunc("/export/guest_list", h.exportGuestList).Methods(http.MethodGet)
+	r.HandleFunc("/export/checkins", h.exportCheckIns).Methods(http.MethodGet)
+	r.HandleFunc("/export/seating_chart", h.exportSeatingChart).Methods(http.MethodGet)
+	r.HandleFunc("/audit", h.getAuditLog).Methods(http.MethodGet)
 }
 
 type handler struct {
 	service GuestListService
 }
 
-func (h handler) createTable(w http.ResponseWriter, r *http.Request) {
-	var table entity.Table
-	err := json.NewDecoder(r.Body).Decode(&table)
+// streamHeartbeat is how often an idle stream sends a comment so proxies and
+// clients can tell the connection is still alive.
+var streamHeartbeat = 15 * time.Second
+
+// streamRetry is how long clients wait before reconnecting to a stream.
+const streamRetry = 3 * time.Second
+
+// eventID returns the event addressed by the request, falling back to the
+// default event on the unscoped routes.
+func eventID(r *http.Request) int {
+	id, err := strconv.Atoi(mux.Vars(r)["eventID"])
+	if err != nil {
+		return entity.DefaultEventID
+	}
+	return id
+}
+
+// nameRules are the rules of the guest names in paths, which become rows of
+// the guest list or the waitlist.
+const nameRules = "required,max=255"
+
+// guestName returns the guest name in the path of r, checked like the
+// fields of a request body.
+func guestName(r *http.Request) (string, error) {
+	name := mux.Vars(r)["name"]
+	return name, validate.Var("name", name, nameRules)
+}
+
+// requireEvent responds with 404 when the event in the path doesn't exist.
+func (h handler) requireEvent(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, err := h.service.GetEvent(r.Context(), eventID(r))
+		if err != nil {
+			writeError(w, r, err)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (h handler) createEvent(w http.ResponseWriter, r *http.Request) {
+	var requestBody entity.CreateEventRequestBody
+	err := validate.Decode(r, &requestBody)
+	if err != nil {
+		validate.Error(w, r, err)
+		return
+	}
+
+	event, err := h.service.CreateEvent(r.Context(), &requestBody)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(event)
+}
+
+func (h handler) getAllEvents(w http.ResponseWriter, r *http.Request) {
+	events, err := h.service.GetAllEvents(r.Context())
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	responseBody := entity.GetAllEventsResponseBody{
+		Events: events,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(responseBody)
+}
+
+func (h handler) getEvent(w http.ResponseWriter, r *http.Request) {
+	event, err := h.service.GetEvent(r.Context(), eventID(r))
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
 
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(event)
+}
+
+func (h handler) createTable(w http.ResponseWriter, r *http.Request) {
+	var requestBody entity.CreateTableRequestBody
+	err := validate.Decode(r, &requestBody)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		validate.Error(w, r, err)
 		return
 	}
 
-	newTable, err := h.service.CreateTable(&table)
+	table := entity.Table{Capacity: requestBody.Capacity, Attributes: requestBody.Attributes}
+	newTable, err := h.service.CreateTable(r.Context(), eventID(r), &table)
 
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		writeError(w, r, err)
 		return
 	}
 
@@ -43,24 +171,107 @@ func (h handler) createTable(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(newTable)
 }
 
+func (h handler) getAllTables(w http.ResponseWriter, r *http.Request) {
+	tables, err := h.service.GetAllTables(r.Context(), eventID(r))
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	responseBody := entity.GetAllTablesResponseBody{
+		Tables: tables,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(responseBody)
+}
+
+func (h handler) getTable(w http.ResponseWriter, r *http.Request) {
+	id, _ := strconv.Atoi(mux.Vars(r)["id"])
+
+	table, err := h.service.GetTable(r.Context(), eventID(r), id)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(table)
+}
+
+func (h handler) updateTable(w http.ResponseWriter, r *http.Request) {
+	id, _ := strconv.Atoi(mux.Vars(r)["id"])
+
+	var requestBody entity.UpdateTableRequestBody
+	err := validate.Decode(r, &requestBody)
+	if err != nil {
+		validate.Error(w, r, err)
+		return
+	}
+
+	table, err := h.service.UpdateTable(r.Context(), eventID(r), id, &requestBody)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(table)
+}
+
+func (h handler) deleteTable(w http.ResponseWriter, r *http.Request) {
+	id, _ := strconv.Atoi(mux.Vars(r)["id"])
+
+	// Guests seated at the table are only removed or moved when asked to
+	query := r.URL.Query()
+	options := entity.DeleteTableOptions{Guests: query.Get("guests")}
+	switch options.Guests {
+	case "", entity.DeleteTableCascade:
+	case entity.DeleteTableReassign:
+		reassignTo, err := strconv.Atoi(query.Get("reassign_to"))
+		if err != nil {
+			problem.Error(w, r, http.StatusBadRequest, "reassign_to must be a table id")
+			return
+		}
+		options.ReassignTo = reassignTo
+	default:
+		problem.Error(w, r, http.StatusBadRequest, "guests must be either cascade or reassign")
+		return
+	}
+
+	err := h.service.DeleteTable(r.Context(), eventID(r), id, options)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
 func (h handler) addGuest(w http.ResponseWriter, r *http.Request) {
-	vars := mux.Vars(r)
+	name, err := guestName(r)
+	if err != nil {
+		validate.Error(w, r, err)
+		return
+	}
 
 	var requestBody entity.AddGuestRequestBody
-	err := json.NewDecoder(r.Body).Decode(&requestBody)
+	err = validate.Decode(r, &requestBody)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		validate.Error(w, r, err)
 		return
 	}
 
 	var guest entity.Guest
-	guest.Name = vars["name"]
-	guest.TableID = requestBody.Table
+	guest.Name = name
+	if requestBody.Table != nil {
+		guest.TableID = *requestBody.Table
+	}
 	guest.AccompanyingGuests = requestBody.AccompanyingGuests
 
-	newGuest, err := h.service.AddGuest(&guest)
+	newGuest, err := h.service.AddGuest(r.Context(), eventID(r), &guest)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		writeError(w, r, err)
 		return
 	}
 
@@ -68,15 +279,106 @@ func (h handler) addGuest(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(newGuest)
 }
 
+func (h handler) updateGuest(w http.ResponseWriter, r *http.Request) {
+	name, err := guestName(r)
+	if err != nil {
+		validate.Error(w, r, err)
+		return
+	}
+
+	var requestBody entity.UpdateGuestRequestBody
+	err = validate.Decode(r, &requestBody)
+	if err != nil {
+		validate.Error(w, r, err)
+		return
+	}
+
+	updatedGuest, err := h.service.UpdateGuest(r.Context(), eventID(r), name, &requestBody)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(updatedGuest)
+}
+
+func (h handler) removeGuest(w http.ResponseWriter, r *http.Request) {
+	name, err := guestName(r)
+	if err != nil {
+		validate.Error(w, r, err)
+		return
+	}
+
+	err = h.service.RemoveGuest(r.Context(), eventID(r), name)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// importFormats maps the content types accepted by importGuests onto the
+// formats of ParseImport.
+var importFormats = map[string]string{
+	"text/csv":             ImportCSV,
+	"application/x-ndjson": ImportNDJSON,
+	"application/jsonl":    ImportNDJSON,
+	"application/json":     ImportNDJSON,
+}
+
+// maxImportBytes is the size limit of imported guest lists, which are
+// larger than the other request bodies.
+const maxImportBytes = 16 << 20
+
+func (h handler) importGuests(w http.ResponseWriter, r *http.Request) {
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	format, ok := importFormats[mediaType]
+	if !ok {
+		problem.Error(w, r, http.StatusUnsupportedMediaType, "guest lists must be imported as text/csv or application/x-ndjson")
+		return
+	}
+
+	mode := r.URL.Query().Get("mode")
+	if mode == "" {
+		mode = entity.ImportAtomic
+	}
+
+	rows, err := ParseImport(validate.LimitReader(r.Body, maxImportBytes), format)
+	if err != nil {
+		validate.Error(w, r, err)
+		return
+	}
+
+	result, err := h.service.ImportGuests(r.Context(), eventID(r), rows, mode)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	// A failed atomic import added nobody
+	status := http.StatusOK
+	if mode == entity.ImportAtomic && result.Failed > 0 {
+		status = http.StatusUnprocessableEntity
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(result)
+}
+
 func (h handler) getAllGuests(w http.ResponseWriter, r *http.Request) {
-	guests, err := h.service.GetAllGuests()
+	filter, err := parseGuestFilter(r)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		problem.Error(w, r, http.StatusBadRequest, err.Error())
 		return
 	}
 
-	responseBody := entity.GetAllGuestsResponseBody{
-		Guests: guests,
+	responseBody, err := h.service.GetAllGuests(r.Context(), eventID(r), filter)
+	if err != nil {
+		writeError(w, r, err)
+		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
@@ -84,22 +386,26 @@ func (h handler) getAllGuests(w http.ResponseWriter, r *http.Request) {
 }
 
 func (h handler) checkInGuest(w http.ResponseWriter, r *http.Request) {
-	vars := mux.Vars(r)
+	name, err := guestName(r)
+	if err != nil {
+		validate.Error(w, r, err)
+		return
+	}
 
 	var requestBody entity.CheckInGuestRequestBody
-	err := json.NewDecoder(r.Body).Decode(&requestBody)
+	err = validate.Decode(r, &requestBody)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		validate.Error(w, r, err)
 		return
 	}
 
 	var guest entity.Guest
-	guest.Name = vars["name"]
+	guest.Name = name
 	guest.AccompanyingGuests = requestBody.AccompanyingGuests
 
-	_, err = h.service.CheckInGuest(&guest)
+	_, err = h.service.CheckInGuest(r.Context(), eventID(r), &guest)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		writeError(w, r, err)
 		return
 	}
 
@@ -112,14 +418,16 @@ func (h handler) checkInGuest(w http.ResponseWriter, r *http.Request) {
 }
 
 func (h handler) getAllCheckedInGuests(w http.ResponseWriter, r *http.Request) {
-	checkedInGuests, err := h.service.GetAllCheckedInGuests()
+	filter, err := parseGuestFilter(r)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		problem.Error(w, r, http.StatusBadRequest, err.Error())
 		return
 	}
 
-	responseBody := entity.GetAllCheckedInGuestsResponseBody{
-		Guests: checkedInGuests,
+	responseBody, err := h.service.GetAllCheckedInGuests(r.Context(), eventID(r), filter)
+	if err != nil {
+		writeError(w, r, err)
+		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
@@ -127,9 +435,9 @@ func (h handler) getAllCheckedInGuests(w http.ResponseWriter, r *http.Request) {
 }
 
 func (h handler) countEmptySeat(w http.ResponseWriter, r *http.Request) {
-	emptySeats, err := h.service.CountEmptySeats()
+	emptySeats, err := h.service.CountEmptySeats(r.Context(), eventID(r))
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		writeError(w, r, err)
 		return
 	}
 
@@ -142,16 +450,369 @@ func (h handler) countEmptySeat(w http.ResponseWriter, r *http.Request) {
 }
 
 func (h handler) checkoutGuest(w http.ResponseWriter, r *http.Request) {
-	vars := mux.Vars(r)
+	name, err := guestName(r)
+	if err != nil {
+		validate.Error(w, r, err)
+		return
+	}
+
 	var guest entity.Guest
-	guest.Name = vars["name"]
-	err := h.service.CheckoutGuest(&guest)
+	guest.Name = name
+	err = h.service.CheckoutGuest(r.Context(), eventID(r), &guest)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		writeError(w, r, err)
 		return
 	}
 
 	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h handler) getGuestHistory(w http.ResponseWriter, r *http.Request) {
+	departedGuests, err := h.service.GetGuestHistory(r.Context(), eventID(r))
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	responseBody := entity.GetGuestHistoryResponseBody{
+		Guests: departedGuests,
+	}
+
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w)
+	json.NewEncoder(w).Encode(responseBody)
+}
+
+func (h handler) joinWaitlist(w http.ResponseWriter, r *http.Request) {
+	name, err := guestName(r)
+	if err != nil {
+		validate.Error(w, r, err)
+		return
+	}
+
+	var requestBody entity.JoinWaitlistRequestBody
+	err = validate.Decode(r, &requestBody)
+	if err != nil {
+		validate.Error(w, r, err)
+		return
+	}
+
+	entry, err := h.service.JoinWaitlist(r.Context(), eventID(r), name, &requestBody)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(entry)
+}
+
+func (h handler) getWaitlist(w http.ResponseWriter, r *http.Request) {
+	entries, err := h.service.GetWaitlist(r.Context(), eventID(r))
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	responseBody := entity.GetWaitlistResponseBody{
+		Waitlist: entries,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(responseBody)
+}
+
+func (h handler) leaveWaitlist(w http.ResponseWriter, r *http.Request) {
+	name, err := guestName(r)
+	if err != nil {
+		validate.Error(w, r, err)
+		return
+	}
+
+	err = h.service.LeaveWaitlist(r.Context(), eventID(r), name)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h handler) createSeatingConstraint(w http.ResponseWriter, r *http.Request) {
+	var requestBody entity.CreateSeatingConstraintRequestBody
+	err := validate.Decode(r, &requestBody)
+	if err != nil {
+		validate.Error(w, r, err)
+		return
+	}
+
+	constraint, err := h.service.CreateSeatingConstraint(r.Context(), eventID(r), &requestBody)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(constraint)
+}
+
+func (h handler) getSeatingConstraints(w http.ResponseWriter, r *http.Request) {
+	constraints, err := h.service.GetSeatingConstraints(r.Context(), eventID(r))
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	responseBody := entity.GetAllSeatingConstraintsResponseBody{
+		Constraints: constraints,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(responseBody)
+}
+
+func (h handler) deleteSeatingConstraint(w http.ResponseWriter, r *http.Request) {
+	id, _ := strconv.Atoi(mux.Vars(r)["id"])
+
+	err := h.service.DeleteSeatingConstraint(r.Context(), eventID(r), id)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h handler) optimizeSeating(w http.ResponseWriter, r *http.Request) {
+	plan, err := h.service.OptimizeSeating(r.Context(), eventID(r))
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(plan)
+}
+
+func (h handler) applySeatingPlan(w http.ResponseWriter, r *http.Request) {
+	plan, err := h.service.ApplySeatingPlan(r.Context(), eventID(r))
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(plan)
+}
+
+func (h handler) exportGuestList(w http.ResponseWriter, r *http.Request) {
+	format, status, err := exportFormat(r)
+	if err != nil {
+		problem.Error(w, r, status, err.Error())
+		return
+	}
+
+	guests, err := h.service.ListGuests(r.Context(), eventID(r))
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	writeReport(w, format, "guest_list", guestListReport(guests))
+}
+
+func (h handler) exportCheckIns(w http.ResponseWriter, r *http.Request) {
+	format, status, err := exportFormat(r)
+	if err != nil {
+		problem.Error(w, r, status, err.Error())
+		return
+	}
+
+	guests, err := h.service.GetCheckInLog(r.Context(), eventID(r))
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	writeReport(w, format, "checkins", checkInLogReport(guests))
+}
+
+func (h handler) exportSeatingChart(w http.ResponseWriter, r *http.Request) {
+	format, status, err := exportFormat(r)
+	if err != nil {
+		problem.Error(w, r, status, err.Error())
+		return
+	}
+
+	chart, err := h.service.GetSeatingChart(r.Context(), eventID(r))
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	writeReport(w, format, "seating_chart", seatingChartReport(chart))
+}
+
+// streamEvents pushes the changes to the guest list as Server-Sent Events.
+// Clients reconnecting with a Last-Event-ID header get the events they
+// missed first.
+func (h handler) streamEvents(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		problem.Error(w, r, http.StatusInternalServerError, "streaming is not supported")
+		return
+	}
+
+	var lastEventID int64
+	if header := r.Header.Get("Last-Event-ID"); header != "" {
+		var err error
+		lastEventID, err = strconv.ParseInt(header, 10, 64)
+		if err != nil || lastEventID < 0 {
+			problem.Error(w, r, http.StatusBadRequest, "Last-Event-ID must be an event id")
+			return
+		}
+	}
+
+	sub := h.service.Subscribe(eventID(r), lastEventID)
+	defer sub.Close()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	// Keep reverse proxies from buffering the stream
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	fmt.Fprintf(w, "retry: %d\n\n", streamRetry.Milliseconds())
+	flusher.Flush()
+
+	heartbeat := time.NewTicker(streamHeartbeat)
+	defer heartbeat.Stop()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case event, ok := <-sub.Events():
+			// The subscription ends when the client fell too far behind or
+			// the server shuts down, either way it should reconnect
+			if !ok {
+				return
+			}
+			data, err := json.Marshal(event)
+			if err != nil {
+				return
+			}
+			fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", event.ID, event.Type, data)
+		case <-heartbeat.C:
+			fmt.Fprint(w, ": heartbeat\n\n")
+		}
+		flusher.Flush()
+	}
+}
+
+func (h handler) getAuditLog(w http.ResponseWriter, r *http.Request) {
+	filter, err := parseAuditFilter(r)
+	if err != nil {
+		problem.Error(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	entries, err := h.service.GetAuditLog(r.Context(), eventID(r), filter)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	responseBody := entity.GetAuditLogResponseBody{
+		Entries: entries,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(responseBody)
+}
+
+// parseAuditFilter reads the filter of the audit log from the query string.
+func parseAuditFilter(r *http.Request) (entity.AuditFilter, error) {
+	var filter entity.AuditFilter
+	query := r.URL.Query()
+
+	if guest := query.Get("guest"); guest != "" {
+		filter.Guest = &guest
+	}
+	if actor := query.Get("actor"); actor != "" {
+		filter.Actor = &actor
+	}
+	if table := query.Get("table"); table != "" {
+		id, err := strconv.Atoi(table)
+		if err != nil {
+			return filter, fmt.Errorf("table must be a table id")
+		}
+		filter.Table = &id
+	}
+	var err error
+	filter.Since, err = parseQueryTime(query, "since")
+	if err != nil {
+		return filter, err
+	}
+	filter.Until, err = parseQueryTime(query, "until")
+	if err != nil {
+		return filter, err
+	}
+
+	return filter, nil
+}
+
+// parseGuestFilter reads the filter of guest listings from the query string.
+func parseGuestFilter(r *http.Request) (entity.GuestFilter, error) {
+	query := r.URL.Query()
+	filter := entity.GuestFilter{Sort: query.Get("sort"), Cursor: query.Get("cursor")}
+
+	if table := query.Get("table"); table != "" {
+		id, err := strconv.Atoi(table)
+		if err != nil {
+			return filter, fmt.Errorf("table must be a table id")
+		}
+		filter.Table = &id
+	}
+	if prefix, ok := query["name_prefix"]; ok {
+		filter.NamePrefix = &prefix[0]
+	}
+	if checkedIn := query.Get("checked_in"); checkedIn != "" {
+		value, err := strconv.ParseBool(checkedIn)
+		if err != nil {
+			return filter, fmt.Errorf("checked_in must be true or false")
+		}
+		filter.CheckedIn = &value
+	}
+	if limit := query.Get("limit"); limit != "" {
+		n, err := strconv.Atoi(limit)
+		if err != nil || n < 1 {
+			return filter, fmt.Errorf("limit must be a positive number")
+		}
+		filter.Limit = n
+	}
+	var err error
+	filter.ArrivedSince, err = parseQueryTime(query, "arrived_since")
+	if err != nil {
+		return filter, err
+	}
+	filter.ArrivedUntil, err = parseQueryTime(query, "arrived_until")
+	if err != nil {
+		return filter, err
+	}
+
+	return filter, nil
+}
+
+// parseQueryTime reads the RFC 3339 time in the query parameter param, nil
+// when it is absent.
+func parseQueryTime(query url.Values, param string) (*time.Time, error) {
+	value := query.Get(param)
+	if value == "" {
+		return nil, nil
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return nil, fmt.Errorf("%s must be an RFC 3339 time", param)
+	}
+	t = t.UTC()
+	return &t, nil
 }
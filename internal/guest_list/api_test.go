@@ -1,26 +1,32 @@
 package guest_list
 
 import (
+	"fmt"
 	"log"
 	"net/http"
+	"strings"
 	"testing"
 
 	"github.com/getground/tech-tasks/backend/internal/entity"
 	"github.com/getground/tech-tasks/backend/internal/test"
-	"github.com/getground/tech-tasks/backend/pkg/database"
 	"github.com/gorilla/mux"
 )
 
 func TestAPI(t *testing.T) {
-	dbClient, err := database.NewClient(dsn)
+	dbClient, err := newTestClient()
 	if err != nil {
 		log.Fatal(err)
 	}
 	defer dbClient.Close()
 
 	// Cleanup tables
-	cleanupTable(dbClient, "table")
+	cleanupTable(dbClient, "audit")
+	cleanupTable(dbClient, "outbox")
+	cleanupTable(dbClient, "seating_constraint")
+	cleanupTable(dbClient, "waitlist")
 	cleanupTable(dbClient, "guest")
+	cleanupTable(dbClient, "table")
+	cleanupEvents(dbClient)
 
 	// Register routes
 	r := mux.NewRouter()
@@ -31,12 +37,24 @@ func TestAPI(t *testing.T) {
 	// Create new table
 	var table entity.Table
 	table.Capacity = 5
-	tableResponse, err := guestListService.CreateTable(&table)
+	tableResponse, err := guestListService.CreateTable(ctx, entity.DefaultEventID, &table)
 	if err != nil {
 		log.Fatal(err)
 	}
 	table.ID = tableResponse.ID
 
+	// Create a second event with its own table
+	event, err := guestListService.CreateEvent(ctx, &entity.CreateEventRequestBody{Name: "Afterparty"})
+	if err != nil {
+		log.Fatal(err)
+	}
+	eventTable := entity.Table{Capacity: 3}
+	eventTableResponse, err := guestListService.CreateTable(ctx, event.ID, &eventTable)
+	if err != nil {
+		log.Fatal(err)
+	}
+	eventTable.ID = eventTableResponse.ID
+
 	tests := []test.APITestCase{
 		{
 			Name:   "Create table",
@@ -55,12 +73,135 @@ func TestAPI(t *testing.T) {
 			Method: "POST",
 			URL:    "/guest_list/john",
 			Body: entity.AddGuestRequestBody{
-				Table:              table.ID,
+				Table:              &table.ID,
 				AccompanyingGuests: 0,
 			},
 			ExpectedStatus: http.StatusOK,
 			ExpectedResponse: map[string]interface{}{
-				"name": "john",
+				"name":  "john",
+				"table": table.ID,
+			},
+		},
+		{
+			Name:   "Add an existing guest",
+			Method: "POST",
+			URL:    "/guest_list/john",
+			Body: entity.AddGuestRequestBody{
+				Table:              &table.ID,
+				AccompanyingGuests: 0,
+			},
+			ExpectedStatus: http.StatusConflict,
+			ExpectedResponse: map[string]interface{}{
+				"status": http.StatusConflict,
+				"detail": "guest with name john already exists",
+			},
+		},
+		{
+			Name:   "Add a guest to a missing table",
+			Method: "POST",
+			URL:    "/guest_list/rob",
+			Body: map[string]interface{}{
+				"table":               table.ID + 100,
+				"accompanying_guests": 0,
+			},
+			ExpectedStatus: http.StatusNotFound,
+			ExpectedResponse: map[string]interface{}{
+				"status": http.StatusNotFound,
+			},
+		},
+		{
+			Name:   "Add a guest to a full table",
+			Method: "POST",
+			URL:    "/guest_list/rob",
+			Body: entity.AddGuestRequestBody{
+				Table:              &table.ID,
+				AccompanyingGuests: 10,
+			},
+			ExpectedStatus: http.StatusUnprocessableEntity,
+			ExpectedResponse: map[string]interface{}{
+				"status": http.StatusUnprocessableEntity,
+			},
+		},
+		{
+			Name:   "Add a guest with a negative party size",
+			Method: "POST",
+			URL:    "/guest_list/rob",
+			Body: entity.AddGuestRequestBody{
+				Table:              &table.ID,
+				AccompanyingGuests: -1,
+			},
+			ExpectedStatus: http.StatusBadRequest,
+			ExpectedResponse: map[string]interface{}{
+				"status": http.StatusBadRequest,
+				"detail": "the request has invalid fields",
+			},
+		},
+		{
+			Name:   "Add a guest with an unknown field",
+			Method: "POST",
+			URL:    "/guest_list/rob",
+			Body: map[string]interface{}{
+				"table":  table.ID,
+				"plus_1": true,
+			},
+			ExpectedStatus: http.StatusBadRequest,
+		},
+		{
+			Name:   "Create a table without seats",
+			Method: "POST",
+			URL:    "/tables",
+			Body: entity.CreateTableRequestBody{
+				Capacity: 0,
+			},
+			ExpectedStatus: http.StatusBadRequest,
+		},
+		{
+			Name:   "Add a guest to remove",
+			Method: "POST",
+			URL:    "/guest_list/rob",
+			Body: entity.AddGuestRequestBody{
+				Table:              &table.ID,
+				AccompanyingGuests: 1,
+			},
+			ExpectedStatus: http.StatusOK,
+		},
+		{
+			Name:   "Update guest party size",
+			Method: "PATCH",
+			URL:    "/guest_list/rob",
+			Body: map[string]interface{}{
+				"accompanying_guests": 2,
+			},
+			ExpectedStatus: http.StatusOK,
+			ExpectedResponse: map[string]interface{}{
+				"name":                "rob",
+				"table":               table.ID,
+				"accompanying_guests": 2,
+			},
+		},
+		{
+			Name:             "Remove guest",
+			Method:           "DELETE",
+			URL:              "/guest_list/rob",
+			Body:             nil,
+			ExpectedStatus:   http.StatusNoContent,
+			ExpectedResponse: nil,
+		},
+		{
+			Name:           "Remove unknown guest",
+			Method:         "DELETE",
+			URL:            "/guest_list/rob",
+			Body:           nil,
+			ExpectedStatus: http.StatusNotFound,
+		},
+		{
+			Name:           "Check out guest before arrival",
+			Method:         "DELETE",
+			URL:            "/guests/john",
+			Body:           nil,
+			ExpectedStatus: http.StatusUnprocessableEntity,
+			ExpectedResponse: map[string]interface{}{
+				"detail": "guest `john` is not checked in",
 			},
 		},
 		{
@@ -79,6 +220,31 @@ func TestAPI(t *testing.T) {
 				},
 			},
 		},
+		{
+			Name:           "Get guests by name prefix",
+			Method:         "GET",
+			URL:            "/guest_list?name_prefix=jo&sort=-name&limit=1",
+			ExpectedStatus: http.StatusOK,
+			ExpectedResponse: map[string]interface{}{
+				"guests": []interface{}{
+					map[string]interface{}{
+						"name": "john",
+					},
+				},
+			},
+		},
+		{
+			Name:           "Get guests with a malformed filter",
+			Method:         "GET",
+			URL:            "/guest_list?checked_in=maybe",
+			ExpectedStatus: http.StatusBadRequest,
+		},
+		{
+			Name:           "Get guests in an unknown order",
+			Method:         "GET",
+			URL:            "/guest_list?sort=table",
+			ExpectedStatus: http.StatusBadRequest,
+		},
 		{
 			Name:   "Check in guest",
 			Method: "PUT",
@@ -91,6 +257,30 @@ func TestAPI(t *testing.T) {
 				"name": "john",
 			},
 		},
+		{
+			Name:   "Check in guest twice",
+			Method: "PUT",
+			URL:    "/guests/john",
+			Body: map[string]interface{}{
+				"accompanying_guests": 3,
+			},
+			ExpectedStatus: http.StatusConflict,
+			ExpectedResponse: map[string]interface{}{
+				"status": http.StatusConflict,
+			},
+		},
+		{
+			Name:   "Check in unknown guest",
+			Method: "PUT",
+			URL:    "/guests/rob",
+			Body: map[string]interface{}{
+				"accompanying_guests": 0,
+			},
+			ExpectedStatus: http.StatusNotFound,
+			ExpectedResponse: map[string]interface{}{
+				"detail": "found no guest called `rob`",
+			},
+		},
 		{
 			Name:           "Get all checked in guests",
 			Method:         "GET",
@@ -115,6 +305,75 @@ func TestAPI(t *testing.T) {
 				"seats_empty": 6,
 			},
 		},
+		{
+			Name:           "Get all tables",
+			Method:         "GET",
+			URL:            "/tables",
+			Body:           nil,
+			ExpectedStatus: http.StatusOK,
+			ExpectedResponse: map[string]interface{}{
+				"tables": []interface{}{
+					map[string]interface{}{
+						"id":             table.ID,
+						"capacity":       5,
+						"reserved_seats": 4,
+					},
+				},
+			},
+		},
+		{
+			Name:           "Get table",
+			Method:         "GET",
+			URL:            fmt.Sprintf("/tables/%d", table.ID),
+			Body:           nil,
+			ExpectedStatus: http.StatusOK,
+			ExpectedResponse: map[string]interface{}{
+				"capacity":       5,
+				"reserved_seats": 4,
+			},
+		},
+		{
+			Name:   "Shrink table below reserved seats",
+			Method: "PATCH",
+			URL:    fmt.Sprintf("/tables/%d", table.ID),
+			Body: map[string]interface{}{
+				"capacity": 3,
+			},
+			ExpectedStatus: http.StatusUnprocessableEntity,
+			ExpectedResponse: map[string]interface{}{
+				"status": http.StatusUnprocessableEntity,
+			},
+		},
+		{
+			Name:   "Resize table",
+			Method: "PATCH",
+			URL:    fmt.Sprintf("/tables/%d", table.ID),
+			Body: map[string]interface{}{
+				"capacity": 8,
+			},
+			ExpectedStatus: http.StatusOK,
+			ExpectedResponse: map[string]interface{}{
+				"capacity":       8,
+				"reserved_seats": 4,
+			},
+		},
+		{
+			Name:           "Delete table with guests",
+			Method:         "DELETE",
+			URL:            fmt.Sprintf("/tables/%d", table.ID),
+			Body:           nil,
+			ExpectedStatus: http.StatusConflict,
+			ExpectedResponse: map[string]interface{}{
+				"status": http.StatusConflict,
+			},
+		},
+		{
+			Name:           "Delete table with an unknown guest option",
+			Method:         "DELETE",
+			URL:            fmt.Sprintf("/tables/%d?guests=drop", table.ID),
+			Body:           nil,
+			ExpectedStatus: http.StatusBadRequest,
+		},
 		{
 			Name:             "Check out guest",
 			Method:           "DELETE",
@@ -123,6 +382,249 @@ func TestAPI(t *testing.T) {
 			ExpectedStatus:   http.StatusNoContent,
 			ExpectedResponse: nil,
 		},
+		{
+			Name:           "Get guest history",
+			Method:         "GET",
+			URL:            "/guests/history",
+			Body:           nil,
+			ExpectedStatus: http.StatusOK,
+			ExpectedResponse: map[string]interface{}{
+				"guests": []interface{}{
+					map[string]interface{}{
+						"name":                "john",
+						"accompanying_guests": 3,
+					},
+				},
+			},
+		},
+		{
+			Name:   "Create event",
+			Method: "POST",
+			URL:    "/events",
+			Body: entity.CreateEventRequestBody{
+				Name:  "Brunch",
+				Venue: "Garden",
+			},
+			ExpectedStatus: http.StatusOK,
+			ExpectedResponse: map[string]interface{}{
+				"name":  "Brunch",
+				"venue": "Garden",
+			},
+		},
+		{
+			Name:           "Get event",
+			Method:         "GET",
+			URL:            fmt.Sprintf("/events/%d", event.ID),
+			Body:           nil,
+			ExpectedStatus: http.StatusOK,
+			ExpectedResponse: map[string]interface{}{
+				"id":   event.ID,
+				"name": "Afterparty",
+			},
+		},
+		{
+			Name:   "Add a guest with the same name to another event",
+			Method: "POST",
+			URL:    fmt.Sprintf("/events/%d/guest_list/john", event.ID),
+			Body: entity.AddGuestRequestBody{
+				Table:              &eventTable.ID,
+				AccompanyingGuests: 1,
+			},
+			ExpectedStatus: http.StatusOK,
+			ExpectedResponse: map[string]interface{}{
+				"name": "john",
+			},
+		},
+		{
+			Name:   "Add a guest to a table of another event",
+			Method: "POST",
+			URL:    fmt.Sprintf("/events/%d/guest_list/jane", event.ID),
+			Body: entity.AddGuestRequestBody{
+				Table:              &table.ID,
+				AccompanyingGuests: 0,
+			},
+			ExpectedStatus: http.StatusNotFound,
+		},
+		{
+			Name:           "Count empty seats of an event",
+			Method:         "GET",
+			URL:            fmt.Sprintf("/events/%d/seats_empty", event.ID),
+			Body:           nil,
+			ExpectedStatus: http.StatusOK,
+			ExpectedResponse: map[string]interface{}{
+				"seats_empty": 1,
+			},
+		},
+		{
+			Name:           "Get tables of an unknown event",
+			Method:         "GET",
+			URL:            fmt.Sprintf("/events/%d/tables", event.ID+100),
+			Body:           nil,
+			ExpectedStatus: http.StatusNotFound,
+			ExpectedResponse: map[string]interface{}{
+				"status": http.StatusNotFound,
+			},
+		},
+		{
+			Name:   "Join the waitlist of a full table",
+			Method: "POST",
+			URL:    fmt.Sprintf("/events/%d/waitlist/jane", event.ID),
+			Body: entity.JoinWaitlistRequestBody{
+				Table:              &eventTable.ID,
+				AccompanyingGuests: 1,
+			},
+			ExpectedStatus: http.StatusOK,
+			ExpectedResponse: map[string]interface{}{
+				"name":     "jane",
+				"status":   entity.WaitlistStatusWaiting,
+				"table_id": eventTable.ID,
+			},
+		},
+		{
+			Name:           "Get waitlist",
+			Method:         "GET",
+			URL:            fmt.Sprintf("/events/%d/waitlist", event.ID),
+			Body:           nil,
+			ExpectedStatus: http.StatusOK,
+			ExpectedResponse: map[string]interface{}{
+				"waitlist": []interface{}{
+					map[string]interface{}{
+						"name":                "jane",
+						"accompanying_guests": 1,
+						"status":              entity.WaitlistStatusWaiting,
+					},
+				},
+			},
+		},
+		{
+			Name:             "Leave the waitlist",
+			Method:           "DELETE",
+			URL:              fmt.Sprintf("/events/%d/waitlist/jane", event.ID),
+			Body:             nil,
+			ExpectedStatus:   http.StatusNoContent,
+			ExpectedResponse: nil,
+		},
+		{
+			Name:           "Leave the waitlist twice",
+			Method:         "DELETE",
+			URL:            fmt.Sprintf("/events/%d/waitlist/jane", event.ID),
+			Body:           nil,
+			ExpectedStatus: http.StatusNotFound,
+		},
+		{
+			Name:   "Add a seating constraint",
+			Method: "POST",
+			URL:    fmt.Sprintf("/events/%d/seating_constraints", event.ID),
+			Body: map[string]interface{}{
+				"kind":      entity.ConstraintRequires,
+				"guest":     "john",
+				"attribute": "quiet",
+			},
+			ExpectedStatus: http.StatusOK,
+			ExpectedResponse: map[string]interface{}{
+				"kind":      entity.ConstraintRequires,
+				"guest":     "john",
+				"attribute": "quiet",
+			},
+		},
+		{
+			Name:           "Preview the seating plan",
+			Method:         "POST",
+			URL:            fmt.Sprintf("/events/%d/seating_plan/optimize", event.ID),
+			Body:           nil,
+			ExpectedStatus: http.StatusOK,
+			ExpectedResponse: map[string]interface{}{
+				"moves":   0,
+				"applied": false,
+				"violations": []interface{}{
+					map[string]interface{}{
+						"kind":  entity.ConstraintRequires,
+						"guest": "john",
+					},
+				},
+			},
+		},
+		{
+			Name:   "Add a seating constraint with an unknown kind",
+			Method: "POST",
+			URL:    fmt.Sprintf("/events/%d/seating_constraints", event.ID),
+			Body: map[string]interface{}{
+				"kind":  "near",
+				"guest": "john",
+			},
+			ExpectedStatus: http.StatusBadRequest,
+		},
+		{
+			Name:   "Import guests",
+			Method: "POST",
+			URL:    fmt.Sprintf("/events/%d/guest_list?mode=best-effort", event.ID),
+			Body: map[string]interface{}{
+				"name": "liz",
+			},
+			ExpectedStatus: http.StatusOK,
+			ExpectedResponse: map[string]interface{}{
+				"mode":     entity.ImportBestEffort,
+				"imported": 1,
+				"failed":   0,
+			},
+		},
+		{
+			Name:   "Import guests atomically",
+			Method: "POST",
+			URL:    fmt.Sprintf("/events/%d/guest_list", event.ID),
+			Body: map[string]interface{}{
+				"name": "john",
+			},
+			ExpectedStatus: http.StatusUnprocessableEntity,
+			ExpectedResponse: map[string]interface{}{
+				"mode":   entity.ImportAtomic,
+				"failed": 1,
+			},
+		},
+		{
+			Name:           "Export in an unknown format",
+			Method:         "GET",
+			URL:            "/export/guest_list?format=xml",
+			ExpectedStatus: http.StatusBadRequest,
+		},
+		{
+			Name:           "Export the seating chart as JSON lines",
+			Method:         "GET",
+			URL:            fmt.Sprintf("/events/%d/export/seating_chart?format=ndjson", event.ID),
+			ExpectedStatus: http.StatusOK,
+			ExpectedResponse: map[string]interface{}{
+				"id":       eventTable.ID,
+				"capacity": 3,
+			},
+		},
+		{
+			Name:           "Get the audit log with a malformed time",
+			Method:         "GET",
+			URL:            "/audit?since=yesterday",
+			ExpectedStatus: http.StatusBadRequest,
+		},
+		{
+			Name:           "Get the audit log with an empty time range",
+			Method:         "GET",
+			URL:            "/audit?since=2022-01-02T00:00:00Z&until=2022-01-01T00:00:00Z",
+			ExpectedStatus: http.StatusBadRequest,
+		},
+	}
+	// Test every guest name in a path is checked like the ones in bodies
+	for _, endpoint := range []struct{ method, path string }{
+		{"PATCH", "/guest_list/"},
+		{"DELETE", "/guest_list/"},
+		{"PUT", "/guests/"},
+		{"DELETE", "/guests/"},
+		{"DELETE", "/waitlist/"},
+	} {
+		tests = append(tests, test.APITestCase{
+			Name:           fmt.Sprintf("%s %s with a name too long", endpoint.method, endpoint.path),
+			Method:         endpoint.method,
+			URL:            endpoint.path + strings.Repeat("j", 256),
+			Body:           map[string]interface{}{},
+			ExpectedStatus: http.StatusBadRequest,
+		})
 	}
 
 	for _, tc := range tests {
@@ -0,0 +1,102 @@
+package guest_list
+
+import (
+	"fmt"
+
+	"github.com/getground/tech-tasks/backend/internal/entity"
+)
+
+// TableAssigner picks the table a new party is seated at when the caller
+// doesn't choose one. tables are ordered by id and seats is the size of the
+// party. It returns false when no table has room.
+type TableAssigner interface {
+	AssignTable(tables []entity.Table, seats int) (int, bool)
+}
+
+// TableAssignerFunc adapts a function to the TableAssigner interface.
+type TableAssignerFunc func(tables []entity.Table, seats int) (int, bool)
+
+func (f TableAssignerFunc) AssignTable(tables []entity.Table, seats int) (int, bool) {
+	return f(tables, seats)
+}
+
+// Names of the built in table assignment strategies.
+const (
+	AssignBestFit             = "best-fit"
+	AssignFirstFit            = "first-fit"
+	AssignKeepPartiesTogether = "keep-parties-together"
+	AssignFillEvenly          = "fill-evenly"
+)
+
+var tableAssigners = map[string]TableAssigner{
+	AssignBestFit:             TableAssignerFunc(bestFit),
+	AssignFirstFit:            TableAssignerFunc(firstFit),
+	AssignKeepPartiesTogether: TableAssignerFunc(keepPartiesTogether),
+	AssignFillEvenly:          TableAssignerFunc(fillEvenly),
+}
+
+// TableAssignerByName returns the built in strategy called name.
+func TableAssignerByName(name string) (TableAssigner, error) {
+	assigner, ok := tableAssigners[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown table assignment strategy %s", name)
+	}
+	return assigner, nil
+}
+
+// WithTableAssigner makes the service seat guests without a table using
+// assigner instead of best-fit.
+func WithTableAssigner(assigner TableAssigner) Option {
+	return func(s *service) {
+		s.tableAssigner = assigner
+	}
+}
+
+// pickTable returns the table that fits seats and ranks lowest by less,
+// keeping the lowest id on ties.
+func pickTable(tables []entity.Table, seats int, less func(a, b entity.Table) bool) (int, bool) {
+	best := -1
+	for i, table := range tables {
+		if table.ReservedSeats+seats > table.Capacity {
+			continue
+		}
+		if best < 0 || less(table, tables[best]) {
+			best = i
+		}
+	}
+	if best < 0 {
+		return 0, false
+	}
+	return tables[best].ID, true
+}
+
+// bestFit seats the party where the fewest seats are left over.
+func bestFit(tables []entity.Table, seats int) (int, bool) {
+	return pickTable(tables, seats, func(a, b entity.Table) bool {
+		return a.Capacity-a.ReservedSeats < b.Capacity-b.ReservedSeats
+	})
+}
+
+// firstFit seats the party at the first table with room.
+func firstFit(tables []entity.Table, seats int) (int, bool) {
+	return pickTable(tables, seats, func(a, b entity.Table) bool {
+		return false
+	})
+}
+
+// keepPartiesTogether seats the party next to the most guests, only opening
+// empty tables once the occupied ones are full.
+func keepPartiesTogether(tables []entity.Table, seats int) (int, bool) {
+	return pickTable(tables, seats, func(a, b entity.Table) bool {
+		return a.ReservedSeats > b.ReservedSeats
+	})
+}
+
+// fillEvenly seats the party where the table ends up the least full.
+func fillEvenly(tables []entity.Table, seats int) (int, bool) {
+	return pickTable(tables, seats, func(a, b entity.Table) bool {
+		// Compare (a.ReservedSeats+seats)/a.Capacity with the same for b
+		// without dividing
+		return (a.ReservedSeats+seats)*b.Capacity < (b.ReservedSeats+seats)*a.Capacity
+	})
+}
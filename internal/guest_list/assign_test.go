@@ -0,0 +1,50 @@
+package guest_list
+
+import (
+	"testing"
+
+	"github.com/getground/tech-tasks/backend/internal/entity"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTableAssigners(t *testing.T) {
+	tables := []entity.Table{
+		{ID: 1, Capacity: 10, ReservedSeats: 0},
+		{ID: 2, Capacity: 4, ReservedSeats: 1},
+		{ID: 3, Capacity: 6, ReservedSeats: 4},
+		{ID: 4, Capacity: 8, ReservedSeats: 5},
+	}
+
+	tests := []struct {
+		strategy string
+		seats    int
+		expected int
+	}{
+		{AssignBestFit, 2, 3},
+		{AssignBestFit, 3, 2},
+		{AssignFirstFit, 2, 1},
+		{AssignKeepPartiesTogether, 2, 4},
+		{AssignKeepPartiesTogether, 4, 1},
+		{AssignFillEvenly, 2, 1},
+		{AssignFillEvenly, 9, 1},
+	}
+
+	for _, tc := range tests {
+		assigner, err := TableAssignerByName(tc.strategy)
+		assert.Nil(t, err, "Error while looking up strategy %s, %v", tc.strategy, err)
+
+		id, ok := assigner.AssignTable(tables, tc.seats)
+		assert.True(t, ok, "Expected %s to find a table for %d seats", tc.strategy, tc.seats)
+		assert.Equal(t, tc.expected, id, "Unexpected table picked by %s for %d seats", tc.strategy, tc.seats)
+	}
+
+	// Test a party too large for every table
+	for name, assigner := range tableAssigners {
+		_, ok := assigner.AssignTable(tables, 11)
+		assert.False(t, ok, "Expected %s to find no table", name)
+	}
+
+	// Test an unknown strategy
+	_, err := TableAssignerByName("random")
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,180 @@
+package guest_list
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+
+	"github.com/getground/tech-tasks/backend/internal/entity"
+	"github.com/getground/tech-tasks/backend/pkg/database"
+	"github.com/getground/tech-tasks/backend/pkg/problem"
+)
+
+// ActorHeader names the person or device making a request, as recorded in
+// the audit log.
+const ActorHeader = "X-Actor"
+
+// maxActorLength is the size of the actor column.
+const maxActorLength = 255
+
+type actorKey struct{}
+
+// ContextWithActor returns a copy of ctx whose changes are attributed to
+// actor in the audit log.
+func ContextWithActor(ctx context.Context, actor string) context.Context {
+	return context.WithValue(ctx, actorKey{}, actor)
+}
+
+// ActorFromContext returns the actor changes made with ctx are attributed to.
+func ActorFromContext(ctx context.Context) string {
+	actor, ok := ctx.Value(actorKey{}).(string)
+	if !ok || actor == "" {
+		return entity.AuditActorAnonymous
+	}
+	return actor
+}
+
+// ActorMiddleware attributes the changes made by a request to the actor named
+// in its ActorHeader, unless an earlier middleware such as authentication
+// already named one.
+func ActorMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, ok := r.Context().Value(actorKey{}).(string); ok {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		actor := r.Header.Get(ActorHeader)
+		if len(actor) > maxActorLength {
+			problem.Error(w, r, http.StatusBadRequest, fmt.Sprintf("%s must be at most %d bytes", ActorHeader, maxActorLength))
+			return
+		}
+		if actor != "" {
+			r = r.WithContext(ContextWithActor(r.Context(), actor))
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// auditGuest records a change made in tx to the guest called name. before is
+// nil for added guests, the state after the change is read back from tx.
+func (s *service) auditGuest(ctx context.Context, tx database.Tx, eventID int, action string, name string, before *entity.Guest) error {
+	var after *entity.Guest
+	var guest entity.Guest
+	err := tx.FindUnique(ctx, &guest, "guest", guestKey(eventID, name))
+	if err == nil {
+		after = &guest
+	} else if !errors.Is(err, database.ErrNotFound) {
+		return err
+	}
+
+	var tableID *int
+	if after != nil {
+		tableID = &after.TableID
+	} else if before != nil {
+		tableID = &before.TableID
+	}
+	return s.audit(ctx, tx, eventID, action, entity.AuditEntityGuest, &name, tableID, before, after)
+}
+
+// auditTable records a change made in tx to the table with the given id.
+// before is nil for created tables, the state after the change is read back
+// from tx.
+func (s *service) auditTable(ctx context.Context, tx database.Tx, eventID int, action string, id int, before *entity.Table) error {
+	var after *entity.Table
+	var table entity.Table
+	err := tx.FindUnique(ctx, &table, "table", tableKey(eventID, id))
+	if err == nil {
+		after = &table
+	} else if !errors.Is(err, database.ErrNotFound) {
+		return err
+	}
+
+	return s.audit(ctx, tx, eventID, action, entity.AuditEntityTable, nil, &id, before, after)
+}
+
+// auditReservedSeats records the reserved seats of table changed in tx.
+// before is the table as it was before the change.
+func (s *service) auditReservedSeats(ctx context.Context, tx database.Tx, before entity.Table) error {
+	return s.auditTable(ctx, tx, before.EventID, entity.AuditTableUpdated, before.ID, &before)
+}
+
+// auditWaitlist records a change made in tx to a waitlist entry. before is
+// nil for joining parties and after for leaving ones.
+func (s *service) auditWaitlist(ctx context.Context, tx database.Tx, eventID int, action string, before *entity.WaitlistEntry, after *entity.WaitlistEntry) error {
+	entry := after
+	if entry == nil {
+		entry = before
+	}
+	return s.audit(ctx, tx, eventID, action, entity.AuditEntityWaitlist, &entry.Name, entry.TableID, before, after)
+}
+
+// audit writes an audit entry attributed to the actor of ctx within tx, so
+// the entry exists if and only if the change commits.
+func (s *service) audit(ctx context.Context, tx database.Tx, eventID int, action string, entityType string, guest *string, tableID *int, before interface{}, after interface{}) error {
+	beforeState, err := auditState(before)
+	if err != nil {
+		return err
+	}
+	afterState, err := auditState(after)
+	if err != nil {
+		return err
+	}
+
+	columns := []string{"event_id", "actor", "action", "entity_type", "guest", "table_id", "before_state", "after_state", "time_created"}
+	values := []interface{}{eventID, ActorFromContext(ctx), action, entityType, guest, tableID, beforeState, afterState, s.now()}
+	_, err = tx.Create(ctx, "audit", columns, values...)
+	return err
+}
+
+// auditState encodes the state of an entity as stored in the audit log, nil
+// pointers are stored as NULL.
+func auditState(state interface{}) (*string, error) {
+	if v := reflect.ValueOf(state); !v.IsValid() || (v.Kind() == reflect.Ptr && v.IsNil()) {
+		return nil, nil
+	}
+
+	encoded, err := json.Marshal(state)
+	if err != nil {
+		return nil, err
+	}
+	s := string(encoded)
+	return &s, nil
+}
+
+// GetAuditLog returns the audit entries of the event matching filter, oldest
+// first.
+func (s *service) GetAuditLog(ctx context.Context, eventID int, filter entity.AuditFilter) ([]entity.AuditEntry, error) {
+	if filter.Since != nil && filter.Until != nil && filter.Until.Before(*filter.Since) {
+		return nil, newError(ErrInvalidAuditFilter, "the time range cannot end before it starts")
+	}
+
+	var conditions []database.Condition
+	if filter.Guest != nil {
+		conditions = append(conditions, database.Eq("guest", *filter.Guest))
+	}
+	if filter.Table != nil {
+		conditions = append(conditions, database.Eq("table_id", *filter.Table))
+	}
+	if filter.Actor != nil {
+		conditions = append(conditions, database.Eq("actor", *filter.Actor))
+	}
+	if filter.Since != nil {
+		conditions = append(conditions, database.Ge("time_created", *filter.Since))
+	}
+	if filter.Until != nil {
+		conditions = append(conditions, database.Lt("time_created", *filter.Until))
+	}
+
+	entries := []entity.AuditEntry{}
+	query := eventQuery(eventID, conditions...).OrderBy(database.Asc("id"))
+	err := s.dbClient.FindMany(ctx, &entries, "audit", query)
+	if err != nil {
+		return nil, err
+	}
+
+	return entries, nil
+}
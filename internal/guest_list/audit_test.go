@@ -0,0 +1,244 @@
+package guest_list
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/getground/tech-tasks/backend/internal/entity"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAudit(t *testing.T) {
+	setupServiceTest()
+	defer dbClient.Close()
+
+	hostCtx := ContextWithActor(ctx, "host")
+	doorCtx := ContextWithActor(ctx, "door")
+
+	table := entity.Table{Capacity: 4}
+	response, err := guestListService.CreateTable(hostCtx, entity.DefaultEventID, &table)
+	assert.Nil(t, err, "Error while creating table, %v", err)
+	_, err = guestListService.AddGuest(hostCtx, entity.DefaultEventID, &entity.Guest{Name: "john", TableID: response.ID})
+	assert.Nil(t, err, "Error while adding guest, %v", err)
+
+	// Test the party size changed at the door is recorded with the old value
+	clock.now = clock.now.Add(time.Hour)
+	checkInTime := clock.now
+	_, err = guestListService.CheckInGuest(doorCtx, entity.DefaultEventID, &entity.Guest{Name: "john", AccompanyingGuests: 2})
+	assert.Nil(t, err, "Error while checking in guest, %v", err)
+	err = guestListService.CheckoutGuest(doorCtx, entity.DefaultEventID, &entity.Guest{Name: "john"})
+	assert.Nil(t, err, "Error while checking out guest, %v", err)
+
+	// Test failed changes are not recorded
+	_, err = guestListService.AddGuest(ctx, entity.DefaultEventID, &entity.Guest{Name: "rob", TableID: response.ID, AccompanyingGuests: 9})
+	assert.ErrorIs(t, err, ErrNoSeats)
+
+	entries, err := guestListService.GetAuditLog(ctx, entity.DefaultEventID, entity.AuditFilter{})
+	assert.Nil(t, err, "Error while getting audit log, %v", err)
+	assert.Equal(t, 7, len(entries))
+	assert.Equal(t, entity.AuditTableCreated, entries[0].Action)
+	assert.Equal(t, entity.AuditEntityTable, entries[0].EntityType)
+	assert.Equal(t, "host", entries[0].Actor)
+	assert.Nil(t, entries[0].Before)
+	assert.Equal(t, entity.AuditGuestAdded, entries[2].Action)
+
+	// Test the seats reserved by every change are recorded on the table
+	for _, i := range []int{1, 3, 5} {
+		assert.Equal(t, entity.AuditTableUpdated, entries[i].Action)
+		assert.Equal(t, response.ID, *entries[i].TableID)
+	}
+	var tableBefore, tableAfter entity.Table
+	assert.Nil(t, json.Unmarshal(entries[1].Before, &tableBefore))
+	assert.Nil(t, json.Unmarshal(entries[1].After, &tableAfter))
+	assert.Equal(t, 0, tableBefore.ReservedSeats)
+	assert.Equal(t, 1, tableAfter.ReservedSeats)
+
+	checkIn := entries[4]
+	assert.Equal(t, entity.AuditGuestCheckedIn, checkIn.Action)
+	assert.Equal(t, "door", checkIn.Actor)
+	assert.Equal(t, "john", *checkIn.Guest)
+	assert.Equal(t, response.ID, *checkIn.TableID)
+	assert.Equal(t, checkInTime, checkIn.TimeCreated)
+	var before, after entity.Guest
+	assert.Nil(t, json.Unmarshal(checkIn.Before, &before))
+	assert.Nil(t, json.Unmarshal(checkIn.After, &after))
+	assert.Equal(t, 0, before.AccompanyingGuests)
+	assert.Equal(t, entity.GuestStatusExpected, before.Status)
+	assert.Equal(t, 2, after.AccompanyingGuests)
+	assert.Equal(t, entity.GuestStatusArrived, after.Status)
+	assert.Equal(t, entity.AuditGuestCheckedOut, entries[6].Action)
+
+	// Test the filters
+	actor := "door"
+	entries, err = guestListService.GetAuditLog(ctx, entity.DefaultEventID, entity.AuditFilter{Actor: &actor})
+	assert.Nil(t, err, "Error while getting audit log, %v", err)
+	assert.Equal(t, 4, len(entries))
+
+	guest := "john"
+	entries, err = guestListService.GetAuditLog(ctx, entity.DefaultEventID, entity.AuditFilter{Guest: &guest, Until: &checkInTime})
+	assert.Nil(t, err, "Error while getting audit log, %v", err)
+	assert.Equal(t, 1, len(entries))
+	assert.Equal(t, entity.AuditGuestAdded, entries[0].Action)
+
+	entries, err = guestListService.GetAuditLog(ctx, entity.DefaultEventID, entity.AuditFilter{Table: &response.ID, Since: &checkInTime})
+	assert.Nil(t, err, "Error while getting audit log, %v", err)
+	assert.Equal(t, 4, len(entries))
+
+	otherTable := response.ID + 1
+	entries, err = guestListService.GetAuditLog(ctx, entity.DefaultEventID, entity.AuditFilter{Table: &otherTable})
+	assert.Nil(t, err, "Error while getting audit log, %v", err)
+	assert.Equal(t, 0, len(entries))
+
+	until := checkInTime.Add(-time.Second)
+	_, err = guestListService.GetAuditLog(ctx, entity.DefaultEventID, entity.AuditFilter{Since: &checkInTime, Until: &until})
+	assert.ErrorIs(t, err, ErrInvalidAuditFilter)
+
+	// Test removing the table, which keeps the guest who already left
+	err = guestListService.DeleteTable(hostCtx, entity.DefaultEventID, response.ID, entity.DeleteTableOptions{Guests: entity.DeleteTableCascade})
+	assert.Nil(t, err, "Error while deleting table, %v", err)
+	entries, err = guestListService.GetAuditLog(ctx, entity.DefaultEventID, entity.AuditFilter{Table: &response.ID})
+	assert.Nil(t, err, "Error while getting audit log, %v", err)
+	assert.Equal(t, 8, len(entries))
+	assert.Equal(t, entity.AuditTableDeleted, entries[7].Action)
+	assert.Nil(t, entries[7].After)
+}
+
+func TestAuditCoverage(t *testing.T) {
+	setupServiceTest()
+	defer dbClient.Close()
+
+	// actions returns the actions recorded in the event since the last call
+	since := clock.now
+	actions := func(eventID int) []string {
+		entries, err := guestListService.GetAuditLog(ctx, eventID, entity.AuditFilter{Since: &since})
+		assert.Nil(t, err, "Error while getting audit log, %v", err)
+		clock.now = clock.now.Add(time.Second)
+		since = clock.now
+
+		recorded := make([]string, len(entries))
+		for i, entry := range entries {
+			recorded[i] = entry.Action
+		}
+		return recorded
+	}
+
+	// Test creating an event
+	event, err := guestListService.CreateEvent(ctx, &entity.CreateEventRequestBody{Name: "party"})
+	assert.Nil(t, err, "Error while creating event, %v", err)
+	assert.Equal(t, []string{entity.AuditEventCreated}, actions(event.ID))
+
+	small, err := guestListService.CreateTable(ctx, entity.DefaultEventID, &entity.Table{Capacity: 2})
+	assert.Nil(t, err, "Error while creating table, %v", err)
+	large, err := guestListService.CreateTable(ctx, entity.DefaultEventID, &entity.Table{Capacity: 4})
+	assert.Nil(t, err, "Error while creating table, %v", err)
+	_, err = guestListService.AddGuest(ctx, entity.DefaultEventID, &entity.Guest{Name: "john", TableID: small.ID, AccompanyingGuests: 1})
+	assert.Nil(t, err, "Error while adding guest, %v", err)
+	actions(entity.DefaultEventID)
+
+	// Test joining and leaving the waitlist
+	_, err = guestListService.JoinWaitlist(ctx, entity.DefaultEventID, "rob", &entity.JoinWaitlistRequestBody{Table: &small.ID})
+	assert.Nil(t, err, "Error while joining waitlist, %v", err)
+	err = guestListService.LeaveWaitlist(ctx, entity.DefaultEventID, "rob")
+	assert.Nil(t, err, "Error while leaving waitlist, %v", err)
+	_, err = guestListService.JoinWaitlist(ctx, entity.DefaultEventID, "amy", &entity.JoinWaitlistRequestBody{Table: &small.ID})
+	assert.Nil(t, err, "Error while joining waitlist, %v", err)
+	assert.Equal(t, []string{entity.AuditWaitlistJoined, entity.AuditWaitlistLeft, entity.AuditWaitlistJoined}, actions(entity.DefaultEventID))
+
+	// Test moving a guest records the seats of both tables and the promotion
+	// of the party waiting for the seats freed
+	_, err = guestListService.UpdateGuest(ctx, entity.DefaultEventID, "john", &entity.UpdateGuestRequestBody{Table: &large.ID})
+	assert.Nil(t, err, "Error while updating guest, %v", err)
+	entries, err := guestListService.GetAuditLog(ctx, entity.DefaultEventID, entity.AuditFilter{Since: &since, Table: &small.ID})
+	assert.Nil(t, err, "Error while getting audit log, %v", err)
+	var before, after entity.Table
+	assert.Nil(t, json.Unmarshal(entries[0].Before, &before))
+	assert.Nil(t, json.Unmarshal(entries[0].After, &after))
+	assert.Equal(t, 2, before.ReservedSeats)
+	assert.Equal(t, 0, after.ReservedSeats)
+	assert.Equal(t, []string{
+		entity.AuditTableUpdated,
+		entity.AuditTableUpdated,
+		entity.AuditGuestUpdated,
+		entity.AuditTableUpdated,
+		entity.AuditWaitlistPromoted,
+		entity.AuditGuestAdded,
+	}, actions(entity.DefaultEventID))
+
+	// Test seating constraints and applying the seating plan they lead to
+	other := "amy"
+	constraint, err := guestListService.CreateSeatingConstraint(ctx, entity.DefaultEventID, &entity.CreateSeatingConstraintRequestBody{Kind: entity.ConstraintTogether, Guest: "john", OtherGuest: &other})
+	assert.Nil(t, err, "Error while creating seating constraint, %v", err)
+	assert.Equal(t, []string{entity.AuditConstraintCreated}, actions(entity.DefaultEventID))
+
+	_, err = guestListService.ApplySeatingPlan(ctx, entity.DefaultEventID)
+	assert.Nil(t, err, "Error while applying seating plan, %v", err)
+	assert.Contains(t, actions(entity.DefaultEventID), entity.AuditTableUpdated)
+
+	err = guestListService.DeleteSeatingConstraint(ctx, entity.DefaultEventID, constraint.ID)
+	assert.Nil(t, err, "Error while deleting seating constraint, %v", err)
+	entries, err = guestListService.GetAuditLog(ctx, entity.DefaultEventID, entity.AuditFilter{Since: &since})
+	assert.Nil(t, err, "Error while getting audit log, %v", err)
+	assert.Equal(t, 1, len(entries))
+	assert.Equal(t, entity.AuditConstraintDeleted, entries[0].Action)
+	assert.Equal(t, "john", *entries[0].Guest)
+	assert.Nil(t, entries[0].After)
+	actions(entity.DefaultEventID)
+
+	// Test reassigning the guests of a deleted table records the seats of
+	// the table they move to
+	extra, err := guestListService.CreateTable(ctx, entity.DefaultEventID, &entity.Table{Capacity: 1})
+	assert.Nil(t, err, "Error while creating table, %v", err)
+	_, err = guestListService.AddGuest(ctx, entity.DefaultEventID, &entity.Guest{Name: "mary", TableID: extra.ID})
+	assert.Nil(t, err, "Error while adding guest, %v", err)
+	actions(entity.DefaultEventID)
+	err = guestListService.DeleteTable(ctx, entity.DefaultEventID, extra.ID, entity.DeleteTableOptions{Guests: entity.DeleteTableReassign, ReassignTo: large.ID})
+	assert.Nil(t, err, "Error while deleting table, %v", err)
+	entries, err = guestListService.GetAuditLog(ctx, entity.DefaultEventID, entity.AuditFilter{Since: &since})
+	assert.Nil(t, err, "Error while getting audit log, %v", err)
+	assert.Equal(t, 3, len(entries))
+	assert.Equal(t, entity.AuditTableUpdated, entries[0].Action)
+	assert.Equal(t, large.ID, *entries[0].TableID)
+	assert.Equal(t, entity.AuditGuestUpdated, entries[1].Action)
+	assert.Equal(t, entity.AuditTableDeleted, entries[2].Action)
+}
+
+func TestActorMiddleware(t *testing.T) {
+	setupServiceTest()
+	defer dbClient.Close()
+
+	r := mux.NewRouter()
+	r.Use(ActorMiddleware)
+	RegisterHandlers(r, guestListService)
+
+	req := httptest.NewRequest(http.MethodPost, "/tables", strings.NewReader(`{"capacity": 2}`))
+	req.Header.Set(ActorHeader, "alice")
+	res := httptest.NewRecorder()
+	r.ServeHTTP(res, req)
+	assert.Equal(t, http.StatusOK, res.Code)
+
+	req = httptest.NewRequest(http.MethodPost, "/tables", strings.NewReader(`{"capacity": 2}`))
+	res = httptest.NewRecorder()
+	r.ServeHTTP(res, req)
+	assert.Equal(t, http.StatusOK, res.Code)
+
+	req = httptest.NewRequest(http.MethodGet, "/audit?actor=alice", nil)
+	res = httptest.NewRecorder()
+	r.ServeHTTP(res, req)
+	assert.Equal(t, http.StatusOK, res.Code)
+
+	var body entity.GetAuditLogResponseBody
+	err := json.NewDecoder(res.Body).Decode(&body)
+	assert.Nil(t, err, "Error while decoding audit log, %v", err)
+	assert.Equal(t, 1, len(body.Entries))
+	assert.Equal(t, "alice", body.Entries[0].Actor)
+
+	entries, err := guestListService.GetAuditLog(ctx, entity.DefaultEventID, entity.AuditFilter{})
+	assert.Nil(t, err, "Error while getting audit log, %v", err)
+	assert.Equal(t, 2, len(entries))
+	assert.Equal(t, entity.AuditActorAnonymous, entries[1].Actor)
+}
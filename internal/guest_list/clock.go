@@ -0,0 +1,15 @@
+package guest_list
+
+import "time"
+
+// Clock tells the service the current time. Tests replace it to control
+// check-in and checkout times.
+type Clock interface {
+	Now() time.Time
+}
+
+type systemClock struct{}
+
+func (systemClock) Now() time.Time {
+	return time.Now()
+}
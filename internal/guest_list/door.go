@@ -0,0 +1,180 @@
+package guest_list
+
+import (
+	"bytes"
+	"net/http"
+	"time"
+
+	"github.com/getground/tech-tasks/backend/internal/entity"
+	"github.com/getground/tech-tasks/backend/pkg/problem"
+	"github.com/getground/tech-tasks/backend/pkg/validate"
+	"github.com/gorilla/mux"
+	"github.com/gorilla/websocket"
+)
+
+const (
+	// doorWriteWait bounds the time spent writing a message to a device.
+	doorWriteWait = 10 * time.Second
+	// doorPongWait is how long a device may stay silent before it is
+	// considered gone. Pings are sent often enough to keep it talking.
+	doorPongWait   = 60 * time.Second
+	doorPingPeriod = doorPongWait * 9 / 10
+	// doorMaxMessage bounds the size of a command.
+	doorMaxMessage = 4096
+)
+
+// The default origin check refuses browsers on other hosts while letting
+// native apps, which send no Origin header, through.
+var doorUpgrader = websocket.Upgrader{
+	ReadBufferSize:  1024,
+	WriteBufferSize: 1024,
+}
+
+// RegisterDoorHandlers registers the WebSocket endpoint door devices use to
+// check guests in and out and follow the check-ins of the other devices.
+func RegisterDoorHandlers(r *mux.Router, service GuestListService) {
+	h := handler{service}
+	r.HandleFunc("/door", h.door).Methods(http.MethodGet)
+
+	events := r.PathPrefix("/events/{eventID:[0-9]+}").Subrouter()
+	events.Use(h.requireEvent)
+	events.HandleFunc("/door", h.door).Methods(http.MethodGet)
+}
+
+// door upgrades the request to a WebSocket over which the device sends
+// DoorCommands. Each command is answered with a result or an error, and
+// every change to the guest list is pushed to all devices as an event.
+func (h handler) door(w http.ResponseWriter, r *http.Request) {
+	conn, err := doorUpgrader.Upgrade(w, r, nil)
+	if err != nil {
+		// Upgrade has already responded with the error
+		return
+	}
+	defer conn.Close()
+
+	sub := h.service.Subscribe(eventID(r), 0)
+	defer sub.Close()
+
+	out := make(chan entity.DoorMessage)
+	quit := make(chan struct{})
+	written := make(chan struct{})
+	go func() {
+		writeDoorMessages(conn, sub, out, quit)
+		close(written)
+	}()
+
+	h.readDoorCommands(conn, r, out, written)
+	close(quit)
+	<-written
+}
+
+// readDoorCommands runs the commands of the device until the connection
+// fails or the writer gives up.
+func (h handler) readDoorCommands(conn *websocket.Conn, r *http.Request, out chan<- entity.DoorMessage, written <-chan struct{}) {
+	conn.SetReadLimit(doorMaxMessage)
+	conn.SetReadDeadline(time.Now().Add(doorPongWait))
+	conn.SetPongHandler(func(string) error {
+		return conn.SetReadDeadline(time.Now().Add(doorPongWait))
+	})
+
+	for {
+		_, data, err := conn.ReadMessage()
+		if err != nil {
+			return
+		}
+
+		var message entity.DoorMessage
+		var command entity.DoorCommand
+		err = validate.DecodeReader(bytes.NewReader(data), &command)
+		if err != nil {
+			details := validate.Details(r, err)
+			message = entity.DoorMessage{Type: entity.DoorMessageError, ID: command.ID, Error: &details}
+		} else {
+			message = h.runDoorCommand(r, command)
+		}
+
+		select {
+		case out <- message:
+		case <-written:
+			return
+		}
+	}
+}
+
+func (h handler) runDoorCommand(r *http.Request, command entity.DoorCommand) entity.DoorMessage {
+	message := entity.DoorMessage{
+		Type:    entity.DoorMessageResult,
+		ID:      command.ID,
+		Command: command.Type,
+		Guest:   command.Guest,
+	}
+
+	guest := entity.Guest{Name: command.Guest, AccompanyingGuests: command.AccompanyingGuests}
+	var err error
+	switch command.Type {
+	case entity.DoorCheckIn:
+		_, err = h.service.CheckInGuest(r.Context(), eventID(r), &guest)
+	case entity.DoorCheckout:
+		err = h.service.CheckoutGuest(r.Context(), eventID(r), &guest)
+	default:
+		details := problem.New(r, http.StatusBadRequest, "command type must be check_in or checkout")
+		message.Type = entity.DoorMessageError
+		message.Error = &details
+		return message
+	}
+
+	if err != nil {
+		details := errorDetails(r, err)
+		message.Type = entity.DoorMessageError
+		message.Error = &details
+	}
+	return message
+}
+
+// writeDoorMessages is the only writer of conn. It sends the answers from out
+// and the events of sub, and pings the device, until quit is closed or a
+// write fails.
+func writeDoorMessages(conn *websocket.Conn, sub *Subscription, out <-chan entity.DoorMessage, quit <-chan struct{}) {
+	ping := time.NewTicker(doorPingPeriod)
+	defer ping.Stop()
+
+	for {
+		var message entity.DoorMessage
+		select {
+		case <-quit:
+			closeDoor(conn, websocket.CloseNormalClosure, "")
+			return
+		case event, ok := <-sub.Events():
+			// The device fell behind or the server is shutting down, it
+			// should reconnect and fetch the current state
+			if !ok {
+				closeDoor(conn, websocket.CloseTryAgainLater, "event stream ended")
+				return
+			}
+			message = entity.DoorMessage{Type: entity.DoorMessageEvent, Event: &event}
+		case message = <-out:
+		case <-ping.C:
+			err := conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(doorWriteWait))
+			if err != nil {
+				conn.Close()
+				return
+			}
+			continue
+		}
+
+		conn.SetWriteDeadline(time.Now().Add(doorWriteWait))
+		err := conn.WriteJSON(message)
+		if err != nil {
+			conn.Close()
+			return
+		}
+	}
+}
+
+// closeDoor tells the device why the connection ends and closes it, which
+// also stops the reader.
+func closeDoor(conn *websocket.Conn, code int, text string) {
+	message := websocket.FormatCloseMessage(code, text)
+	conn.WriteControl(websocket.CloseMessage, message, time.Now().Add(doorWriteWait))
+	conn.Close()
+}
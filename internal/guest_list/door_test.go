@@ -0,0 +1,97 @@
+package guest_list
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/getground/tech-tasks/backend/internal/entity"
+	"github.com/gorilla/mux"
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDoor(t *testing.T) {
+	setupServiceTest()
+	defer dbClient.Close()
+
+	bus := NewBus(DefaultStreamHistory)
+	relay := NewRelay(dbClient, []Sink{BusSink(bus)})
+	runCtx, stop := context.WithCancel(ctx)
+	defer stop()
+	go relay.Run(runCtx)
+	guestListService := NewGuestListService(dbClient, WithBus(bus), WithRelay(relay))
+	r := mux.NewRouter()
+	RegisterHandlers(r, guestListService)
+	RegisterDoorHandlers(r, guestListService)
+	server := httptest.NewServer(r)
+	defer server.Close()
+
+	table := entity.Table{Capacity: 4}
+	response, err := guestListService.CreateTable(ctx, entity.DefaultEventID, &table)
+	assert.Nil(t, err, "Error while creating table, %v", err)
+	_, err = guestListService.AddGuest(ctx, entity.DefaultEventID, &entity.Guest{Name: "john", TableID: response.ID})
+	assert.Nil(t, err, "Error while adding guest, %v", err)
+
+	url := "ws" + strings.TrimPrefix(server.URL, "http")
+	dial := func(path string) *websocket.Conn {
+		conn, _, err := websocket.DefaultDialer.Dial(url+path, nil)
+		if err != nil {
+			t.Fatalf("Error while dialing %s, %v", path, err)
+		}
+		return conn
+	}
+	read := func(conn *websocket.Conn) entity.DoorMessage {
+		var message entity.DoorMessage
+		conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+		err := conn.ReadJSON(&message)
+		assert.Nil(t, err, "Error while reading message, %v", err)
+		return message
+	}
+
+	first := dial("/door")
+	defer first.Close()
+	second := dial(fmt.Sprintf("/events/%d/door", entity.DefaultEventID))
+	defer second.Close()
+
+	// Test a check-in is confirmed to its device and broadcast to every device
+	err = first.WriteJSON(entity.DoorCommand{ID: "1", Type: entity.DoorCheckIn, Guest: "john", AccompanyingGuests: 1})
+	assert.Nil(t, err)
+	messages := []entity.DoorMessage{read(first), read(first)}
+	if messages[0].Type == entity.DoorMessageEvent {
+		messages[0], messages[1] = messages[1], messages[0]
+	}
+	assert.Equal(t, entity.DoorMessageResult, messages[0].Type)
+	assert.Equal(t, "1", messages[0].ID)
+	assert.Equal(t, entity.StreamGuestCheckedIn, messages[1].Event.Type)
+	assert.Equal(t, 2, messages[1].Event.EmptySeats)
+
+	event := read(second)
+	assert.Equal(t, entity.DoorMessageEvent, event.Type)
+	assert.Equal(t, "john", *event.Event.Guest)
+
+	// Test conflicting commands from another device are reported
+	err = second.WriteJSON(entity.DoorCommand{ID: "2", Type: entity.DoorCheckIn, Guest: "john"})
+	assert.Nil(t, err)
+	conflict := read(second)
+	assert.Equal(t, entity.DoorMessageError, conflict.Type)
+	assert.Equal(t, "2", conflict.ID)
+	assert.Equal(t, http.StatusConflict, conflict.Error.Status)
+
+	// Test unknown commands and malformed messages are refused
+	err = second.WriteJSON(entity.DoorCommand{ID: "3", Type: "dance", Guest: "john"})
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusBadRequest, read(second).Error.Status)
+	err = second.WriteMessage(websocket.TextMessage, []byte("{"))
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusBadRequest, read(second).Error.Status)
+
+	// Test devices can't connect to unknown events
+	_, res, err := websocket.DefaultDialer.Dial(url+"/events/999/door", nil)
+	assert.NotNil(t, err)
+	assert.Equal(t, http.StatusNotFound, res.StatusCode)
+}
@@ -0,0 +1,91 @@
+package guest_list
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+
+	"github.com/getground/tech-tasks/backend/pkg/problem"
+)
+
+var (
+	ErrEventNotFound      = errors.New("event not found")
+	ErrInvalidEvent       = errors.New("invalid event")
+	ErrGuestExists        = errors.New("guest already exists")
+	ErrGuestNotFound      = errors.New("guest not found")
+	ErrTableNotFound      = errors.New("table not found")
+	ErrTableNotEmpty      = errors.New("table has seated guests")
+	ErrCapacityTooSmall   = errors.New("capacity is below reserved seats")
+	ErrInvalidReassign    = errors.New("invalid reassign target")
+	ErrNoSeats            = errors.New("no available seats")
+	ErrNotCheckedIn       = errors.New("guest is not checked in")
+	ErrAlreadyCheckedIn   = errors.New("guest is already checked in")
+	ErrNotWaitlisted      = errors.New("guest is not on the waitlist")
+	ErrInvalidAttribute   = errors.New("invalid table attribute")
+	ErrInvalidConstraint  = errors.New("invalid seating constraint")
+	ErrConstraintNotFound = errors.New("seating constraint not found")
+	ErrInvalidImport      = errors.New("invalid import")
+	ErrInvalidAuditFilter = errors.New("invalid audit filter")
+	ErrInvalidGuestFilter = errors.New("invalid guest filter")
+)
+
+// statusCodes maps each service error onto the HTTP status it is reported as.
+var statusCodes = map[error]int{
+	ErrEventNotFound:      http.StatusNotFound,
+	ErrInvalidEvent:       http.StatusUnprocessableEntity,
+	ErrGuestExists:        http.StatusConflict,
+	ErrGuestNotFound:      http.StatusNotFound,
+	ErrTableNotFound:      http.StatusNotFound,
+	ErrTableNotEmpty:      http.StatusConflict,
+	ErrCapacityTooSmall:   http.StatusUnprocessableEntity,
+	ErrInvalidReassign:    http.StatusUnprocessableEntity,
+	ErrNoSeats:            http.StatusUnprocessableEntity,
+	ErrNotCheckedIn:       http.StatusUnprocessableEntity,
+	ErrAlreadyCheckedIn:   http.StatusConflict,
+	ErrNotWaitlisted:      http.StatusNotFound,
+	ErrInvalidAttribute:   http.StatusUnprocessableEntity,
+	ErrInvalidConstraint:  http.StatusUnprocessableEntity,
+	ErrConstraintNotFound: http.StatusNotFound,
+	ErrInvalidImport:      http.StatusUnprocessableEntity,
+	ErrInvalidAuditFilter: http.StatusBadRequest,
+	ErrInvalidGuestFilter: http.StatusBadRequest,
+}
+
+// serviceError carries a descriptive message while still matching one of the
+// sentinel errors above with errors.Is.
+type serviceError struct {
+	kind error
+	msg  string
+}
+
+func (e *serviceError) Error() string {
+	return e.msg
+}
+
+func (e *serviceError) Unwrap() error {
+	return e.kind
+}
+
+func newError(kind error, format string, args ...interface{}) error {
+	return &serviceError{kind, fmt.Sprintf(format, args...)}
+}
+
+// writeError responds with the problem details matching err.
+func writeError(w http.ResponseWriter, r *http.Request, err error) {
+	details := errorDetails(r, err)
+	problem.Write(w, details, details.Status)
+}
+
+// errorDetails returns the problem details reporting err. Unexpected errors
+// are logged and reported as a 500 without leaking their message.
+func errorDetails(r *http.Request, err error) problem.Details {
+	for kind, status := range statusCodes {
+		if errors.Is(err, kind) {
+			return problem.New(r, status, err.Error())
+		}
+	}
+
+	log.Printf("Error %s when handling %s %s", err, r.Method, r.URL.Path)
+	return problem.New(r, http.StatusInternalServerError, "internal server error")
+}
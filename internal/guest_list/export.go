@@ -0,0 +1,305 @@
+package guest_list
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"io"
+	"mime"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/getground/tech-tasks/backend/internal/entity"
+	"github.com/getground/tech-tasks/backend/pkg/database"
+)
+
+// Formats the exports can be written in.
+const (
+	ExportCSV    = "csv"
+	ExportNDJSON = "ndjson"
+	ExportHTML   = "html"
+)
+
+var exportContentTypes = map[string]string{
+	ExportCSV:    "text/csv; charset=utf-8",
+	ExportNDJSON: "application/x-ndjson",
+	ExportHTML:   "text/html; charset=utf-8",
+}
+
+// exportMediaTypes maps the media types of an Accept header onto formats.
+var exportMediaTypes = map[string]string{
+	"text/csv":             ExportCSV,
+	"application/x-ndjson": ExportNDJSON,
+	"application/jsonl":    ExportNDJSON,
+	"application/json":     ExportNDJSON,
+	"text/html":            ExportHTML,
+	"text/*":               ExportCSV,
+	"*/*":                  ExportCSV,
+}
+
+// ListGuests returns every guest of the event ordered by table and name.
+func (s *service) ListGuests(ctx context.Context, eventID int) ([]entity.Guest, error) {
+	guests, err := findEventGuests(ctx, s.dbClient, eventID)
+	if err != nil {
+		return nil, err
+	}
+
+	sort.SliceStable(guests, func(i, j int) bool {
+		if guests[i].TableID != guests[j].TableID {
+			return guests[i].TableID < guests[j].TableID
+		}
+		return guests[i].Name < guests[j].Name
+	})
+	return guests, nil
+}
+
+// GetCheckInLog returns the guests who arrived, in order of arrival.
+func (s *service) GetCheckInLog(ctx context.Context, eventID int) ([]entity.Guest, error) {
+	guests := []entity.Guest{}
+	query := eventQuery(eventID, database.IsNotNull("time_arrived"))
+
+	err := s.dbClient.FindMany(ctx, &guests, "guest", query)
+	if err != nil {
+		return nil, err
+	}
+
+	sort.SliceStable(guests, func(i, j int) bool {
+		return guests[i].TimeArrived.Before(*guests[j].TimeArrived)
+	})
+	return guests, nil
+}
+
+func (s *service) GetSeatingChart(ctx context.Context, eventID int) ([]entity.SeatingChartTable, error) {
+	tables, err := s.GetAllTables(ctx, eventID)
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(tables, func(i, j int) bool { return tables[i].ID < tables[j].ID })
+
+	guests, err := s.ListGuests(ctx, eventID)
+	if err != nil {
+		return nil, err
+	}
+	byTable := map[int][]entity.SeatingChartGuest{}
+	for _, guest := range guests {
+		// Departed guests no longer hold a seat
+		if guest.Status == entity.GuestStatusDeparted {
+			continue
+		}
+		byTable[guest.TableID] = append(byTable[guest.TableID], entity.SeatingChartGuest{
+			Name:               guest.Name,
+			AccompanyingGuests: guest.AccompanyingGuests,
+			Status:             guest.Status,
+		})
+	}
+
+	chart := make([]entity.SeatingChartTable, 0, len(tables))
+	for _, table := range tables {
+		tableGuests := byTable[table.ID]
+		if tableGuests == nil {
+			tableGuests = []entity.SeatingChartGuest{}
+		}
+		chart = append(chart, entity.SeatingChartTable{Table: table, Guests: tableGuests})
+	}
+	return chart, nil
+}
+
+// exportFormat picks the format of an export from the format query parameter
+// or else the Accept header, defaulting to CSV.
+func exportFormat(r *http.Request) (string, int, error) {
+	if format := r.URL.Query().Get("format"); format != "" {
+		if _, ok := exportContentTypes[format]; !ok {
+			return "", http.StatusBadRequest, fmt.Errorf("format must be %s, %s or %s", ExportCSV, ExportNDJSON, ExportHTML)
+		}
+		return format, 0, nil
+	}
+
+	accept := r.Header.Get("Accept")
+	if accept == "" {
+		return ExportCSV, 0, nil
+	}
+
+	// Media types are tried in the order given, ignoring quality values
+	for _, part := range strings.Split(accept, ",") {
+		mediaType, _, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+		if format, ok := exportMediaTypes[mediaType]; ok {
+			return format, 0, nil
+		}
+	}
+	return "", http.StatusNotAcceptable, fmt.Errorf("exports are available as text/csv, application/x-ndjson or text/html")
+}
+
+// report is an export ready to be written in any format. Sections group the
+// rows under a heading in HTML while CSV lists them one after the other, and
+// NDJSON writes one line per record.
+type report struct {
+	Title    string
+	Columns  []string
+	Sections []reportSection
+	Records  []interface{}
+}
+
+type reportSection struct {
+	Heading string
+	Rows    [][]string
+}
+
+func writeReport(w http.ResponseWriter, format string, name string, rep report) error {
+	w.Header().Set("Content-Type", exportContentTypes[format])
+	if format != ExportHTML {
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", name+"."+format))
+	}
+
+	switch format {
+	case ExportCSV:
+		return writeCSV(w, rep)
+	case ExportNDJSON:
+		return writeNDJSON(w, rep)
+	default:
+		return reportTemplate.Execute(w, rep)
+	}
+}
+
+func writeCSV(w io.Writer, rep report) error {
+	writer := csv.NewWriter(w)
+	err := writer.Write(rep.Columns)
+	if err != nil {
+		return err
+	}
+	for _, section := range rep.Sections {
+		for _, row := range section.Rows {
+			if err := writer.Write(row); err != nil {
+				return err
+			}
+		}
+		// Flush section by section so large exports stream
+		writer.Flush()
+		if err := writer.Error(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func writeNDJSON(w io.Writer, rep report) error {
+	encoder := json.NewEncoder(w)
+	for _, record := range rep.Records {
+		if err := encoder.Encode(record); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+var reportTemplate = template.Must(template.New("report").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>{{.Title}}</title>
+<style>
+body { font-family: sans-serif; font-size: 11pt; margin: 1cm; }
+table { border-collapse: collapse; width: 100%; margin-bottom: 1em; }
+th, td { border: 1px solid #444; padding: 4px 8px; text-align: left; }
+th { background: #eee; }
+section { page-break-inside: avoid; }
+@media print { body { margin: 0; } }
+</style>
+</head>
+<body>
+<h1>{{.Title}}</h1>
+{{range .Sections}}<section>
+{{if .Heading}}<h2>{{.Heading}}</h2>
+{{end}}<table>
+<thead><tr>{{range $.Columns}}<th>{{.}}</th>{{end}}</tr></thead>
+<tbody>
+{{range .Rows}}<tr>{{range .}}<td>{{.}}</td>{{end}}</tr>
+{{end}}</tbody>
+</table>
+</section>
+{{end}}</body>
+</html>
+`))
+
+func formatTime(t *time.Time) string {
+	if t == nil {
+		return ""
+	}
+	return t.Format(time.RFC3339)
+}
+
+func guestListReport(guests []entity.Guest) report {
+	rows := make([][]string, 0, len(guests))
+	records := make([]interface{}, 0, len(guests))
+	for _, guest := range guests {
+		rows = append(rows, []string{
+			guest.Name,
+			strconv.Itoa(guest.TableID),
+			strconv.Itoa(guest.AccompanyingGuests),
+			guest.Status,
+		})
+		records = append(records, guest)
+	}
+	return report{
+		Title:    "Guest list",
+		Columns:  []string{"name", "table", "accompanying_guests", "status"},
+		Sections: []reportSection{{Rows: rows}},
+		Records:  records,
+	}
+}
+
+func checkInLogReport(guests []entity.Guest) report {
+	rows := make([][]string, 0, len(guests))
+	records := make([]interface{}, 0, len(guests))
+	for _, guest := range guests {
+		rows = append(rows, []string{
+			guest.Name,
+			strconv.Itoa(guest.TableID),
+			strconv.Itoa(guest.AccompanyingGuests),
+			formatTime(guest.TimeArrived),
+			formatTime(guest.TimeLeft),
+		})
+		records = append(records, guest)
+	}
+	return report{
+		Title:    "Check-in log",
+		Columns:  []string{"name", "table", "accompanying_guests", "time_arrived", "time_left"},
+		Sections: []reportSection{{Rows: rows}},
+		Records:  records,
+	}
+}
+
+func seatingChartReport(chart []entity.SeatingChartTable) report {
+	sections := make([]reportSection, 0, len(chart))
+	records := make([]interface{}, 0, len(chart))
+	for _, table := range chart {
+		section := reportSection{
+			Heading: fmt.Sprintf("Table %d (%d of %d seats reserved)", table.ID, table.ReservedSeats, table.Capacity),
+		}
+		attributes := strings.Join(table.Attributes, " ")
+		for _, guest := range table.Guests {
+			section.Rows = append(section.Rows, []string{
+				strconv.Itoa(table.ID),
+				attributes,
+				guest.Name,
+				strconv.Itoa(guest.AccompanyingGuests),
+				guest.Status,
+			})
+		}
+		sections = append(sections, section)
+		records = append(records, table)
+	}
+	return report{
+		Title:    "Seating chart",
+		Columns:  []string{"table", "attributes", "guest", "accompanying_guests", "status"},
+		Sections: sections,
+		Records:  records,
+	}
+}
@@ -0,0 +1,102 @@
+package guest_list
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/getground/tech-tasks/backend/internal/entity"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExport(t *testing.T) {
+	setupServiceTest()
+	defer dbClient.Close()
+
+	guestListService := NewGuestListService(dbClient)
+	var tableIDs []int
+	for _, capacity := range []int{4, 2} {
+		table := entity.Table{Capacity: capacity, Attributes: entity.Attributes{"window"}}
+		response, err := guestListService.CreateTable(ctx, entity.DefaultEventID, &table)
+		assert.Nil(t, err, "Error while creating table, %v", err)
+		tableIDs = append(tableIDs, response.ID)
+	}
+	guests := []entity.Guest{
+		{Name: "rob", TableID: tableIDs[1], AccompanyingGuests: 1},
+		{Name: "mary", TableID: tableIDs[0]},
+		{Name: "john", TableID: tableIDs[0], AccompanyingGuests: 1},
+	}
+	for i := range guests {
+		_, err := guestListService.AddGuest(ctx, entity.DefaultEventID, &guests[i])
+		assert.Nil(t, err, "Error while adding guest, %v", err)
+	}
+	for _, name := range []string{"mary", "rob"} {
+		_, err := guestListService.CheckInGuest(ctx, entity.DefaultEventID, &entity.Guest{Name: name})
+		assert.Nil(t, err, "Error while checking in guest, %v", err)
+	}
+	err := guestListService.CheckoutGuest(ctx, entity.DefaultEventID, &entity.Guest{Name: "rob"})
+	assert.Nil(t, err, "Error while checking out guest, %v", err)
+
+	// Test the guest list is ordered by table and name
+	list, err := guestListService.ListGuests(ctx, entity.DefaultEventID)
+	assert.Nil(t, err, "Error while listing guests, %v", err)
+	assert.Equal(t, 3, len(list))
+	assert.Equal(t, "john", list[0].Name)
+	assert.Equal(t, "mary", list[1].Name)
+	assert.Equal(t, "rob", list[2].Name)
+
+	// Test the check-in log holds arrived and departed guests
+	log, err := guestListService.GetCheckInLog(ctx, entity.DefaultEventID)
+	assert.Nil(t, err, "Error while getting check-in log, %v", err)
+	assert.Equal(t, 2, len(log))
+
+	// Test departed guests are left off the seating chart
+	chart, err := guestListService.GetSeatingChart(ctx, entity.DefaultEventID)
+	assert.Nil(t, err, "Error while getting seating chart, %v", err)
+	assert.Equal(t, 2, len(chart))
+	assert.Equal(t, 2, len(chart[0].Guests))
+	assert.Equal(t, 0, len(chart[1].Guests))
+
+	r := mux.NewRouter()
+	RegisterHandlers(r, guestListService)
+	export := func(url, accept string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, url, nil)
+		if accept != "" {
+			req.Header.Set("Accept", accept)
+		}
+		res := httptest.NewRecorder()
+		r.ServeHTTP(res, req)
+		return res
+	}
+
+	// Test exports default to CSV
+	res := export("/export/guest_list", "")
+	assert.Equal(t, http.StatusOK, res.Code)
+	assert.Equal(t, "text/csv; charset=utf-8", res.Header().Get("Content-Type"))
+	assert.Contains(t, res.Header().Get("Content-Disposition"), "guest_list.csv")
+	lines := strings.Split(strings.TrimSpace(res.Body.String()), "\n")
+	assert.Equal(t, 4, len(lines))
+	assert.Equal(t, "name,table,accompanying_guests,status", lines[0])
+
+	// Test the format is negotiated from the Accept header
+	res = export("/export/checkins", "application/x-ndjson")
+	assert.Equal(t, http.StatusOK, res.Code)
+	assert.Equal(t, "application/x-ndjson", res.Header().Get("Content-Type"))
+	assert.Equal(t, 2, strings.Count(res.Body.String(), "\n"))
+
+	res = export("/export/seating_chart", "application/pdf, text/html;q=0.9")
+	assert.Equal(t, http.StatusOK, res.Code)
+	assert.Equal(t, "text/html; charset=utf-8", res.Header().Get("Content-Type"))
+	assert.Contains(t, res.Body.String(), "<h2>Table")
+	assert.Contains(t, res.Body.String(), "<td>john</td>")
+
+	res = export("/export/seating_chart", "application/pdf")
+	assert.Equal(t, http.StatusNotAcceptable, res.Code)
+
+	// Test the format parameter wins over the Accept header
+	res = export("/export/seating_chart?format=csv", "text/html")
+	assert.Equal(t, http.StatusOK, res.Code)
+	assert.Equal(t, "text/csv; charset=utf-8", res.Header().Get("Content-Type"))
+}
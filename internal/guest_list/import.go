@@ -0,0 +1,225 @@
+package guest_list
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/getground/tech-tasks/backend/internal/entity"
+	"github.com/getground/tech-tasks/backend/pkg/database"
+	"github.com/getground/tech-tasks/backend/pkg/validate"
+)
+
+// Formats accepted by ParseImport.
+const (
+	ImportCSV    = "csv"
+	ImportNDJSON = "ndjson"
+)
+
+// errImportFailed rolls back an atomic import once a row has failed.
+var errImportFailed = errors.New("import failed")
+
+// ParseImport reads the guest rows of a CSV file with a name, table and
+// accompanying_guests header, or of JSON lines with the same fields. Rows
+// that can't be parsed are returned with their Error set, only unreadable
+// input fails the whole import.
+func ParseImport(r io.Reader, format string) ([]entity.ImportGuestRow, error) {
+	switch format {
+	case ImportCSV:
+		return parseCSV(r)
+	case ImportNDJSON:
+		return parseNDJSON(r)
+	default:
+		return nil, fmt.Errorf("unknown import format %s", format)
+	}
+}
+
+func parseCSV(r io.Reader) ([]entity.ImportGuestRow, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err == io.EOF {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	columns := map[string]int{}
+	for i, column := range header {
+		columns[strings.ToLower(strings.TrimSpace(column))] = i
+	}
+	if _, ok := columns["name"]; !ok {
+		return nil, errors.New("CSV header has no name column")
+	}
+	field := func(record []string, column string) string {
+		i, ok := columns[column]
+		if !ok || i >= len(record) {
+			return ""
+		}
+		return strings.TrimSpace(record[i])
+	}
+
+	var rows []entity.ImportGuestRow
+	// Lines are counted from the header, quoted line breaks aside
+	for line := 2; ; line++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			var parseErr *csv.ParseError
+			if !errors.As(err, &parseErr) {
+				return nil, err
+			}
+			rows = append(rows, entity.ImportGuestRow{Line: parseErr.Line, Error: parseErr.Err})
+			continue
+		}
+
+		row := entity.ImportGuestRow{Line: line, Name: field(record, "name")}
+		if table := field(record, "table"); table != "" {
+			id, err := strconv.Atoi(table)
+			if err != nil {
+				row.Error = fmt.Errorf("table must be a table id, found `%s`", table)
+			}
+			row.Table = &id
+		}
+		if accompanyingGuests := field(record, "accompanying_guests"); accompanyingGuests != "" && row.Error == nil {
+			row.AccompanyingGuests, err = strconv.Atoi(accompanyingGuests)
+			if err != nil {
+				row.Error = fmt.Errorf("accompanying_guests must be a number, found `%s`", accompanyingGuests)
+			}
+		}
+		rows = append(rows, validateImportRow(row))
+	}
+	return rows, nil
+}
+
+func parseNDJSON(r io.Reader) ([]entity.ImportGuestRow, error) {
+	decoder := json.NewDecoder(r)
+
+	var rows []entity.ImportGuestRow
+	for line := 1; ; line++ {
+		var raw json.RawMessage
+		err := decoder.Decode(&raw)
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			var syntaxErr *json.SyntaxError
+			if !errors.As(err, &syntaxErr) && err != io.ErrUnexpectedEOF {
+				return nil, err
+			}
+			// The rest of the stream can't be trusted after a syntax error
+			return append(rows, entity.ImportGuestRow{Line: line, Error: err}), nil
+		}
+
+		var body struct {
+			Name               string `json:"name"`
+			Table              *int   `json:"table"`
+			AccompanyingGuests int    `json:"accompanying_guests"`
+		}
+		row := entity.ImportGuestRow{Line: line}
+		if err := json.Unmarshal(raw, &body); err != nil {
+			row.Error = err
+		} else {
+			row.Name = body.Name
+			row.Table = body.Table
+			row.AccompanyingGuests = body.AccompanyingGuests
+		}
+		rows = append(rows, validateImportRow(row))
+	}
+	return rows, nil
+}
+
+func validateImportRow(row entity.ImportGuestRow) entity.ImportGuestRow {
+	if row.Error != nil {
+		return row
+	}
+	if err := validate.Var("name", row.Name, nameRules); err != nil {
+		row.Error = err
+	} else if row.AccompanyingGuests < 0 {
+		row.Error = errors.New("accompanying_guests cannot be negative")
+	}
+	return row
+}
+
+// ImportGuests adds the rows to the guest list with the same rules as
+// AddGuest. Rows failing those rules are reported in the response while
+// database failures abort the import.
+func (s *service) ImportGuests(ctx context.Context, eventID int, rows []entity.ImportGuestRow, mode string) (*entity.ImportGuestsResponseBody, error) {
+	result := entity.ImportGuestsResponseBody{Mode: mode, Rows: []entity.ImportGuestResult{}}
+
+	importRow := func(tx database.Tx, row entity.ImportGuestRow) error {
+		rowResult := entity.ImportGuestResult{Line: row.Line, Name: row.Name}
+		err := row.Error
+		if err == nil {
+			guest := entity.Guest{Name: row.Name, AccompanyingGuests: row.AccompanyingGuests}
+			if row.Table != nil {
+				guest.TableID = *row.Table
+			}
+			var tableID int
+			tableID, err = s.addGuest(ctx, tx, eventID, &guest)
+			if err == nil {
+				rowResult.Table = &tableID
+			}
+		}
+
+		var serviceErr *serviceError
+		if err != nil && row.Error == nil && !errors.As(err, &serviceErr) {
+			return err
+		}
+		if err != nil {
+			msg := err.Error()
+			rowResult.Error = &msg
+			result.Failed++
+		} else {
+			result.Imported++
+		}
+		result.Rows = append(result.Rows, rowResult)
+		return nil
+	}
+
+	switch mode {
+	case entity.ImportAtomic:
+		err := s.dbClient.Transaction(ctx, func(tx database.Tx) error {
+			for _, row := range rows {
+				if err := importRow(tx, row); err != nil {
+					return err
+				}
+			}
+			if result.Failed > 0 {
+				return errImportFailed
+			}
+			return nil
+		})
+		if errors.Is(err, errImportFailed) {
+			// Nothing was added, so the rows that went through don't count
+			result.Imported = 0
+			for i := range result.Rows {
+				result.Rows[i].Table = nil
+			}
+		} else if err != nil {
+			return nil, err
+		}
+	case entity.ImportBestEffort:
+		for _, row := range rows {
+			err := s.dbClient.Transaction(ctx, func(tx database.Tx) error {
+				return importRow(tx, row)
+			})
+			if err != nil {
+				return nil, err
+			}
+		}
+	default:
+		return nil, newError(ErrInvalidImport, "import mode must be %s or %s", entity.ImportAtomic, entity.ImportBestEffort)
+	}
+
+	s.relayOutbox()
+
+	return &result, nil
+}
@@ -0,0 +1,49 @@
+package guest_list
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseImport(t *testing.T) {
+	// Test parsing CSV with columns in any order
+	csvInput := `accompanying_guests,name,table
+2,john,1
+0,rob,
+x,mary,1
+1,,2
+`
+	rows, err := ParseImport(strings.NewReader(csvInput), ImportCSV)
+	assert.Nil(t, err, "Error while parsing CSV, %v", err)
+	assert.Equal(t, 4, len(rows))
+	assert.Equal(t, "john", rows[0].Name)
+	assert.Equal(t, 1, *rows[0].Table)
+	assert.Equal(t, 2, rows[0].AccompanyingGuests)
+	assert.Equal(t, 2, rows[0].Line)
+	assert.Nil(t, rows[1].Table)
+	assert.Nil(t, rows[1].Error)
+	assert.NotNil(t, rows[2].Error)
+	assert.EqualError(t, rows[3].Error, "name is required")
+	assert.Equal(t, 5, rows[3].Line)
+
+	// Test a CSV file without names
+	_, err = ParseImport(strings.NewReader("table\n1\n"), ImportCSV)
+	assert.NotNil(t, err)
+
+	// Test parsing JSON lines
+	ndjsonInput := `{"name": "john", "table": 1, "accompanying_guests": 2}
+{"name": "rob"}
+{"name": "mary", "accompanying_guests": -1}
+{"name": 
+`
+	rows, err = ParseImport(strings.NewReader(ndjsonInput), ImportNDJSON)
+	assert.Nil(t, err, "Error while parsing JSON lines, %v", err)
+	assert.Equal(t, 4, len(rows))
+	assert.Equal(t, 1, *rows[0].Table)
+	assert.Nil(t, rows[1].Table)
+	assert.EqualError(t, rows[2].Error, "accompanying_guests cannot be negative")
+	assert.NotNil(t, rows[3].Error)
+	assert.Equal(t, 4, rows[3].Line)
+}
@@ -0,0 +1,140 @@
+package guest_list
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"strings"
+	"time"
+
+	"github.com/getground/tech-tasks/backend/internal/entity"
+	"github.com/getground/tech-tasks/backend/pkg/database"
+)
+
+// guestCursor is the position of the last guest of a page, which the next
+// page starts after. It is handed out base64 encoded.
+type guestCursor struct {
+	Sort        string     `json:"sort"`
+	Name        string     `json:"name,omitempty"`
+	TimeArrived *time.Time `json:"time_arrived,omitempty"`
+	ID          int        `json:"id"`
+}
+
+func encodeGuestCursor(sort string, guest entity.Guest) string {
+	cursor := guestCursor{Sort: sort, ID: guest.ID}
+	if strings.TrimPrefix(sort, "-") == entity.GuestSortArrival {
+		cursor.TimeArrived = guest.TimeArrived
+	} else {
+		cursor.Name = guest.Name
+	}
+	data, _ := json.Marshal(cursor)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decodeGuestCursor(encoded string) (guestCursor, error) {
+	var cursor guestCursor
+	data, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err == nil {
+		err = json.Unmarshal(data, &cursor)
+	}
+	if err != nil {
+		return cursor, newError(ErrInvalidGuestFilter, "malformed cursor")
+	}
+	return cursor, nil
+}
+
+// guestOrder returns the columns guests are sorted by in the order sort
+// names, breaking ties by id so every guest has a single position.
+func guestOrder(sort string) ([]database.Order, error) {
+	desc := strings.HasPrefix(sort, "-")
+	var column string
+	switch strings.TrimPrefix(sort, "-") {
+	case entity.GuestSortName:
+		column = "name"
+	case entity.GuestSortArrival:
+		column = "time_arrived"
+	default:
+		return nil, newError(ErrInvalidGuestFilter, "cannot sort guests by `%s`", sort)
+	}
+	return []database.Order{{Column: column, Desc: desc}, {Column: "id", Desc: desc}}, nil
+}
+
+// escapeLike escapes the wildcards of a LIKE pattern in s.
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
+
+// listGuests returns a page of the guests of an event matching filter and
+// every condition, along with the cursor of the next page, nil on the last
+// one.
+func (s *service) listGuests(ctx context.Context, eventID int, filter entity.GuestFilter, conditions ...database.Condition) ([]entity.Guest, *string, error) {
+	if filter.Sort == "" {
+		filter.Sort = entity.GuestSortName
+	}
+	orderBy, err := guestOrder(filter.Sort)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	limit := filter.Limit
+	if limit == 0 {
+		limit = entity.DefaultGuestPageSize
+	}
+	if limit < 0 || limit > entity.MaxGuestPageSize {
+		return nil, nil, newError(ErrInvalidGuestFilter, "limit must be between 1 and %d", entity.MaxGuestPageSize)
+	}
+	if filter.ArrivedSince != nil && filter.ArrivedUntil != nil && filter.ArrivedUntil.Before(*filter.ArrivedSince) {
+		return nil, nil, newError(ErrInvalidGuestFilter, "the arrival window cannot end before it starts")
+	}
+
+	if filter.Table != nil {
+		conditions = append(conditions, database.Eq("table_id", *filter.Table))
+	}
+	if filter.NamePrefix != nil {
+		conditions = append(conditions, database.Like("name", escapeLike(*filter.NamePrefix)+"%"))
+	}
+	if filter.ArrivedSince != nil {
+		conditions = append(conditions, database.Ge("time_arrived", *filter.ArrivedSince))
+	}
+	if filter.ArrivedUntil != nil {
+		conditions = append(conditions, database.Lt("time_arrived", *filter.ArrivedUntil))
+	}
+	if filter.CheckedIn != nil {
+		if *filter.CheckedIn {
+			conditions = append(conditions, database.Eq("status", entity.GuestStatusArrived))
+		} else {
+			conditions = append(conditions, database.Ne("status", entity.GuestStatusArrived))
+		}
+	}
+
+	// One more guest than asked for tells whether there is a next page
+	query := eventQuery(eventID, conditions...).OrderBy(orderBy...).Limit(limit + 1)
+
+	if filter.Cursor != "" {
+		cursor, err := decodeGuestCursor(filter.Cursor)
+		if err != nil {
+			return nil, nil, err
+		}
+		if cursor.Sort != filter.Sort {
+			return nil, nil, newError(ErrInvalidGuestFilter, "the cursor belongs to guests sorted by `%s`", cursor.Sort)
+		}
+		if orderBy[0].Column == "time_arrived" {
+			query = query.After(cursor.TimeArrived, cursor.ID)
+		} else {
+			query = query.After(cursor.Name, cursor.ID)
+		}
+	}
+
+	guests := []entity.Guest{}
+	err = s.dbClient.FindMany(ctx, &guests, "guest", query)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if len(guests) <= limit {
+		return guests, nil, nil
+	}
+	guests = guests[:limit]
+	next := encodeGuestCursor(filter.Sort, guests[limit-1])
+	return guests, &next, nil
+}
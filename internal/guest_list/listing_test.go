@@ -0,0 +1,134 @@
+package guest_list
+
+import (
+	"testing"
+	"time"
+
+	"github.com/getground/tech-tasks/backend/internal/entity"
+	"github.com/stretchr/testify/assert"
+)
+
+func listedNames(guests []entity.GetAllGuestsElement) []string {
+	names := make([]string, len(guests))
+	for i, guest := range guests {
+		names[i] = guest.Name
+	}
+	return names
+}
+
+func TestListGuests(t *testing.T) {
+	setupServiceTest()
+	defer dbClient.Close()
+
+	first, err := guestListService.CreateTable(ctx, entity.DefaultEventID, &entity.Table{Capacity: 10})
+	assert.Nil(t, err, "Error while creating table, %v", err)
+	second, err := guestListService.CreateTable(ctx, entity.DefaultEventID, &entity.Table{Capacity: 10})
+	assert.Nil(t, err, "Error while creating table, %v", err)
+
+	for _, guest := range []entity.Guest{
+		{Name: "rob", TableID: first.ID},
+		{Name: "john", TableID: second.ID},
+		{Name: "jane", TableID: first.ID},
+		{Name: "jo_e", TableID: second.ID},
+		{Name: "amy", TableID: first.ID},
+	} {
+		_, err = guestListService.AddGuest(ctx, entity.DefaultEventID, &guest)
+		assert.Nil(t, err, "Error while adding guest, %v", err)
+	}
+
+	// jane arrives first, then rob, then john who leaves again
+	start := clock.now
+	for _, name := range []string{"jane", "rob", "john"} {
+		clock.now = clock.now.Add(time.Minute)
+		_, err = guestListService.CheckInGuest(ctx, entity.DefaultEventID, &entity.Guest{Name: name})
+		assert.Nil(t, err, "Error while checking in guest, %v", err)
+	}
+	err = guestListService.CheckoutGuest(ctx, entity.DefaultEventID, &entity.Guest{Name: "john"})
+	assert.Nil(t, err, "Error while checking out guest, %v", err)
+
+	// Test guests are sorted by name by default and paged through
+	page, err := guestListService.GetAllGuests(ctx, entity.DefaultEventID, entity.GuestFilter{Limit: 2})
+	assert.Nil(t, err, "Error while getting guests, %v", err)
+	assert.Equal(t, []string{"amy", "jane"}, listedNames(page.Guests))
+	assert.NotNil(t, page.NextCursor)
+	byName := *page.NextCursor
+
+	var names []string
+	for page.NextCursor != nil {
+		page, err = guestListService.GetAllGuests(ctx, entity.DefaultEventID, entity.GuestFilter{Limit: 2, Cursor: *page.NextCursor})
+		assert.Nil(t, err, "Error while getting guests, %v", err)
+		names = append(names, listedNames(page.Guests)...)
+	}
+	assert.Equal(t, []string{"jo_e", "john", "rob"}, names)
+
+	// Test the filters
+	tests := []struct {
+		name   string
+		filter entity.GuestFilter
+		guests []string
+	}{
+		{"table", entity.GuestFilter{Table: &first.ID}, []string{"amy", "jane", "rob"}},
+		{"name prefix", entity.GuestFilter{NamePrefix: stringPtr("jo")}, []string{"jo_e", "john"}},
+		{"name prefix with a wildcard", entity.GuestFilter{NamePrefix: stringPtr("jo_")}, []string{"jo_e"}},
+		{"checked in", entity.GuestFilter{CheckedIn: boolPtr(true)}, []string{"jane", "rob"}},
+		{"not checked in", entity.GuestFilter{CheckedIn: boolPtr(false)}, []string{"amy", "jo_e", "john"}},
+		{"arrival window", entity.GuestFilter{ArrivedSince: timePtr(start.Add(2 * time.Minute)), ArrivedUntil: timePtr(start.Add(4 * time.Minute))}, []string{"john", "rob"}},
+		{"arrival order", entity.GuestFilter{Sort: entity.GuestSortArrival}, []string{"jo_e", "amy", "jane", "rob", "john"}},
+		{"reversed arrival order", entity.GuestFilter{Sort: "-" + entity.GuestSortArrival, Table: &first.ID}, []string{"rob", "jane", "amy"}},
+		{"reversed name order", entity.GuestFilter{Sort: "-" + entity.GuestSortName}, []string{"rob", "john", "jo_e", "jane", "amy"}},
+	}
+	for _, tc := range tests {
+		page, err := guestListService.GetAllGuests(ctx, entity.DefaultEventID, tc.filter)
+		assert.Nil(t, err, "Error while getting guests filtered by %s, %v", tc.name, err)
+		assert.Equal(t, tc.guests, listedNames(page.Guests), "guests filtered by %s", tc.name)
+	}
+
+	// Test paging by arrival goes past guests who haven't arrived
+	filter := entity.GuestFilter{Sort: entity.GuestSortArrival, Limit: 1}
+	names = nil
+	for {
+		page, err = guestListService.GetAllGuests(ctx, entity.DefaultEventID, filter)
+		assert.Nil(t, err, "Error while getting guests, %v", err)
+		names = append(names, listedNames(page.Guests)...)
+		if page.NextCursor == nil {
+			break
+		}
+		filter.Cursor = *page.NextCursor
+	}
+	assert.Equal(t, []string{"jo_e", "amy", "jane", "rob", "john"}, names)
+
+	// Test checked in guests are paged through too
+	arrived, err := guestListService.GetAllCheckedInGuests(ctx, entity.DefaultEventID, entity.GuestFilter{Sort: entity.GuestSortArrival, Limit: 1})
+	assert.Nil(t, err, "Error while getting checked in guests, %v", err)
+	assert.Equal(t, 1, len(arrived.Guests))
+	assert.Equal(t, "jane", arrived.Guests[0].Name)
+	arrived, err = guestListService.GetAllCheckedInGuests(ctx, entity.DefaultEventID, entity.GuestFilter{Sort: entity.GuestSortArrival, Cursor: *arrived.NextCursor})
+	assert.Nil(t, err, "Error while getting checked in guests, %v", err)
+	assert.Equal(t, 1, len(arrived.Guests))
+	assert.Equal(t, "rob", arrived.Guests[0].Name)
+	assert.Nil(t, arrived.NextCursor)
+
+	// Test invalid filters
+	_, err = guestListService.GetAllGuests(ctx, entity.DefaultEventID, entity.GuestFilter{Sort: "table"})
+	assert.ErrorIs(t, err, ErrInvalidGuestFilter)
+	_, err = guestListService.GetAllGuests(ctx, entity.DefaultEventID, entity.GuestFilter{Limit: entity.MaxGuestPageSize + 1})
+	assert.ErrorIs(t, err, ErrInvalidGuestFilter)
+	_, err = guestListService.GetAllGuests(ctx, entity.DefaultEventID, entity.GuestFilter{Cursor: "not a cursor"})
+	assert.ErrorIs(t, err, ErrInvalidGuestFilter)
+	_, err = guestListService.GetAllGuests(ctx, entity.DefaultEventID, entity.GuestFilter{Sort: entity.GuestSortArrival, Cursor: byName})
+	assert.ErrorIs(t, err, ErrInvalidGuestFilter, "cursors only continue listings in their own order")
+	_, err = guestListService.GetAllGuests(ctx, entity.DefaultEventID, entity.GuestFilter{ArrivedSince: timePtr(start), ArrivedUntil: timePtr(start.Add(-time.Minute))})
+	assert.ErrorIs(t, err, ErrInvalidGuestFilter)
+}
+
+func stringPtr(s string) *string {
+	return &s
+}
+
+func boolPtr(b bool) *bool {
+	return &b
+}
+
+func timePtr(t time.Time) *time.Time {
+	return &t
+}
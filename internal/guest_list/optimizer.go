@@ -0,0 +1,271 @@
+package guest_list
+
+import (
+	"sort"
+
+	"github.com/getground/tech-tasks/backend/internal/entity"
+)
+
+// maxSearchPasses bounds the local search so large guest lists still get an
+// answer quickly.
+const maxSearchPasses = 20
+
+// seatingParty is a guest and their companions, who always share a table.
+type seatingParty struct {
+	guestID int
+	seats   int
+	// table is the index of the party's current table
+	table int
+	// fixed parties have arrived and keep their seats
+	fixed bool
+}
+
+// seatingProblem assigns parties to tables without exceeding any capacity,
+// violating as few constraints as possible. Table indexes are positions in
+// tables rather than table ids.
+type seatingProblem struct {
+	tables      []entity.Table
+	parties     []seatingParty
+	constraints []entity.SeatingConstraint
+
+	// byGuest maps guest ids onto party indexes
+	byGuest map[int]int
+	// involving lists the constraints each party takes part in
+	involving [][]int
+}
+
+func newSeatingProblem(tables []entity.Table, parties []seatingParty, constraints []entity.SeatingConstraint) *seatingProblem {
+	p := &seatingProblem{
+		tables:    tables,
+		parties:   parties,
+		byGuest:   make(map[int]int, len(parties)),
+		involving: make([][]int, len(parties)),
+	}
+	for i, party := range parties {
+		p.byGuest[party.guestID] = i
+	}
+
+	// Constraints on guests who aren't seated, like departed ones, can't be
+	// violated so they are left out
+	for _, c := range constraints {
+		a, ok := p.byGuest[c.GuestID]
+		if !ok {
+			continue
+		}
+		if c.Kind != entity.ConstraintRequires {
+			if c.OtherGuestID == nil {
+				continue
+			}
+			b, ok := p.byGuest[*c.OtherGuestID]
+			if !ok {
+				continue
+			}
+			p.involving[b] = append(p.involving[b], len(p.constraints))
+		}
+		p.involving[a] = append(p.involving[a], len(p.constraints))
+		p.constraints = append(p.constraints, c)
+	}
+	return p
+}
+
+// violated reports whether constraint c is broken by assign. Parties that
+// are not placed yet, marked with -1, never violate a constraint.
+func (p *seatingProblem) violated(c int, assign []int) bool {
+	constraint := p.constraints[c]
+	a := assign[p.byGuest[constraint.GuestID]]
+	if a < 0 {
+		return false
+	}
+
+	switch constraint.Kind {
+	case entity.ConstraintRequires:
+		return !p.tables[a].Attributes.Has(*constraint.Attribute)
+	default:
+		b := assign[p.byGuest[*constraint.OtherGuestID]]
+		if b < 0 {
+			return false
+		}
+		if constraint.Kind == entity.ConstraintTogether {
+			return a != b
+		}
+		return a == b
+	}
+}
+
+// cost counts the constraints broken by assign.
+func (p *seatingProblem) cost(assign []int) int {
+	cost := 0
+	for c := range p.constraints {
+		if p.violated(c, assign) {
+			cost++
+		}
+	}
+	return cost
+}
+
+// localCost counts the broken constraints involving any of parties.
+func (p *seatingProblem) localCost(assign []int, parties ...int) int {
+	seen := map[int]bool{}
+	cost := 0
+	for _, i := range parties {
+		for _, c := range p.involving[i] {
+			if seen[c] {
+				continue
+			}
+			seen[c] = true
+			if p.violated(c, assign) {
+				cost++
+			}
+		}
+	}
+	return cost
+}
+
+// freeSeats returns the seats left at each table under assign.
+func (p *seatingProblem) freeSeats(assign []int) []int {
+	free := make([]int, len(p.tables))
+	for t, table := range p.tables {
+		free[t] = table.Capacity
+	}
+	for i, party := range p.parties {
+		if assign[i] >= 0 {
+			free[assign[i]] -= party.seats
+		}
+	}
+	return free
+}
+
+// current returns the assignment the parties are seated with today.
+func (p *seatingProblem) current() []int {
+	assign := make([]int, len(p.parties))
+	for i, party := range p.parties {
+		assign[i] = party.table
+	}
+	return assign
+}
+
+// solve builds a plan greedily, improves it by moving and swapping parties
+// and returns it, or the current seating when that is at least as good.
+func (p *seatingProblem) solve() []int {
+	assign, ok := p.greedy()
+	if !ok {
+		assign = p.current()
+	}
+	p.improve(assign)
+
+	current := p.current()
+	if p.cost(current) <= p.cost(assign) {
+		return current
+	}
+	return assign
+}
+
+// greedy places the most constrained and largest parties first, each at the
+// table breaking the fewest constraints with the parties already placed. It
+// fails when a party no longer fits anywhere.
+func (p *seatingProblem) greedy() ([]int, bool) {
+	assign := make([]int, len(p.parties))
+	var order []int
+	for i, party := range p.parties {
+		if party.fixed {
+			assign[i] = party.table
+		} else {
+			assign[i] = -1
+			order = append(order, i)
+		}
+	}
+	sort.SliceStable(order, func(x, y int) bool {
+		a, b := order[x], order[y]
+		if len(p.involving[a]) != len(p.involving[b]) {
+			return len(p.involving[a]) > len(p.involving[b])
+		}
+		return p.parties[a].seats > p.parties[b].seats
+	})
+
+	free := p.freeSeats(assign)
+	for _, i := range order {
+		party := p.parties[i]
+		best, bestCost := -1, 0
+		for t := range p.tables {
+			if free[t] < party.seats {
+				continue
+			}
+			assign[i] = t
+			cost := p.localCost(assign, i)
+			if best < 0 || cost < bestCost || (cost == bestCost && p.preferTable(i, t, best, free)) {
+				best, bestCost = t, cost
+			}
+		}
+		if best < 0 {
+			return nil, false
+		}
+		assign[i] = best
+		free[best] -= party.seats
+	}
+	return assign, true
+}
+
+// preferTable breaks ties between tables t and other for party i, keeping
+// the party where it sits and otherwise leaving the fewest seats over.
+func (p *seatingProblem) preferTable(i, t, other int, free []int) bool {
+	if t == p.parties[i].table || other == p.parties[i].table {
+		return t == p.parties[i].table
+	}
+	return free[t] < free[other]
+}
+
+// improve moves single parties and swaps pairs of parties while that breaks
+// fewer constraints.
+func (p *seatingProblem) improve(assign []int) {
+	free := p.freeSeats(assign)
+	for pass := 0; pass < maxSearchPasses; pass++ {
+		improved := false
+
+		for i, party := range p.parties {
+			if party.fixed {
+				continue
+			}
+			for t := range p.tables {
+				from := assign[i]
+				if t == from || free[t] < party.seats {
+					continue
+				}
+				before := p.localCost(assign, i)
+				assign[i] = t
+				if p.localCost(assign, i) < before {
+					free[from] += party.seats
+					free[t] -= party.seats
+					improved = true
+				} else {
+					assign[i] = from
+				}
+			}
+		}
+
+		for i := range p.parties {
+			for j := i + 1; j < len(p.parties); j++ {
+				a, b := assign[i], assign[j]
+				if p.parties[i].fixed || p.parties[j].fixed || a == b {
+					continue
+				}
+				diff := p.parties[i].seats - p.parties[j].seats
+				if free[b] < diff || free[a] < -diff {
+					continue
+				}
+				before := p.localCost(assign, i, j)
+				assign[i], assign[j] = b, a
+				if p.localCost(assign, i, j) < before {
+					free[a] += diff
+					free[b] -= diff
+					improved = true
+				} else {
+					assign[i], assign[j] = a, b
+				}
+			}
+		}
+
+		if !improved {
+			return
+		}
+	}
+}
@@ -0,0 +1,57 @@
+package guest_list
+
+import (
+	"testing"
+
+	"github.com/getground/tech-tasks/backend/internal/entity"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSeatingProblemSolve(t *testing.T) {
+	quiet := "quiet"
+	tables := []entity.Table{
+		{ID: 10, Capacity: 3},
+		{ID: 20, Capacity: 3, Attributes: entity.Attributes{quiet}},
+	}
+	together := func(a, b int) entity.SeatingConstraint {
+		return entity.SeatingConstraint{Kind: entity.ConstraintTogether, GuestID: a, OtherGuestID: &b}
+	}
+	apart := func(a, b int) entity.SeatingConstraint {
+		return entity.SeatingConstraint{Kind: entity.ConstraintApart, GuestID: a, OtherGuestID: &b}
+	}
+
+	// Both tables are full, so the parties of guests 2 and 4 must swap
+	parties := []seatingParty{
+		{guestID: 1, seats: 2, table: 0},
+		{guestID: 2, seats: 1, table: 0},
+		{guestID: 3, seats: 2, table: 1},
+		{guestID: 4, seats: 1, table: 1},
+	}
+	constraints := []entity.SeatingConstraint{together(2, 3), together(1, 4)}
+	problem := newSeatingProblem(tables, parties, constraints)
+	assign := problem.solve()
+	assert.Equal(t, []int{0, 1, 1, 0}, assign)
+	assert.Equal(t, 0, problem.cost(assign))
+
+	// Fixed parties stay put and contradicting constraints leave one violation
+	parties = []seatingParty{
+		{guestID: 1, seats: 1, table: 0, fixed: true},
+		{guestID: 2, seats: 1, table: 0},
+		{guestID: 3, seats: 1, table: 1},
+	}
+	constraints = []entity.SeatingConstraint{
+		together(1, 2),
+		apart(1, 2),
+		{Kind: entity.ConstraintRequires, GuestID: 1, Attribute: &quiet},
+		{Kind: entity.ConstraintRequires, GuestID: 3, Attribute: &quiet},
+	}
+	problem = newSeatingProblem(tables, parties, constraints)
+	assign = problem.solve()
+	assert.Equal(t, 0, assign[0])
+	assert.Equal(t, 1, assign[2])
+	assert.Equal(t, 2, problem.cost(assign))
+
+	// Constraints on guests who aren't seated are ignored
+	problem = newSeatingProblem(tables, parties, []entity.SeatingConstraint{together(2, 99)})
+	assert.Equal(t, 0, len(problem.constraints))
+}
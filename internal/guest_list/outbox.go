@@ -0,0 +1,282 @@
+package guest_list
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/getground/tech-tasks/backend/internal/entity"
+	"github.com/getground/tech-tasks/backend/pkg/database"
+)
+
+// Defaults of the Relay.
+const (
+	DefaultRelayInterval = time.Second
+	DefaultRelayRetry    = 10 * time.Second
+)
+
+// relayLease is how long a claimed outbox entry is left alone by other
+// relays, so the entries of a relay that died are picked up again.
+const relayLease = time.Minute
+
+// maxOutboxError keeps recorded errors within their column.
+const maxOutboxError = 1024
+
+// Sink receives the events relayed from the outbox. Name identifies the sink
+// in the outbox so it must be unique, stable and without commas.
+type Sink interface {
+	Name() string
+	Send(ctx context.Context, event entity.StreamEvent) error
+}
+
+type sink struct {
+	name string
+	send func(ctx context.Context, event entity.StreamEvent) error
+}
+
+// NewSink returns a Sink called name sending events with send.
+func NewSink(name string, send func(ctx context.Context, event entity.StreamEvent) error) Sink {
+	return sink{name, send}
+}
+
+func (s sink) Name() string {
+	return s.name
+}
+
+func (s sink) Send(ctx context.Context, event entity.StreamEvent) error {
+	return s.send(ctx, event)
+}
+
+// BusSink publishes the events on bus for the live stream.
+func BusSink(bus *Bus) Sink {
+	return NewSink("bus", func(ctx context.Context, event entity.StreamEvent) error {
+		bus.Publish(event)
+		return nil
+	})
+}
+
+// LogSink appends the events to w as JSON lines.
+func LogSink(w io.Writer) Sink {
+	var mu sync.Mutex
+	encoder := json.NewEncoder(w)
+	return NewSink("log", func(ctx context.Context, event entity.StreamEvent) error {
+		mu.Lock()
+		defer mu.Unlock()
+		return encoder.Encode(event)
+	})
+}
+
+// Relay sends the entries of the outbox to its sinks. Each entry is marked
+// delivered once, after every sink accepted it. Sinks are retried until they
+// accept an entry and may see it twice if the relay stops right after one
+// accepted it.
+type Relay struct {
+	dbClient database.Client
+	sinks    []Sink
+	clock    Clock
+	interval time.Duration
+	retry    time.Duration
+	wake     chan struct{}
+}
+
+// RelayOption configures the Relay returned by NewRelay.
+type RelayOption func(*Relay)
+
+// WithRelayClock makes the relay read the current time from clock.
+func WithRelayClock(clock Clock) RelayOption {
+	return func(r *Relay) {
+		r.clock = clock
+	}
+}
+
+// WithRelayInterval sets how often Run looks for pending entries.
+func WithRelayInterval(interval time.Duration) RelayOption {
+	return func(r *Relay) {
+		r.interval = interval
+	}
+}
+
+// WithRelayRetry sets how long an entry waits after a sink refused it.
+func WithRelayRetry(retry time.Duration) RelayOption {
+	return func(r *Relay) {
+		r.retry = retry
+	}
+}
+
+func NewRelay(dbClient database.Client, sinks []Sink, opts ...RelayOption) *Relay {
+	r := &Relay{
+		dbClient: dbClient,
+		sinks:    sinks,
+		clock:    systemClock{},
+		interval: DefaultRelayInterval,
+		retry:    DefaultRelayRetry,
+		wake:     make(chan struct{}, 1),
+	}
+	for _, opt := range opts {
+		opt(r)
+	}
+	return r
+}
+
+// WithRelay makes the service relay its outbox with relay, which should be
+// running. relay should include a BusSink for the live stream to work.
+// Without a relay the service leaves its entries pending for the relay of
+// another process, like the server's.
+func WithRelay(relay *Relay) Option {
+	return func(s *service) {
+		s.relay = relay
+	}
+}
+
+// Notify wakes the relay up to send newly committed entries.
+func (r *Relay) Notify() {
+	select {
+	case r.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Run relays pending entries until ctx is done. The service notifies the
+// relay as soon as its entries commit, polling catches the ones left behind
+// by refusing sinks or other processes.
+func (r *Relay) Run(ctx context.Context) {
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+
+	for {
+		_, err := r.RelayPending(ctx)
+		if err != nil && ctx.Err() == nil {
+			log.Printf("Error %s when relaying the outbox", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-r.wake:
+		}
+	}
+}
+
+// RelayPending sends every due pending entry, oldest first, and returns how
+// many were delivered.
+func (r *Relay) RelayPending(ctx context.Context) (int, error) {
+	entries := []entity.OutboxEntry{}
+	query := database.Where(database.Eq("status", entity.OutboxStatusPending)).OrderBy(database.Asc("id"))
+	err := r.dbClient.FindMany(ctx, &entries, "outbox", query)
+	if err != nil {
+		return 0, err
+	}
+
+	delivered := 0
+	for _, entry := range entries {
+		if !r.due(entry) {
+			continue
+		}
+		ok, err := r.relay(ctx, entry)
+		if err != nil {
+			return delivered, err
+		}
+		if ok {
+			delivered++
+		}
+	}
+	return delivered, nil
+}
+
+func (r *Relay) due(entry entity.OutboxEntry) bool {
+	return entry.Status == entity.OutboxStatusPending && (entry.NextAttempt == nil || !entry.NextAttempt.After(r.clock.Now()))
+}
+
+// relay claims entry and sends it to the sinks that don't have it yet. It
+// reports whether the entry was delivered.
+func (r *Relay) relay(ctx context.Context, entry entity.OutboxEntry) (bool, error) {
+	claimed := false
+	err := r.dbClient.Transaction(ctx, func(tx database.Tx) error {
+		err := tx.FindUniqueForUpdate(ctx, &entry, "outbox", database.By("id", entry.ID))
+		if errors.Is(err, database.ErrNotFound) {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		if !r.due(entry) {
+			return nil
+		}
+
+		entry.Attempts++
+		lease := r.now().Add(relayLease)
+		columnsToUpdate := []string{"attempts", "next_attempt"}
+		err = tx.Update(ctx, "outbox", database.By("id", entry.ID), columnsToUpdate, entry.Attempts, lease)
+		if err != nil {
+			return err
+		}
+		claimed = true
+		return nil
+	})
+	if err != nil || !claimed {
+		return false, err
+	}
+
+	event := entry.StreamEvent()
+	for _, sink := range r.sinks {
+		if entry.DeliveredTo.Has(sink.Name()) {
+			continue
+		}
+
+		sendErr := sink.Send(ctx, event)
+		if sendErr != nil {
+			msg := fmt.Sprintf("sink %s: %s", sink.Name(), sendErr)
+			if len(msg) > maxOutboxError {
+				msg = msg[:maxOutboxError]
+			}
+			columnsToUpdate := []string{"last_error", "next_attempt"}
+			err = r.dbClient.Update(ctx, "outbox", database.By("id", entry.ID), columnsToUpdate, msg, r.now().Add(r.retry))
+			return false, err
+		}
+
+		// Remember the sink so a retry doesn't send it the entry again
+		entry.DeliveredTo = append(entry.DeliveredTo, sink.Name())
+		err = r.dbClient.Update(ctx, "outbox", database.By("id", entry.ID), []string{"delivered_to"}, entry.DeliveredTo)
+		if err != nil {
+			return false, err
+		}
+	}
+
+	columnsToUpdate := []string{"status", "next_attempt", "time_delivered"}
+	values := []interface{}{entity.OutboxStatusDelivered, nil, r.now()}
+	err = r.dbClient.Update(ctx, "outbox", database.By("id", entry.ID), columnsToUpdate, values...)
+	return err == nil, err
+}
+
+// now returns the current time at the precision stored in DATETIME columns.
+func (r *Relay) now() time.Time {
+	return r.clock.Now().UTC().Truncate(time.Second)
+}
+
+// record writes an event about a change made in tx to the outbox, so the
+// event is relayed if and only if the change commits. The empty seats are
+// counted as they are at this point of tx.
+func (s *service) record(ctx context.Context, tx database.Tx, eventID int, kind string, guest *string, tableID *int) error {
+	emptySeats, err := countEmptySeats(ctx, tx, eventID)
+	if err != nil {
+		return err
+	}
+
+	columns := []string{"event_id", "type", "guest", "table_id", "empty_seats", "time_created"}
+	values := []interface{}{eventID, kind, guest, tableID, emptySeats, s.now()}
+	_, err = tx.Create(ctx, "outbox", columns, values...)
+	return err
+}
+
+// relayOutbox wakes the relay up to send the entries committed by the caller,
+// keeping the sinks off the path of the change.
+func (s *service) relayOutbox() {
+	if s.relay != nil {
+		s.relay.Notify()
+	}
+}
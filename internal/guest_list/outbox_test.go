@@ -0,0 +1,129 @@
+package guest_list
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/getground/tech-tasks/backend/internal/entity"
+	"github.com/getground/tech-tasks/backend/pkg/database"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOutbox(t *testing.T) {
+	setupServiceTest()
+	defer dbClient.Close()
+
+	// The second sink refuses the first event it is sent
+	var accepted, refused int
+	var logged bytes.Buffer
+	sinks := []Sink{
+		NewSink("accepting", func(ctx context.Context, event entity.StreamEvent) error {
+			accepted++
+			return nil
+		}),
+		NewSink("refusing", func(ctx context.Context, event entity.StreamEvent) error {
+			if refused == 0 {
+				refused++
+				return errors.New("sink is down")
+			}
+			return nil
+		}),
+		LogSink(&logged),
+	}
+	relay := NewRelay(dbClient, sinks, WithRelayClock(clock), WithRelayRetry(10*time.Second))
+	guestListService = NewGuestListService(dbClient, WithClock(clock), WithRelay(relay))
+
+	table := entity.Table{Capacity: 2}
+	response, err := guestListService.CreateTable(ctx, entity.DefaultEventID, &table)
+	assert.Nil(t, err, "Error while creating table, %v", err)
+
+	// Test the event stays in the outbox while a sink refuses it
+	delivered, err := relay.RelayPending(ctx)
+	assert.Nil(t, err, "Error while relaying, %v", err)
+	assert.Equal(t, 0, delivered)
+
+	entries := []entity.OutboxEntry{}
+	err = dbClient.FindMany(ctx, &entries, "outbox", database.Query{})
+	assert.Nil(t, err, "Error while reading outbox, %v", err)
+	assert.Equal(t, 1, len(entries))
+	assert.Equal(t, entity.OutboxStatusPending, entries[0].Status)
+	assert.Equal(t, entity.SinkNames{"accepting"}, entries[0].DeliveredTo)
+	assert.Equal(t, "sink refusing: sink is down", *entries[0].LastError)
+	assert.Equal(t, 2, entries[0].EmptySeats)
+
+	delivered, err = relay.RelayPending(ctx)
+	assert.Nil(t, err, "Error while relaying, %v", err)
+	assert.Equal(t, 0, delivered)
+
+	// Test the retry skips the sinks that already accepted the event
+	clock.now = clock.now.Add(10 * time.Second)
+	delivered, err = relay.RelayPending(ctx)
+	assert.Nil(t, err, "Error while relaying, %v", err)
+	assert.Equal(t, 1, delivered)
+	assert.Equal(t, 1, accepted)
+
+	var event entity.StreamEvent
+	err = json.Unmarshal(logged.Bytes(), &event)
+	assert.Nil(t, err, "Error while decoding log, %v", err)
+	assert.Equal(t, int64(entries[0].ID), event.ID)
+	assert.Equal(t, entity.StreamTableChanged, event.Type)
+
+	delivered, err = relay.RelayPending(ctx)
+	assert.Nil(t, err, "Error while relaying, %v", err)
+	assert.Equal(t, 0, delivered)
+
+	// Test changes that roll back leave nothing in the outbox
+	_, err = guestListService.AddGuest(ctx, entity.DefaultEventID, &entity.Guest{Name: "john", TableID: response.ID, AccompanyingGuests: 2})
+	assert.ErrorIs(t, err, ErrNoSeats)
+	_, err = guestListService.ImportGuests(ctx, entity.DefaultEventID, []entity.ImportGuestRow{
+		{Line: 1, Name: "rob", Table: &response.ID},
+		{Line: 2, Name: "mary", Table: &response.ID, AccompanyingGuests: 1},
+	}, entity.ImportAtomic)
+	assert.Nil(t, err, "Error while importing guests, %v", err)
+
+	entries = []entity.OutboxEntry{}
+	err = dbClient.FindMany(ctx, &entries, "outbox", database.Query{})
+	assert.Nil(t, err, "Error while reading outbox, %v", err)
+	assert.Equal(t, 1, len(entries))
+
+	// Test a service without a relay leaves its entries to another one, like
+	// the import command does for the server
+	_, err = NewGuestListService(dbClient, WithClock(clock)).CreateTable(ctx, entity.DefaultEventID, &table)
+	assert.Nil(t, err, "Error while creating table, %v", err)
+
+	delivered, err = relay.RelayPending(ctx)
+	assert.Nil(t, err, "Error while relaying, %v", err)
+	assert.Equal(t, 1, delivered)
+	assert.Equal(t, 2, accepted)
+}
+
+func TestRelayRun(t *testing.T) {
+	setupServiceTest()
+	defer dbClient.Close()
+
+	// Poll too rarely for anything but a notification to relay the event
+	relayed := make(chan entity.StreamEvent, 1)
+	sinks := []Sink{NewSink("test", func(ctx context.Context, event entity.StreamEvent) error {
+		relayed <- event
+		return nil
+	})}
+	relay := NewRelay(dbClient, sinks, WithRelayInterval(time.Hour))
+	runCtx, stop := context.WithCancel(ctx)
+	defer stop()
+	go relay.Run(runCtx)
+	guestListService = NewGuestListService(dbClient, WithClock(clock), WithRelay(relay))
+
+	// Test committed changes wake the relay up
+	_, err := guestListService.CreateTable(ctx, entity.DefaultEventID, &entity.Table{Capacity: 2})
+	assert.Nil(t, err, "Error while creating table, %v", err)
+	select {
+	case event := <-relayed:
+		assert.Equal(t, entity.StreamTableChanged, event.Type)
+	case <-time.After(5 * time.Second):
+		t.Fatal("the relay was not woken up")
+	}
+}
@@ -0,0 +1,336 @@
+package guest_list
+
+import (
+	"context"
+	"errors"
+
+	"github.com/getground/tech-tasks/backend/internal/entity"
+	"github.com/getground/tech-tasks/backend/pkg/database"
+)
+
+func (s *service) CreateSeatingConstraint(ctx context.Context, eventID int, request *entity.CreateSeatingConstraintRequestBody) (*entity.SeatingConstraintElement, error) {
+	columns := []string{"event_id", "kind", "guest_id", "other_guest_id", "attribute"}
+	var element entity.SeatingConstraintElement
+	err := s.dbClient.Transaction(ctx, func(tx database.Tx) error {
+		var guest entity.Guest
+		err := findGuest(ctx, tx, eventID, request.Guest, &guest)
+		if err != nil {
+			return err
+		}
+
+		var otherGuestID *int
+		var attribute *string
+		switch request.Kind {
+		case entity.ConstraintTogether, entity.ConstraintApart:
+			if request.OtherGuest == nil || *request.OtherGuest == request.Guest {
+				return newError(ErrInvalidConstraint, "%s constraints need another guest", request.Kind)
+			}
+			var otherGuest entity.Guest
+			err = findGuest(ctx, tx, eventID, *request.OtherGuest, &otherGuest)
+			if err != nil {
+				return err
+			}
+			otherGuestID = &otherGuest.ID
+		case entity.ConstraintRequires:
+			if request.Attribute == nil || *request.Attribute == "" {
+				return newError(ErrInvalidConstraint, "requires constraints need a table attribute")
+			}
+			attribute = request.Attribute
+		default:
+			return newError(ErrInvalidConstraint, "constraint kind must be together, apart or requires")
+		}
+
+		id, err := tx.Create(ctx, "seating_constraint", columns, eventID, request.Kind, guest.ID, otherGuestID, attribute)
+		if err != nil {
+			return err
+		}
+
+		element = entity.SeatingConstraintElement{
+			ID:        id,
+			Kind:      request.Kind,
+			Guest:     request.Guest,
+			Attribute: attribute,
+		}
+		if otherGuestID != nil {
+			element.OtherGuest = request.OtherGuest
+		}
+
+		constraint := entity.SeatingConstraint{
+			ID:           id,
+			EventID:      eventID,
+			Kind:         request.Kind,
+			GuestID:      guest.ID,
+			OtherGuestID: otherGuestID,
+			Attribute:    attribute,
+		}
+		return s.audit(ctx, tx, eventID, entity.AuditConstraintCreated, entity.AuditEntityConstraint, &guest.Name, nil, nil, &constraint)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &element, nil
+}
+
+func (s *service) GetSeatingConstraints(ctx context.Context, eventID int) ([]entity.SeatingConstraintElement, error) {
+	guests, err := findEventGuests(ctx, s.dbClient, eventID)
+	if err != nil {
+		return nil, err
+	}
+	constraints, err := findSeatingConstraints(ctx, s.dbClient, eventID)
+	if err != nil {
+		return nil, err
+	}
+
+	return constraintElements(constraints, guestNames(guests)), nil
+}
+
+func (s *service) DeleteSeatingConstraint(ctx context.Context, eventID int, id int) error {
+	return s.dbClient.Transaction(ctx, func(tx database.Tx) error {
+		key := database.Key{"event_id": eventID, "id": id}
+		var constraint entity.SeatingConstraint
+		err := tx.FindUniqueForUpdate(ctx, &constraint, "seating_constraint", key)
+		if errors.Is(err, database.ErrNotFound) {
+			return newError(ErrConstraintNotFound, "found no seating constraint with id %d", id)
+		} else if err != nil {
+			return err
+		}
+
+		var guest entity.Guest
+		err = tx.FindUnique(ctx, &guest, "guest", database.By("id", constraint.GuestID))
+		if err != nil {
+			return err
+		}
+
+		err = tx.Delete(ctx, "seating_constraint", key)
+		if err != nil {
+			return err
+		}
+
+		return s.audit(ctx, tx, eventID, entity.AuditConstraintDeleted, entity.AuditEntityConstraint, &guest.Name, nil, &constraint, nil)
+	})
+}
+
+// OptimizeSeating previews the seating plan without changing any table.
+func (s *service) OptimizeSeating(ctx context.Context, eventID int) (*entity.SeatingPlan, error) {
+	tables := []entity.Table{}
+	query := eventQuery(eventID).OrderBy(database.Asc("id"))
+	err := s.dbClient.FindMany(ctx, &tables, "table", query)
+	if err != nil {
+		return nil, err
+	}
+
+	guests, err := findEventGuests(ctx, s.dbClient, eventID)
+	if err != nil {
+		return nil, err
+	}
+
+	plan, _, err := planSeating(ctx, s.dbClient, eventID, tables, guests)
+	return plan, err
+}
+
+// ApplySeatingPlan moves the expected guests to the tables of the optimized
+// seating plan and recomputes the reserved seats of every table.
+func (s *service) ApplySeatingPlan(ctx context.Context, eventID int) (*entity.SeatingPlan, error) {
+	var plan *entity.SeatingPlan
+	var promoted []entity.WaitlistEntry
+	err := s.dbClient.Transaction(ctx, func(tx database.Tx) error {
+		tables, err := lockEventTables(ctx, tx, eventID)
+		if err != nil {
+			return err
+		}
+
+		// Plan from locked rows so no guest changes underneath the plan
+		guests, err := findEventGuests(ctx, tx, eventID)
+		if err != nil {
+			return err
+		}
+		for i := range guests {
+			err = tx.FindUniqueForUpdate(ctx, &guests[i], "guest", database.By("id", guests[i].ID))
+			if err != nil {
+				return err
+			}
+		}
+
+		var tableIDs map[int]int
+		plan, tableIDs, err = planSeating(ctx, tx, eventID, tables, guests)
+		if err != nil {
+			return err
+		}
+
+		reservedSeats := map[int]int{}
+		for _, guest := range guests {
+			if guest.Status == entity.GuestStatusDeparted {
+				continue
+			}
+			tableID := tableIDs[guest.ID]
+			reservedSeats[tableID] += guest.AccompanyingGuests + 1
+			if tableID == guest.TableID {
+				continue
+			}
+			err = tx.Update(ctx, "guest", database.By("id", guest.ID), []string{"table_id"}, tableID)
+			if err != nil {
+				return err
+			}
+			err = s.auditGuest(ctx, tx, eventID, entity.AuditGuestUpdated, guest.Name, &guest)
+			if err != nil {
+				return err
+			}
+		}
+
+		for i := range tables {
+			table := &tables[i]
+			if table.ReservedSeats != reservedSeats[table.ID] {
+				before := *table
+				table.ReservedSeats = reservedSeats[table.ID]
+				err = updateReservedSeats(ctx, tx, table.ID, table.ReservedSeats)
+				if err != nil {
+					return err
+				}
+				err = s.auditReservedSeats(ctx, tx, before)
+				if err != nil {
+					return err
+				}
+				err = s.record(ctx, tx, eventID, entity.StreamTableChanged, nil, &table.ID)
+				if err != nil {
+					return err
+				}
+			}
+
+			// Seats may have come free where the plan moved parties away
+			tablePromoted, err := s.promoteWaitlist(ctx, tx, table)
+			if err != nil {
+				return err
+			}
+			promoted = append(promoted, tablePromoted...)
+		}
+
+		plan.Applied = true
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	s.relayOutbox()
+	s.notifyPromotions(promoted)
+
+	return plan, nil
+}
+
+// planSeating runs the optimizer over the seated guests of the event and
+// returns the plan along with the planned table id of each guest id. Arrived
+// guests keep their seats, departed ones take no part.
+func planSeating(ctx context.Context, q database.Queryer, eventID int, tables []entity.Table, guests []entity.Guest) (*entity.SeatingPlan, map[int]int, error) {
+	constraints, err := findSeatingConstraints(ctx, q, eventID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	tableIndexes := make(map[int]int, len(tables))
+	for i, table := range tables {
+		tableIndexes[table.ID] = i
+	}
+
+	var seated []entity.Guest
+	var parties []seatingParty
+	for _, guest := range guests {
+		if guest.Status == entity.GuestStatusDeparted {
+			continue
+		}
+		seated = append(seated, guest)
+		parties = append(parties, seatingParty{
+			guestID: guest.ID,
+			seats:   guest.AccompanyingGuests + 1,
+			table:   tableIndexes[guest.TableID],
+			fixed:   guest.Status == entity.GuestStatusArrived,
+		})
+	}
+
+	problem := newSeatingProblem(tables, parties, constraints)
+	assign := problem.solve()
+
+	plan := entity.SeatingPlan{
+		Assignments: []entity.SeatingAssignment{},
+		Violations:  []entity.SeatingConstraintElement{},
+	}
+	tableIDs := make(map[int]int, len(seated))
+	for i, guest := range seated {
+		tableID := tables[assign[i]].ID
+		tableIDs[guest.ID] = tableID
+		plan.Assignments = append(plan.Assignments, entity.SeatingAssignment{
+			Name:               guest.Name,
+			AccompanyingGuests: guest.AccompanyingGuests,
+			Table:              tableID,
+			PreviousTable:      guest.TableID,
+		})
+		if tableID != guest.TableID {
+			plan.Moves++
+		}
+	}
+
+	var violated []entity.SeatingConstraint
+	for c, constraint := range problem.constraints {
+		if problem.violated(c, assign) {
+			violated = append(violated, constraint)
+		}
+	}
+	plan.Violations = append(plan.Violations, constraintElements(violated, guestNames(guests))...)
+
+	return &plan, tableIDs, nil
+}
+
+// findGuest loads the guest called name without locking it.
+func findGuest(ctx context.Context, q database.Queryer, eventID int, name string, guest *entity.Guest) error {
+	err := q.FindUnique(ctx, guest, "guest", guestKey(eventID, name))
+	if errors.Is(err, database.ErrNotFound) {
+		return newError(ErrGuestNotFound, "found no guest called `%s`", name)
+	}
+	return err
+}
+
+func findEventGuests(ctx context.Context, q database.Queryer, eventID int) ([]entity.Guest, error) {
+	guests := []entity.Guest{}
+	query := eventQuery(eventID).OrderBy(database.Asc("id"))
+	err := q.FindMany(ctx, &guests, "guest", query)
+	if err != nil {
+		return nil, err
+	}
+	return guests, nil
+}
+
+func findSeatingConstraints(ctx context.Context, q database.Queryer, eventID int) ([]entity.SeatingConstraint, error) {
+	constraints := []entity.SeatingConstraint{}
+	query := eventQuery(eventID).OrderBy(database.Asc("id"))
+	err := q.FindMany(ctx, &constraints, "seating_constraint", query)
+	if err != nil {
+		return nil, err
+	}
+	return constraints, nil
+}
+
+func guestNames(guests []entity.Guest) map[int]string {
+	names := make(map[int]string, len(guests))
+	for _, guest := range guests {
+		names[guest.ID] = guest.Name
+	}
+	return names
+}
+
+// constraintElements names the guests of constraints.
+func constraintElements(constraints []entity.SeatingConstraint, names map[int]string) []entity.SeatingConstraintElement {
+	elements := []entity.SeatingConstraintElement{}
+	for _, constraint := range constraints {
+		element := entity.SeatingConstraintElement{
+			ID:        constraint.ID,
+			Kind:      constraint.Kind,
+			Guest:     names[constraint.GuestID],
+			Attribute: constraint.Attribute,
+		}
+		if constraint.OtherGuestID != nil {
+			otherGuest := names[*constraint.OtherGuestID]
+			element.OtherGuest = &otherGuest
+		}
+		elements = append(elements, element)
+	}
+	return elements
+}
@@ -1,162 +1,879 @@
 package guest_list
 
 import (
-	"database/sql"
-	"fmt"
+	"context"
+	"errors"
+	"strings"
 	"time"
 
 	"github.com/getground/tech-tasks/backend/internal/entity"
 	"github.com/getground/tech-tasks/backend/pkg/database"
 )
 
+// GuestListService manages the tables and guests of events. Every method
+// apart from the event ones is scoped to the event with id eventID.
 type GuestListService interface {
-	CreateTable(table *entity.Table) (*entity.CreateTableResponseBody, error)
-	AddGuest(guest *entity.Guest) (*entity.AddGuestResponseBody, error)
-	GetAllGuests() ([]entity.GetAllGuestsElement, error)
-	GetAllCheckedInGuests() ([]entity.GetAllCheckedInGuestsElement, error)
-	CheckInGuest(guest *entity.Guest) (*entity.CheckInGuestResponseBody, error)
-	CountEmptySeats() (int, error)
-	CheckoutGuest(guest *entity.Guest) error
+	CreateEvent(ctx context.Context, event *entity.CreateEventRequestBody) (*entity.Event, error)
+	GetAllEvents(ctx context.Context) ([]entity.Event, error)
+	GetEvent(ctx context.Context, eventID int) (*entity.Event, error)
+	CreateTable(ctx context.Context, eventID int, table *entity.Table) (*entity.CreateTableResponseBody, error)
+	GetAllTables(ctx context.Context, eventID int) ([]entity.Table, error)
+	GetTable(ctx context.Context, eventID int, id int) (*entity.Table, error)
+	UpdateTable(ctx context.Context, eventID int, id int, update *entity.UpdateTableRequestBody) (*entity.Table, error)
+	DeleteTable(ctx context.Context, eventID int, id int, options entity.DeleteTableOptions) error
+	AddGuest(ctx context.Context, eventID int, guest *entity.Guest) (*entity.AddGuestResponseBody, error)
+	UpdateGuest(ctx context.Context, eventID int, name string, update *entity.UpdateGuestRequestBody) (*entity.UpdateGuestResponseBody, error)
+	RemoveGuest(ctx context.Context, eventID int, name string) error
+	GetAllGuests(ctx context.Context, eventID int, filter entity.GuestFilter) (*entity.GetAllGuestsResponseBody, error)
+	GetAllCheckedInGuests(ctx context.Context, eventID int, filter entity.GuestFilter) (*entity.GetAllCheckedInGuestsResponseBody, error)
+	CheckInGuest(ctx context.Context, eventID int, guest *entity.Guest) (*entity.CheckInGuestResponseBody, error)
+	CountEmptySeats(ctx context.Context, eventID int) (int, error)
+	CheckoutGuest(ctx context.Context, eventID int, guest *entity.Guest) error
+	GetGuestHistory(ctx context.Context, eventID int) ([]entity.GuestHistoryElement, error)
+	JoinWaitlist(ctx context.Context, eventID int, name string, request *entity.JoinWaitlistRequestBody) (*entity.WaitlistEntry, error)
+	GetWaitlist(ctx context.Context, eventID int) ([]entity.WaitlistEntry, error)
+	LeaveWaitlist(ctx context.Context, eventID int, name string) error
+	ImportGuests(ctx context.Context, eventID int, rows []entity.ImportGuestRow, mode string) (*entity.ImportGuestsResponseBody, error)
+	CreateSeatingConstraint(ctx context.Context, eventID int, request *entity.CreateSeatingConstraintRequestBody) (*entity.SeatingConstraintElement, error)
+	GetSeatingConstraints(ctx context.Context, eventID int) ([]entity.SeatingConstraintElement, error)
+	DeleteSeatingConstraint(ctx context.Context, eventID int, id int) error
+	OptimizeSeating(ctx context.Context, eventID int) (*entity.SeatingPlan, error)
+	ApplySeatingPlan(ctx context.Context, eventID int) (*entity.SeatingPlan, error)
+	ListGuests(ctx context.Context, eventID int) ([]entity.Guest, error)
+	GetCheckInLog(ctx context.Context, eventID int) ([]entity.Guest, error)
+	GetSeatingChart(ctx context.Context, eventID int) ([]entity.SeatingChartTable, error)
+	GetAuditLog(ctx context.Context, eventID int, filter entity.AuditFilter) ([]entity.AuditEntry, error)
+	Subscribe(eventID int, lastEventID int64) *Subscription
 }
 
 type service struct {
-	dbClient database.Client
+	dbClient       database.Client
+	clock          Clock
+	tableAssigner  TableAssigner
+	promotionHooks []PromotionHook
+	bus            *Bus
+	relay          *Relay
 }
 
-func NewGuestListService(dbClient database.Client) GuestListService {
-	return &service{dbClient}
+// Option configures the service returned by NewGuestListService.
+type Option func(*service)
+
+// WithClock makes the service read the current time from clock.
+func WithClock(clock Clock) Option {
+	return func(s *service) {
+		s.clock = clock
+	}
+}
+
+func NewGuestListService(dbClient database.Client, opts ...Option) GuestListService {
+	s := &service{dbClient: dbClient, clock: systemClock{}, tableAssigner: TableAssignerFunc(bestFit)}
+	for _, opt := range opts {
+		opt(s)
+	}
+	if s.bus == nil {
+		s.bus = NewBus(DefaultStreamHistory)
+	}
+	return s
+}
+
+// now returns the current time at the precision stored in DATETIME columns.
+func (s *service) now() time.Time {
+	return s.clock.Now().UTC().Truncate(time.Second)
+}
+
+func (s *service) CreateEvent(ctx context.Context, event *entity.CreateEventRequestBody) (*entity.Event, error) {
+	if event.StartTime != nil && event.EndTime != nil && event.EndTime.Before(*event.StartTime) {
+		return nil, newError(ErrInvalidEvent, "event cannot end before it starts")
+	}
+
+	newEvent := entity.Event{
+		Name:      event.Name,
+		Venue:     event.Venue,
+		StartTime: utcTime(event.StartTime),
+		EndTime:   utcTime(event.EndTime),
+	}
+
+	err := s.dbClient.Transaction(ctx, func(tx database.Tx) error {
+		columns := []string{"name", "venue", "start_time", "end_time"}
+		values := []interface{}{newEvent.Name, newEvent.Venue, newEvent.StartTime, newEvent.EndTime}
+		id, err := tx.Create(ctx, "event", columns, values...)
+		if err != nil {
+			return err
+		}
+		newEvent.ID = id
+
+		return s.audit(ctx, tx, id, entity.AuditEventCreated, entity.AuditEntityEvent, nil, nil, nil, &newEvent)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &newEvent, nil
 }
 
-func (s *service) CreateTable(table *entity.Table) (*entity.CreateTableResponseBody, error) {
-	columns := []string{"capacity"}
-	id, err := s.dbClient.Create("table", columns, table.Capacity)
+func (s *service) GetAllEvents(ctx context.Context) ([]entity.Event, error) {
+	events := []entity.Event{}
+
+	err := s.dbClient.FindMany(ctx, &events, "event", database.Query{})
+	if err != nil {
+		return nil, err
+	}
+
+	return events, nil
+}
+
+func (s *service) GetEvent(ctx context.Context, eventID int) (*entity.Event, error) {
+	var event entity.Event
+	err := s.dbClient.FindUnique(ctx, &event, "event", database.By("id", eventID))
+	if errors.Is(err, database.ErrNotFound) {
+		return nil, newError(ErrEventNotFound, "found no event with id %d", eventID)
+	} else if err != nil {
+		return nil, err
+	}
+
+	return &event, nil
+}
+
+// utcTime converts t to UTC at the precision stored in DATETIME columns.
+func utcTime(t *time.Time) *time.Time {
+	if t == nil {
+		return nil
+	}
+	utc := t.UTC().Truncate(time.Second)
+	return &utc
+}
+
+func (s *service) CreateTable(ctx context.Context, eventID int, table *entity.Table) (*entity.CreateTableResponseBody, error) {
+	err := validateAttributes(table.Attributes)
+	if err != nil {
+		return nil, err
+	}
+
+	var id int
+	err = s.dbClient.Transaction(ctx, func(tx database.Tx) error {
+		columns := []string{"event_id", "capacity", "attributes"}
+		var err error
+		id, err = tx.Create(ctx, "table", columns, eventID, table.Capacity, table.Attributes)
+		if errors.Is(err, database.ErrForeignKey) {
+			return newError(ErrEventNotFound, "found no event with id %d", eventID)
+		} else if err != nil {
+			return err
+		}
+
+		err = s.auditTable(ctx, tx, eventID, entity.AuditTableCreated, id, nil)
+		if err != nil {
+			return err
+		}
+
+		return s.record(ctx, tx, eventID, entity.StreamTableChanged, nil, &id)
+	})
 	if err != nil {
 		return nil, err
 	}
+	s.relayOutbox()
 
 	newTable := entity.CreateTableResponseBody{
-		ID:       id,
-		Capacity: table.Capacity,
+		ID:         id,
+		Capacity:   table.Capacity,
+		Attributes: table.Attributes,
 	}
 
 	return &newTable, nil
 }
 
-func (s *service) AddGuest(guest *entity.Guest) (*entity.AddGuestResponseBody, error) {
-	// Check if a guest with the same already exists in the DB
-	guestExists, err := s.dbClient.Exists("guest", "name", guest.Name)
+func (s *service) GetAllTables(ctx context.Context, eventID int) ([]entity.Table, error) {
+	tables := []entity.Table{}
+	err := s.dbClient.FindMany(ctx, &tables, "table", eventQuery(eventID))
 	if err != nil {
 		return nil, err
 	}
-	if guestExists {
-		err = fmt.Errorf("guest with name %s already exists", guest.Name)
+
+	return tables, nil
+}
+
+func (s *service) GetTable(ctx context.Context, eventID int, id int) (*entity.Table, error) {
+	var table entity.Table
+	err := s.dbClient.FindUnique(ctx, &table, "table", tableKey(eventID, id))
+	if errors.Is(err, database.ErrNotFound) {
+		return nil, newError(ErrTableNotFound, "found no table with id %d", id)
+	} else if err != nil {
 		return nil, err
 	}
 
-	// Check if table already exists in the DB
+	return &table, nil
+}
+
+func (s *service) UpdateTable(ctx context.Context, eventID int, id int, update *entity.UpdateTableRequestBody) (*entity.Table, error) {
 	var table entity.Table
-	err = s.dbClient.FindUnique(&table, "table", "id", guest.TableID)
+	var promoted []entity.WaitlistEntry
+	err := s.dbClient.Transaction(ctx, func(tx database.Tx) error {
+		err := lockTable(ctx, tx, eventID, id, &table)
+		if err != nil {
+			return err
+		}
+		before := table
+
+		if update.Attributes != nil {
+			err = validateAttributes(*update.Attributes)
+			if err != nil {
+				return err
+			}
+
+			table.Attributes = *update.Attributes
+			columnsToUpdate := []string{"attributes"}
+			values := []interface{}{table.Attributes}
+			err = tx.Update(ctx, "table", database.By("id", id), columnsToUpdate, values...)
+			if err != nil {
+				return err
+			}
+		}
+
+		if update.Capacity != nil {
+			// Refuse to shrink the table below the seats already promised
+			if *update.Capacity < table.ReservedSeats {
+				return newError(ErrCapacityTooSmall,
+					"table %d has %d reserved seats, capacity cannot be %d", id, table.ReservedSeats, *update.Capacity)
+			}
+
+			table.Capacity = *update.Capacity
+			columnsToUpdate := []string{"capacity"}
+			values := []interface{}{table.Capacity}
+			err = tx.Update(ctx, "table", database.By("id", id), columnsToUpdate, values...)
+			if err != nil {
+				return err
+			}
+		}
+
+		err = s.auditTable(ctx, tx, eventID, entity.AuditTableUpdated, id, &before)
+		if err != nil {
+			return err
+		}
+
+		err = s.record(ctx, tx, eventID, entity.StreamTableChanged, nil, &id)
+		if err != nil {
+			return err
+		}
+
+		if update.Capacity == nil {
+			return nil
+		}
+		promoted, err = s.promoteWaitlist(ctx, tx, &table)
+		return err
+	})
 	if err != nil {
 		return nil, err
 	}
+	s.relayOutbox()
+	s.notifyPromotions(promoted)
 
-	// Check if there are enough seats
-	if table.ReservedSeats+(guest.AccompanyingGuests+1) > table.Capacity {
-		err = fmt.Errorf("no available seats on table %d", guest.TableID)
+	return &table, nil
+}
+
+// validateAttributes refuses attributes that can't be stored comma separated.
+func validateAttributes(attributes entity.Attributes) error {
+	for _, attribute := range attributes {
+		if attribute == "" || strings.Contains(attribute, ",") {
+			return newError(ErrInvalidAttribute, "table attribute `%s` must be non-empty and without commas", attribute)
+		}
+	}
+	return nil
+}
+
+func (s *service) DeleteTable(ctx context.Context, eventID int, id int, options entity.DeleteTableOptions) error {
+	err := s.dbClient.Transaction(ctx, func(tx database.Tx) error {
+		var table entity.Table
+		err := lockTable(ctx, tx, eventID, id, &table)
+		if err != nil {
+			return err
+		}
+
+		// Departed guests no longer hold a seat and keep the table they sat at
+		guests := []entity.Guest{}
+		query := eventQuery(eventID, database.Eq("table_id", id), database.Ne("status", entity.GuestStatusDeparted))
+		err = tx.FindMany(ctx, &guests, "guest", query)
+		if err != nil {
+			return err
+		}
+
+		if len(guests) > 0 {
+			switch options.Guests {
+			case entity.DeleteTableCascade:
+				for _, guest := range guests {
+					err = tx.Delete(ctx, "guest", database.By("id", guest.ID))
+					if err != nil {
+						return err
+					}
+				}
+			case entity.DeleteTableReassign:
+				err = s.reassignGuests(ctx, tx, eventID, &table, guests, options.ReassignTo)
+				if err != nil {
+					return err
+				}
+			default:
+				return newError(ErrTableNotEmpty,
+					"table %d has %d guests, choose to cascade or reassign them", id, len(guests))
+			}
+		}
+
+		// Guests removed or moved along with the table are audited too
+		guestAction := entity.AuditGuestUpdated
+		if options.Guests == entity.DeleteTableCascade {
+			guestAction = entity.AuditGuestRemoved
+		}
+		for i := range guests {
+			err = s.auditGuest(ctx, tx, eventID, guestAction, guests[i].Name, &guests[i])
+			if err != nil {
+				return err
+			}
+		}
+
+		err = tx.Delete(ctx, "table", database.By("id", id))
+		if err != nil {
+			return err
+		}
+
+		err = s.auditTable(ctx, tx, eventID, entity.AuditTableDeleted, id, &table)
+		if err != nil {
+			return err
+		}
+
+		return s.record(ctx, tx, eventID, entity.StreamTableChanged, nil, &id)
+	})
+	if err != nil {
+		return err
+	}
+	s.relayOutbox()
+
+	return nil
+}
+
+// reassignGuests moves the guests of table to the table with id targetID,
+// carrying their reserved seats along.
+func (s *service) reassignGuests(ctx context.Context, tx database.Tx, eventID int, table *entity.Table, guests []entity.Guest, targetID int) error {
+	if targetID == table.ID {
+		return newError(ErrInvalidReassign, "cannot reassign guests of table %d to itself", table.ID)
+	}
+
+	var target entity.Table
+	err := lockTable(ctx, tx, eventID, targetID, &target)
+	if err != nil {
+		return err
+	}
+
+	if target.ReservedSeats+table.ReservedSeats > target.Capacity {
+		return newError(ErrNoSeats, "no available seats on table %d for %d guests", targetID, table.ReservedSeats)
+	}
+
+	columnsToUpdate := []string{"table_id"}
+	values := []interface{}{targetID}
+	for _, guest := range guests {
+		err = tx.Update(ctx, "guest", database.By("id", guest.ID), columnsToUpdate, values...)
+		if err != nil {
+			return err
+		}
+	}
+
+	err = updateReservedSeats(ctx, tx, targetID, target.ReservedSeats+table.ReservedSeats)
+	if err != nil {
+		return err
+	}
+	return s.auditReservedSeats(ctx, tx, target)
+}
+
+// assignTable locks the tables of the event and loads the one the
+// TableAssigner picks for a party of seats into table.
+func (s *service) assignTable(ctx context.Context, tx database.Tx, eventID int, seats int, table *entity.Table) error {
+	tables, err := lockEventTables(ctx, tx, eventID)
+	if err != nil {
+		return err
+	}
+
+	id, ok := s.tableAssigner.AssignTable(tables, seats)
+	if !ok {
+		return newError(ErrNoSeats, "no table has %d available seats", seats)
+	}
+	for _, t := range tables {
+		if t.ID == id {
+			*table = t
+			return nil
+		}
+	}
+	return newError(ErrTableNotFound, "found no table with id %d", id)
+}
+
+// lockEventTables loads and locks every table of the event in id order, so
+// concurrent transactions locking several tables can't deadlock.
+func lockEventTables(ctx context.Context, tx database.Tx, eventID int) ([]entity.Table, error) {
+	tables := []entity.Table{}
+	query := eventQuery(eventID).OrderBy(database.Asc("id"))
+	err := tx.FindMany(ctx, &tables, "table", query)
+	if err != nil {
 		return nil, err
 	}
 
-	// Add a new guest
-	columns := []string{"name", "accompanying_guests", "table_id"}
-	values := []interface{}{guest.Name, guest.AccompanyingGuests, guest.TableID}
-	_, err = s.dbClient.Create("guest", columns, values...)
+	for i := range tables {
+		err = lockTable(ctx, tx, eventID, tables[i].ID, &tables[i])
+		if err != nil {
+			return nil, err
+		}
+	}
+	return tables, nil
+}
+
+// lockTable loads the table with the given id into table and locks its row
+// for the rest of the transaction.
+func lockTable(ctx context.Context, tx database.Tx, eventID int, id int, table *entity.Table) error {
+	err := tx.FindUniqueForUpdate(ctx, table, "table", tableKey(eventID, id))
+	if errors.Is(err, database.ErrNotFound) {
+		return newError(ErrTableNotFound, "found no table with id %d", id)
+	}
+	return err
+}
+
+// AddGuest puts guest on the guest list of the event. Guests without a
+// TableID are seated by the service's TableAssigner.
+func (s *service) AddGuest(ctx context.Context, eventID int, guest *entity.Guest) (*entity.AddGuestResponseBody, error) {
+	var tableID int
+	err := s.dbClient.Transaction(ctx, func(tx database.Tx) error {
+		var err error
+		tableID, err = s.addGuest(ctx, tx, eventID, guest)
+		return err
+	})
 	if err != nil {
 		return nil, err
 	}
+	s.relayOutbox()
+
+	newGuest := entity.AddGuestResponseBody{
+		Name:  guest.Name,
+		Table: tableID,
+	}
+
+	return &newGuest, nil
+}
+
+// addGuest adds guest within tx and returns the id of their table.
+func (s *service) addGuest(ctx context.Context, tx database.Tx, eventID int, guest *entity.Guest) (int, error) {
+	// Lock the table row so concurrent reservations are serialized
+	tableID := guest.TableID
+	var table entity.Table
+	if tableID == 0 {
+		err := s.assignTable(ctx, tx, eventID, guest.AccompanyingGuests+1, &table)
+		if err != nil {
+			return 0, err
+		}
+		tableID = table.ID
+	} else {
+		err := lockTable(ctx, tx, eventID, tableID, &table)
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	// Check if a guest with the same already exists in the DB
+	guestExists, err := tx.Exists(ctx, "guest", guestKey(eventID, guest.Name))
+	if err != nil {
+		return 0, err
+	}
+	if guestExists {
+		return 0, newError(ErrGuestExists, "guest with name %s already exists", guest.Name)
+	}
+
+	// Check if there are enough seats
+	if table.ReservedSeats+(guest.AccompanyingGuests+1) > table.Capacity {
+		return 0, newError(ErrNoSeats, "no available seats on table %d", tableID)
+	}
+
+	// Add a new guest
+	columns := []string{"event_id", "name", "accompanying_guests", "table_id"}
+	values := []interface{}{eventID, guest.Name, guest.AccompanyingGuests, tableID}
+	_, err = tx.Create(ctx, "guest", columns, values...)
+	if errors.Is(err, database.ErrDuplicate) {
+		return 0, newError(ErrGuestExists, "guest with name %s already exists", guest.Name)
+	} else if err != nil {
+		return 0, err
+	}
 
 	// Update the number of reserved seats
 	updatedReservedSeats := table.ReservedSeats + (guest.AccompanyingGuests + 1)
 	columnsToUpdate := []string{"reserved_seats"}
 	values = []interface{}{updatedReservedSeats}
-	err = s.dbClient.Update("table", "id", guest.TableID, columnsToUpdate, values...)
+	err = tx.Update(ctx, "table", database.By("id", tableID), columnsToUpdate, values...)
 	if err != nil {
-		return nil, err
+		return 0, err
+	}
+	err = s.auditReservedSeats(ctx, tx, table)
+	if err != nil {
+		return 0, err
 	}
 
-	newGuest := entity.AddGuestResponseBody{
-		Name: guest.Name,
+	err = s.auditGuest(ctx, tx, eventID, entity.AuditGuestAdded, guest.Name, nil)
+	if err != nil {
+		return 0, err
 	}
 
-	return &newGuest, nil
+	return tableID, s.record(ctx, tx, eventID, entity.StreamGuestAdded, &guest.Name, &tableID)
 }
 
-func (s *service) GetAllGuests() ([]entity.GetAllGuestsElement, error) {
-	guests := []entity.GetAllGuestsElement{}
+func (s *service) UpdateGuest(ctx context.Context, eventID int, name string, update *entity.UpdateGuestRequestBody) (*entity.UpdateGuestResponseBody, error) {
+	var result entity.UpdateGuestResponseBody
+	var promoted []entity.WaitlistEntry
+	err := s.dbClient.Transaction(ctx, func(tx database.Tx) error {
+		var guest entity.Guest
+		err := lockExpectedGuest(ctx, tx, eventID, name, &guest)
+		if err != nil {
+			return err
+		}
+		before := guest
+
+		tableID := guest.TableID
+		if update.Table != nil {
+			tableID = *update.Table
+		}
+		accompanyingGuests := guest.AccompanyingGuests
+		if update.AccompanyingGuests != nil {
+			accompanyingGuests = *update.AccompanyingGuests
+		}
+
+		oldSeats := guest.AccompanyingGuests + 1
+		newSeats := accompanyingGuests + 1
+
+		// The table the party leaves or shrinks on, which may have room for
+		// waitlisted parties afterwards
+		var freedTable *entity.Table
+		if tableID == guest.TableID {
+			// Resize the party on its current table
+			var table entity.Table
+			err = lockTable(ctx, tx, eventID, tableID, &table)
+			if err != nil {
+				return err
+			}
+
+			reservedSeats := table.ReservedSeats - oldSeats + newSeats
+			if reservedSeats > table.Capacity {
+				return newError(ErrNoSeats, "no available seats on table %d", tableID)
+			}
+
+			err = updateReservedSeats(ctx, tx, tableID, reservedSeats)
+			if err != nil {
+				return err
+			}
+			err = s.auditReservedSeats(ctx, tx, table)
+			if err != nil {
+				return err
+			}
+			if newSeats < oldSeats {
+				table.ReservedSeats = reservedSeats
+				freedTable = &table
+			}
+		} else {
+			// Lock both tables in id order so concurrent moves can't deadlock
+			var oldTable, newTable entity.Table
+			if guest.TableID < tableID {
+				err = lockTable(ctx, tx, eventID, guest.TableID, &oldTable)
+				if err == nil {
+					err = lockTable(ctx, tx, eventID, tableID, &newTable)
+				}
+			} else {
+				err = lockTable(ctx, tx, eventID, tableID, &newTable)
+				if err == nil {
+					err = lockTable(ctx, tx, eventID, guest.TableID, &oldTable)
+				}
+			}
+			if err != nil {
+				return err
+			}
+
+			if newTable.ReservedSeats+newSeats > newTable.Capacity {
+				return newError(ErrNoSeats, "no available seats on table %d", tableID)
+			}
 
-	err := s.dbClient.FindMany(&guests, "guest", nil, nil)
+			err = updateReservedSeats(ctx, tx, oldTable.ID, oldTable.ReservedSeats-oldSeats)
+			if err != nil {
+				return err
+			}
+			err = updateReservedSeats(ctx, tx, newTable.ID, newTable.ReservedSeats+newSeats)
+			if err != nil {
+				return err
+			}
+			err = s.auditReservedSeats(ctx, tx, oldTable)
+			if err != nil {
+				return err
+			}
+			err = s.auditReservedSeats(ctx, tx, newTable)
+			if err != nil {
+				return err
+			}
+			oldTable.ReservedSeats -= oldSeats
+			freedTable = &oldTable
+		}
+
+		columnsToUpdate := []string{"table_id", "accompanying_guests"}
+		values := []interface{}{tableID, accompanyingGuests}
+		err = tx.Update(ctx, "guest", database.By("id", guest.ID), columnsToUpdate, values...)
+		if err != nil {
+			return err
+		}
+
+		err = s.auditGuest(ctx, tx, eventID, entity.AuditGuestUpdated, guest.Name, &before)
+		if err != nil {
+			return err
+		}
+
+		err = s.record(ctx, tx, eventID, entity.StreamTableChanged, &guest.Name, &tableID)
+		if err != nil {
+			return err
+		}
+
+		if freedTable != nil {
+			promoted, err = s.promoteWaitlist(ctx, tx, freedTable)
+			if err != nil {
+				return err
+			}
+		}
+
+		result = entity.UpdateGuestResponseBody{
+			Name:               guest.Name,
+			Table:              tableID,
+			AccompanyingGuests: accompanyingGuests,
+		}
+		return nil
+	})
 	if err != nil {
 		return nil, err
 	}
+	s.relayOutbox()
+	s.notifyPromotions(promoted)
 
-	return guests, nil
+	return &result, nil
 }
 
-func (s *service) CheckInGuest(guest *entity.Guest) (*entity.CheckInGuestResponseBody, error) {
-	// Retrieve the guest info from the DB
-	var retrievedGuest entity.Guest
-	err := s.dbClient.FindUnique(&retrievedGuest, "guest", "name", guest.Name)
-	if err == sql.ErrNoRows {
-		err = fmt.Errorf("found no guest called `%s`", guest.Name)
-		return nil, err
-	} else if err != nil {
-		return nil, err
+func (s *service) RemoveGuest(ctx context.Context, eventID int, name string) error {
+	var promoted []entity.WaitlistEntry
+	err := s.dbClient.Transaction(ctx, func(tx database.Tx) error {
+		var guest entity.Guest
+		err := lockExpectedGuest(ctx, tx, eventID, name, &guest)
+		if err != nil {
+			return err
+		}
+
+		var table entity.Table
+		err = lockTable(ctx, tx, eventID, guest.TableID, &table)
+		if err != nil {
+			return err
+		}
+
+		err = tx.Delete(ctx, "guest", database.By("id", guest.ID))
+		if err != nil {
+			return err
+		}
+
+		err = s.auditGuest(ctx, tx, eventID, entity.AuditGuestRemoved, name, &guest)
+		if err != nil {
+			return err
+		}
+
+		// Free the seats the party had reserved
+		tableBefore := table
+		table.ReservedSeats -= guest.AccompanyingGuests + 1
+		err = updateReservedSeats(ctx, tx, table.ID, table.ReservedSeats)
+		if err != nil {
+			return err
+		}
+		err = s.auditReservedSeats(ctx, tx, tableBefore)
+		if err != nil {
+			return err
+		}
+
+		err = s.record(ctx, tx, eventID, entity.StreamTableChanged, &name, &table.ID)
+		if err != nil {
+			return err
+		}
+
+		promoted, err = s.promoteWaitlist(ctx, tx, &table)
+		return err
+	})
+	if err != nil {
+		return err
 	}
+	s.relayOutbox()
+	s.notifyPromotions(promoted)
 
-	// Check if the guest is already checked in
-	if retrievedGuest.TimeArrived != nil {
-		err := fmt.Errorf("guest with name `%s` is already checked in", guest.Name)
+	return nil
+}
+
+// lockExpectedGuest loads and locks the guest called name, refusing guests
+// that have already arrived since they must be checked out instead and
+// departed guests whose visit is on record.
+func lockExpectedGuest(ctx context.Context, tx database.Tx, eventID int, name string, guest *entity.Guest) error {
+	err := lockGuest(ctx, tx, eventID, name, guest)
+	if err != nil {
+		return err
+	}
+
+	if guest.Status != entity.GuestStatusExpected {
+		return newError(ErrAlreadyCheckedIn, "guest with name `%s` has already arrived", name)
+	}
+
+	return nil
+}
+
+func updateReservedSeats(ctx context.Context, tx database.Tx, tableID int, reservedSeats int) error {
+	columnsToUpdate := []string{"reserved_seats"}
+	values := []interface{}{reservedSeats}
+	return tx.Update(ctx, "table", database.By("id", tableID), columnsToUpdate, values...)
+}
+
+// lockGuest loads the guest called name into guest and locks their row for
+// the rest of the transaction.
+func lockGuest(ctx context.Context, tx database.Tx, eventID int, name string, guest *entity.Guest) error {
+	err := tx.FindUniqueForUpdate(ctx, guest, "guest", guestKey(eventID, name))
+	if errors.Is(err, database.ErrNotFound) {
+		return newError(ErrGuestNotFound, "found no guest called `%s`", name)
+	}
+	return err
+}
+
+func tableKey(eventID int, id int) database.Key {
+	return database.Key{"event_id": eventID, "id": id}
+}
+
+func guestKey(eventID int, name string) database.Key {
+	return database.Key{"event_id": eventID, "name": name}
+}
+
+// eventQuery restricts a FindMany query to the rows of an event matching
+// every condition.
+func eventQuery(eventID int, conditions ...database.Condition) database.Query {
+	return database.Where(database.Eq("event_id", eventID)).Where(conditions...)
+}
+
+func (s *service) GetAllGuests(ctx context.Context, eventID int, filter entity.GuestFilter) (*entity.GetAllGuestsResponseBody, error) {
+	guests, next, err := s.listGuests(ctx, eventID, filter)
+	if err != nil {
 		return nil, err
 	}
 
-	// Check in the guest if they have extras
-	if guest.AccompanyingGuests > retrievedGuest.AccompanyingGuests {
-		var table entity.Table
-		err := s.dbClient.FindUnique(&table, "table", "id", retrievedGuest.TableID)
+	result := entity.GetAllGuestsResponseBody{
+		Guests:     make([]entity.GetAllGuestsElement, len(guests)),
+		NextCursor: next,
+	}
+	for i, guest := range guests {
+		result.Guests[i] = entity.GetAllGuestsElement{
+			Name:               guest.Name,
+			AccompanyingGuests: guest.AccompanyingGuests,
+			TableID:            guest.TableID,
+		}
+	}
+
+	return &result, nil
+}
+
+func (s *service) CheckInGuest(ctx context.Context, eventID int, guest *entity.Guest) (*entity.CheckInGuestResponseBody, error) {
+	err := s.dbClient.Transaction(ctx, func(tx database.Tx) error {
+		// Retrieve the guest info from the DB
+		var retrievedGuest entity.Guest
+		err := lockGuest(ctx, tx, eventID, guest.Name, &retrievedGuest)
 		if err != nil {
-			return nil, err
+			return err
 		}
+		before := retrievedGuest
+		accompanyingGuests := retrievedGuest.AccompanyingGuests
 
-		extras := guest.AccompanyingGuests - retrievedGuest.AccompanyingGuests
+		switch retrievedGuest.Status {
+		case entity.GuestStatusArrived:
+			// Check if the guest is already checked in
+			return newError(ErrAlreadyCheckedIn, "guest with name `%s` is already checked in", guest.Name)
 
-		if (extras + table.ReservedSeats) > table.Capacity {
-			err = fmt.Errorf("no available seats on table %d", retrievedGuest.TableID)
-			return nil, err
+		case entity.GuestStatusDeparted:
+			// Re-admit a guest who stepped out, their seats were freed on checkout
+			var table entity.Table
+			err := lockTable(ctx, tx, eventID, retrievedGuest.TableID, &table)
+			if err != nil {
+				return err
+			}
+
+			seats := guest.AccompanyingGuests + 1
+			if (seats + table.ReservedSeats) > table.Capacity {
+				return newError(ErrNoSeats, "no available seats on table %d", retrievedGuest.TableID)
+			}
+
+			columnsToUpdate := []string{"accompanying_guests"}
+			values := []interface{}{guest.AccompanyingGuests}
+			err = tx.Update(ctx, "guest", database.By("id", retrievedGuest.ID), columnsToUpdate, values...)
+			if err != nil {
+				return err
+			}
+			accompanyingGuests = guest.AccompanyingGuests
+
+			err = updateReservedSeats(ctx, tx, table.ID, table.ReservedSeats+seats)
+			if err != nil {
+				return err
+			}
+			err = s.auditReservedSeats(ctx, tx, table)
+			if err != nil {
+				return err
+			}
+
+		default:
+			// Check in the guest if they have extras
+			if guest.AccompanyingGuests > retrievedGuest.AccompanyingGuests {
+				var table entity.Table
+				err := lockTable(ctx, tx, eventID, retrievedGuest.TableID, &table)
+				if err != nil {
+					return err
+				}
+
+				extras := guest.AccompanyingGuests - retrievedGuest.AccompanyingGuests
+
+				if (extras + table.ReservedSeats) > table.Capacity {
+					return newError(ErrNoSeats, "no available seats on table %d", retrievedGuest.TableID)
+				}
+
+				columnsToUpdate := []string{"accompanying_guests"}
+				values := []interface{}{guest.AccompanyingGuests}
+				err = tx.Update(ctx, "guest", database.By("id", retrievedGuest.ID), columnsToUpdate, values...)
+				if err != nil {
+					return err
+				}
+				accompanyingGuests = guest.AccompanyingGuests
+
+				err = updateReservedSeats(ctx, tx, table.ID, table.ReservedSeats+extras)
+				if err != nil {
+					return err
+				}
+				err = s.auditReservedSeats(ctx, tx, table)
+				if err != nil {
+					return err
+				}
+			}
 		}
 
-		columnsToUpdate := []string{"accompanying_guests"}
-		values := []interface{}{guest.AccompanyingGuests}
-		err = s.dbClient.Update("guest", "id", retrievedGuest.ID, columnsToUpdate, values...)
+		// Check in the guest
+		timeArrived := s.now()
+		columnsToUpdate := []string{"status", "time_arrived", "time_left"}
+		values := []interface{}{entity.GuestStatusArrived, timeArrived, nil}
+		err = tx.Update(ctx, "guest", database.By("id", retrievedGuest.ID), columnsToUpdate, values...)
 		if err != nil {
-			return nil, err
+			return err
 		}
 
-		reservedSeats := table.ReservedSeats + extras
-		columnsToUpdate = []string{"reserved_seats"}
-		values = []interface{}{reservedSeats}
-		err = s.dbClient.Update("table", "id", table.ID, columnsToUpdate, values...)
+		err = startVisit(ctx, tx, retrievedGuest, accompanyingGuests, timeArrived)
 		if err != nil {
-			return nil, err
+			return err
+		}
+
+		err = s.auditGuest(ctx, tx, eventID, entity.AuditGuestCheckedIn, retrievedGuest.Name, &before)
+		if err != nil {
+			return err
 		}
-	}
 
-	// Check in hte guest
-	timeArrived := time.Now().UTC().String()
-	columnsToUpdate := []string{"time_arrived"}
-	values := []interface{}{timeArrived}
-	err = s.dbClient.Update("guest", "id", retrievedGuest.ID, columnsToUpdate, values...)
+		return s.record(ctx, tx, eventID, entity.StreamGuestCheckedIn, &retrievedGuest.Name, &retrievedGuest.TableID)
+	})
 	if err != nil {
 		return nil, err
 	}
+	s.relayOutbox()
 
 	result := entity.CheckInGuestResponseBody{
 		Name: guest.Name,
@@ -165,73 +882,153 @@ func (s *service) CheckInGuest(guest *entity.Guest) (*entity.CheckInGuestRespons
 	return &result, nil
 }
 
-func (s *service) GetAllCheckedInGuests() ([]entity.GetAllCheckedInGuestsElement, error) {
-	guests := []entity.GetAllCheckedInGuestsElement{}
-	condition := "time_arrived IS NOT NULL"
-
-	err := s.dbClient.FindMany(&guests, "guest", &condition, nil)
+func (s *service) GetAllCheckedInGuests(ctx context.Context, eventID int, filter entity.GuestFilter) (*entity.GetAllCheckedInGuestsResponseBody, error) {
+	guests, next, err := s.listGuests(ctx, eventID, filter, database.Eq("status", entity.GuestStatusArrived))
 	if err != nil {
 		return nil, err
 	}
 
-	return guests, nil
+	result := entity.GetAllCheckedInGuestsResponseBody{
+		Guests:     make([]entity.GetAllCheckedInGuestsElement, len(guests)),
+		NextCursor: next,
+	}
+	for i, guest := range guests {
+		result.Guests[i] = entity.GetAllCheckedInGuestsElement{
+			Name:               guest.Name,
+			AccompanyingGuests: guest.AccompanyingGuests,
+			TimeArrived:        *guest.TimeArrived,
+		}
+	}
+
+	return &result, nil
+}
+
+func (s *service) CountEmptySeats(ctx context.Context, eventID int) (int, error) {
+	return countEmptySeats(ctx, s.dbClient, eventID)
 }
 
-func (s *service) CountEmptySeats() (int, error) {
-	var reservedSeatsCount int
-	err := s.dbClient.GetDB().Get(&reservedSeatsCount, "SELECT SUM(reserved_seats) FROM `table`")
+func countEmptySeats(ctx context.Context, q database.Queryer, eventID int) (int, error) {
+	tables := []entity.Table{}
+	err := q.FindMany(ctx, &tables, "table", eventQuery(eventID))
 	if err != nil {
 		return 0, err
 	}
 
-	var capacity int
-	err = s.dbClient.GetDB().Get(&capacity, "SELECT SUM(capacity) FROM `table`")
-	if err != nil {
-		return 0, err
+	emptySeats := 0
+	for _, table := range tables {
+		emptySeats += table.Capacity - table.ReservedSeats
 	}
 
-	emptySeats := capacity - reservedSeatsCount
 	return emptySeats, nil
 }
 
-func (s *service) CheckoutGuest(guest *entity.Guest) error {
-	// Retrieve the guest info from the DB
-	var retrievedGuest entity.Guest
-	err := s.dbClient.FindUnique(&retrievedGuest, "guest", "name", guest.Name)
-	if err == sql.ErrNoRows {
-		err = fmt.Errorf("found no guest called `%s`", guest.Name)
+func (s *service) CheckoutGuest(ctx context.Context, eventID int, guest *entity.Guest) error {
+	var promoted []entity.WaitlistEntry
+	err := s.dbClient.Transaction(ctx, func(tx database.Tx) error {
+		// Retrieve the guest info from the DB
+		var retrievedGuest entity.Guest
+		err := lockGuest(ctx, tx, eventID, guest.Name, &retrievedGuest)
+		if err != nil {
+			return err
+		}
+		before := retrievedGuest
+
+		// Check if guest is checked in
+		if retrievedGuest.Status != entity.GuestStatusArrived {
+			return newError(ErrNotCheckedIn, "guest `%s` is not checked in", retrievedGuest.Name)
+		}
+
+		// Get reserved table info
+		var table entity.Table
+		err = lockTable(ctx, tx, eventID, retrievedGuest.TableID, &table)
+		if err != nil {
+			return err
+		}
+
+		// Check out the guest, keeping their visit on record
+		timeLeft := s.now()
+		columnsToUpdate := []string{"status", "time_left"}
+		values := []interface{}{entity.GuestStatusDeparted, timeLeft}
+		err = tx.Update(ctx, "guest", database.By("id", retrievedGuest.ID), columnsToUpdate, values...)
+		if err != nil {
+			return err
+		}
+
+		err = endVisit(ctx, tx, retrievedGuest, timeLeft)
+		if err != nil {
+			return err
+		}
+
+		// Update the number of reserved seats
+		tableBefore := table
+		table.ReservedSeats -= retrievedGuest.AccompanyingGuests + 1
+		err = updateReservedSeats(ctx, tx, table.ID, table.ReservedSeats)
+		if err != nil {
+			return err
+		}
+		err = s.auditReservedSeats(ctx, tx, tableBefore)
+		if err != nil {
+			return err
+		}
+
+		err = s.auditGuest(ctx, tx, eventID, entity.AuditGuestCheckedOut, retrievedGuest.Name, &before)
+		if err != nil {
+			return err
+		}
+
+		err = s.record(ctx, tx, eventID, entity.StreamGuestCheckedOut, &retrievedGuest.Name, &table.ID)
+		if err != nil {
+			return err
+		}
+
+		promoted, err = s.promoteWaitlist(ctx, tx, &table)
 		return err
-	} else if err != nil {
+	})
+	if err != nil {
 		return err
 	}
+	s.relayOutbox()
+	s.notifyPromotions(promoted)
 
-	// Check if guest is checked in
-	if retrievedGuest.TimeArrived == nil {
-		err = fmt.Errorf("guest `%s` is not checked in", retrievedGuest.Name)
-		return err
-	}
+	return nil
+}
 
-	// Check out the guest
-	err = s.dbClient.Delete("guest", "name", retrievedGuest.Name)
+// startVisit records the visit of guest starting at timeArrived.
+func startVisit(ctx context.Context, tx database.Tx, guest entity.Guest, accompanyingGuests int, timeArrived time.Time) error {
+	columns := []string{"event_id", "name", "table_id", "accompanying_guests", "time_arrived"}
+	values := []interface{}{guest.EventID, guest.Name, guest.TableID, accompanyingGuests, timeArrived}
+	_, err := tx.Create(ctx, "visit", columns, values...)
+	return err
+}
+
+// endVisit ends the visit guest is on at timeLeft.
+func endVisit(ctx context.Context, tx database.Tx, guest entity.Guest, timeLeft time.Time) error {
+	visits := []entity.Visit{}
+	query := eventQuery(guest.EventID, database.Eq("name", guest.Name), database.IsNull("time_left"))
+	err := tx.FindMany(ctx, &visits, "visit", query.OrderBy(database.Asc("id")))
 	if err != nil {
 		return err
 	}
 
-	// Get reserved table info
-	var table entity.Table
-	err = s.dbClient.FindUnique(&table, "table", "id", retrievedGuest.TableID)
-	if err != nil {
-		return err
+	for _, visit := range visits {
+		err = tx.Update(ctx, "visit", database.By("id", visit.ID), []string{"time_left"}, timeLeft)
+		if err != nil {
+			return err
+		}
 	}
+	return nil
+}
 
-	// Update the number of reserved seats
-	updatedReservedSeats := table.ReservedSeats - (retrievedGuest.AccompanyingGuests + 1)
-	columnsToUpdate := []string{"reserved_seats"}
-	values := []interface{}{updatedReservedSeats}
-	err = s.dbClient.Update("table", "id", table.ID, columnsToUpdate, values...)
+// GetGuestHistory returns every visit that ended, in the order the guests
+// arrived.
+func (s *service) GetGuestHistory(ctx context.Context, eventID int) ([]entity.GuestHistoryElement, error) {
+	visits := []entity.GuestHistoryElement{}
+	query := eventQuery(eventID, database.IsNotNull("time_left")).OrderBy(database.Asc("id"))
+
+	err := s.dbClient.FindMany(ctx, &visits, "visit", query)
 	if err != nil {
-		return nil
+		return nil, err
 	}
 
-	return nil
+	return visits, nil
 }
@@ -1,46 +1,88 @@
 package guest_list
 
 import (
+	"context"
 	"fmt"
 	"log"
+	"os"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/getground/tech-tasks/backend/internal/entity"
 	"github.com/getground/tech-tasks/backend/pkg/database"
 	"github.com/stretchr/testify/assert"
 )
 
-const (
-	dsn = "username:password@/getground"
-)
-
 var (
+	ctx              = context.Background()
 	guestListService GuestListService
 	dbClient         database.Client
+	clock            *fixedClock
 )
 
+// newTestClient returns an in-memory client unless TEST_MYSQL_DSN points the
+// tests at a live MySQL server.
+func newTestClient() (database.Client, error) {
+	if dsn := os.Getenv("TEST_MYSQL_DSN"); dsn != "" {
+		return database.NewClient(dsn)
+	}
+	return database.NewMemoryClient(), nil
+}
+
+// fixedClock always reports the same time.
+type fixedClock struct {
+	now time.Time
+}
+
+func (c *fixedClock) Now() time.Time {
+	return c.now
+}
+
 func setupServiceTest() {
 	var err error
-	dbClient, err = database.NewClient(dsn)
+	dbClient, err = newTestClient()
 	if err != nil {
 		log.Fatalf("Error while connecting to the DB, %v", err)
 	}
 
 	// Cleanup tables
-	cleanupTable(dbClient, "table")
+	cleanupTable(dbClient, "audit")
+	cleanupTable(dbClient, "outbox")
+	cleanupTable(dbClient, "seating_constraint")
+	cleanupTable(dbClient, "waitlist")
 	cleanupTable(dbClient, "guest")
+	cleanupTable(dbClient, "table")
+	cleanupEvents(dbClient)
 
 	// Create a new guest list service
-	guestListService = NewGuestListService(dbClient)
+	clock = &fixedClock{time.Date(2023, 6, 1, 18, 30, 0, 0, time.UTC)}
+	guestListService = NewGuestListService(dbClient, WithClock(clock))
 }
 
 func cleanupTable(dbClient database.Client, tableName string) {
-	err := dbClient.DeleteAll(tableName)
+	err := dbClient.DeleteAll(ctx, tableName)
 	if err != nil {
 		log.Fatalf("Error while cleaning table %s, %v", tableName, err)
 	}
 }
 
+// cleanupEvents removes every event apart from the default one.
+func cleanupEvents(dbClient database.Client) {
+	events := []entity.Event{}
+	query := database.Where(database.Ne("id", entity.DefaultEventID))
+	err := dbClient.FindMany(ctx, &events, "event", query)
+	if err != nil {
+		log.Fatalf("Error while cleaning table event, %v", err)
+	}
+	for _, event := range events {
+		err = dbClient.Delete(ctx, "event", database.By("id", event.ID))
+		if err != nil {
+			log.Fatalf("Error while cleaning table event, %v", err)
+		}
+	}
+}
+
 func TestCreateTable(t *testing.T) {
 	// Setup database
 	setupServiceTest()
@@ -49,7 +91,7 @@ func TestCreateTable(t *testing.T) {
 	// Test creating a new table
 	var table entity.Table
 	table.Capacity = 10
-	newTable, err := guestListService.CreateTable(&table)
+	newTable, err := guestListService.CreateTable(ctx, entity.DefaultEventID, &table)
 	assert.Nil(t, err, "Error while creating a new table, %v", err)
 	assert.NotNil(t, newTable, "Expected table to have value but found nil")
 }
@@ -62,7 +104,7 @@ func TestAddGuest(t *testing.T) {
 	// Create a new table
 	var table entity.Table
 	table.Capacity = 5
-	newTable, err := guestListService.CreateTable(&table)
+	newTable, err := guestListService.CreateTable(ctx, entity.DefaultEventID, &table)
 	assert.Nil(t, err, "Error while creating a new table, %v", err)
 	assert.NotNil(t, newTable, "Expected table to have value but found nil")
 
@@ -71,27 +113,78 @@ func TestAddGuest(t *testing.T) {
 	guest.Name = "john"
 	guest.AccompanyingGuests = 3
 	guest.TableID = newTable.ID
-	newGuest, err := guestListService.AddGuest(&guest)
+	newGuest, err := guestListService.AddGuest(ctx, entity.DefaultEventID, &guest)
 	assert.Nil(t, err, "Error while creating a new guest, %v", err)
 	assert.NotNil(t, newGuest, "Expected guest to have value but found nil")
 
 	// Test adding a new guest with the same name
-	_, err = guestListService.AddGuest(&guest)
+	_, err = guestListService.AddGuest(ctx, entity.DefaultEventID, &guest)
 	expectedErrorMsg := fmt.Sprintf("guest with name %s already exists", guest.Name)
 	assert.EqualErrorf(t, err, expectedErrorMsg, "Error should be %v but found %v", err, expectedErrorMsg)
+	assert.ErrorIs(t, err, ErrGuestExists)
 
 	// Test adding a new guest in a table with no available seats
 	guest.Name = "rob"
 	guest.AccompanyingGuests = 1
-	_, err = guestListService.AddGuest(&guest)
+	_, err = guestListService.AddGuest(ctx, entity.DefaultEventID, &guest)
 	expectedErrorMsg = fmt.Sprintf("no available seats on table %d", guest.TableID)
 	assert.EqualErrorf(t, err, expectedErrorMsg, "Error should be %v but found %v", err, expectedErrorMsg)
+	assert.ErrorIs(t, err, ErrNoSeats)
 
 	// Test adding a new guest with a table id that does not exist
 	guest.TableID = newTable.ID + 1
-	_, err = guestListService.AddGuest(&guest)
-	expectedErrorMsg = "sql: no rows in result set"
+	_, err = guestListService.AddGuest(ctx, entity.DefaultEventID, &guest)
+	expectedErrorMsg = fmt.Sprintf("found no table with id %d", guest.TableID)
 	assert.EqualErrorf(t, err, expectedErrorMsg, "Error should be %v but found %v", err, expectedErrorMsg)
+	assert.ErrorIs(t, err, ErrTableNotFound)
+}
+
+func TestAddGuestConcurrently(t *testing.T) {
+	// Setup database
+	setupServiceTest()
+	defer dbClient.Close()
+
+	// Create a new table
+	var table entity.Table
+	table.Capacity = 5
+	newTable, err := guestListService.CreateTable(ctx, entity.DefaultEventID, &table)
+	assert.Nil(t, err, "Error while creating a new table, %v", err)
+	assert.NotNil(t, newTable, "Expected table to have value but found nil")
+
+	// Race more guests than there are seats for the same table
+	const attempts = 20
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	added := 0
+	for i := 0; i < attempts; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+
+			var guest entity.Guest
+			guest.Name = fmt.Sprintf("guest-%d", i)
+			guest.AccompanyingGuests = 0
+			guest.TableID = newTable.ID
+			if _, err := guestListService.AddGuest(ctx, entity.DefaultEventID, &guest); err == nil {
+				mu.Lock()
+				added++
+				mu.Unlock()
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	// Test that the table capacity was never exceeded
+	assert.Equalf(t, table.Capacity, added, "Expected %d guests to be added but found %d", table.Capacity, added)
+
+	var retrievedTable entity.Table
+	err = dbClient.FindUnique(ctx, &retrievedTable, "table", database.By("id", newTable.ID))
+	assert.Nil(t, err, "Error while getting table, %v", err)
+	assert.Equalf(t, table.Capacity, retrievedTable.ReservedSeats, "Expected reserved seats to be %d but found %d", table.Capacity, retrievedTable.ReservedSeats)
+
+	guests, err := guestListService.GetAllGuests(ctx, entity.DefaultEventID, entity.GuestFilter{})
+	assert.Nil(t, err, "Error while getting all guests, %v", err)
+	assert.Equalf(t, table.Capacity, len(guests.Guests), "Expected the number of guests to be %d but found %d", table.Capacity, len(guests.Guests))
 }
 
 func TestGetAllGuests(t *testing.T) {
@@ -102,7 +195,7 @@ func TestGetAllGuests(t *testing.T) {
 	// Create a new table
 	var table entity.Table
 	table.Capacity = 5
-	newTable, err := guestListService.CreateTable(&table)
+	newTable, err := guestListService.CreateTable(ctx, entity.DefaultEventID, &table)
 	assert.Nil(t, err, "Error while creating a new table, %v", err)
 	assert.NotNil(t, newTable, "Expected table to have value but found nil")
 
@@ -111,23 +204,24 @@ func TestGetAllGuests(t *testing.T) {
 	guest.Name = "john"
 	guest.AccompanyingGuests = 0
 	guest.TableID = newTable.ID
-	newGuest, err := guestListService.AddGuest(&guest)
+	newGuest, err := guestListService.AddGuest(ctx, entity.DefaultEventID, &guest)
 	assert.Nil(t, err, "Error while creating a new guest, %v", err)
 	assert.NotNil(t, newGuest, "Expected guest to have value but found nil")
 
 	guest.Name = "abdullah"
 	guest.AccompanyingGuests = 0
 	guest.TableID = newTable.ID
-	newGuest, err = guestListService.AddGuest(&guest)
+	newGuest, err = guestListService.AddGuest(ctx, entity.DefaultEventID, &guest)
 	assert.Nil(t, err, "Error while creating a new guest, %v", err)
 	assert.NotNil(t, newGuest, "Expected guest to have value but found nil")
 
 	// Test getting all guests
-	var guests []entity.GetAllGuestsElement
-	guests, err = guestListService.GetAllGuests()
+	var guests *entity.GetAllGuestsResponseBody
+	guests, err = guestListService.GetAllGuests(ctx, entity.DefaultEventID, entity.GuestFilter{})
 	assert.Nil(t, err, "Error while getting all guests, %v", err)
 	assert.NotNil(t, guests, "Expected guests to have value but found nil")
-	assert.Equalf(t, 2, len(guests), "Expected the number of guests to be 2 but found %d", len(guests))
+	assert.Equalf(t, 2, len(guests.Guests), "Expected the number of guests to be 2 but found %d", len(guests.Guests))
+	assert.Nil(t, guests.NextCursor)
 }
 
 func TestCheckInGuest(t *testing.T) {
@@ -138,7 +232,7 @@ func TestCheckInGuest(t *testing.T) {
 	// Create a new table
 	var table entity.Table
 	table.Capacity = 5
-	newTable, err := guestListService.CreateTable(&table)
+	newTable, err := guestListService.CreateTable(ctx, entity.DefaultEventID, &table)
 	assert.Nil(t, err, "Error while creating a new table, %v", err)
 	assert.NotNil(t, newTable, "Expected table to have value but found nil")
 
@@ -147,30 +241,31 @@ func TestCheckInGuest(t *testing.T) {
 	guest.Name = "john"
 	guest.AccompanyingGuests = 4
 	guest.TableID = newTable.ID
-	newGuest, err := guestListService.AddGuest(&guest)
+	newGuest, err := guestListService.AddGuest(ctx, entity.DefaultEventID, &guest)
 	assert.Nil(t, err, "Error while creating a new guest, %v", err)
 	assert.NotNil(t, newGuest, "Expected guest to have value but found nil")
 
 	// Check in the guest
-	checkedInGuest, err := guestListService.CheckInGuest(&guest)
+	checkedInGuest, err := guestListService.CheckInGuest(ctx, entity.DefaultEventID, &guest)
 	assert.Nil(t, err, "Error while checking in the guest, %v", err)
 	assert.NotNil(t, checkedInGuest, "Expected `checkedInGuest` to have value but found nil")
 
 	// Get guest info
-	err = dbClient.FindUnique(&guest, "guest", "name", checkedInGuest.Name)
+	err = dbClient.FindUnique(ctx, &guest, "guest", guestKey(entity.DefaultEventID, checkedInGuest.Name))
 	assert.Nil(t, err, "Error while getting guest, %v", err)
 	// Test that the user is checked in
 	assert.NotNil(t, guest.TimeArrived, "Expected `time_arrived` to have value but found nil")
+	assert.Equal(t, clock.now, *guest.TimeArrived)
 
 	// Check if guest is already checked in
-	checkedInGuest, err = guestListService.CheckInGuest(&guest)
+	checkedInGuest, err = guestListService.CheckInGuest(ctx, entity.DefaultEventID, &guest)
 	assert.Nil(t, checkedInGuest, "Expected checkedInGuest to not have value")
 	expectedErrorMsg := fmt.Sprintf("guest with name `%s` is already checked in", guest.Name)
 	assert.EqualErrorf(t, err, expectedErrorMsg, "Error should be %v but found %v", err, expectedErrorMsg)
 
 	// Check in undefined guest
 	guest.Name = "rob"
-	checkedInGuest, err = guestListService.CheckInGuest(&guest)
+	checkedInGuest, err = guestListService.CheckInGuest(ctx, entity.DefaultEventID, &guest)
 	expectedErrorMsg = fmt.Sprintf("found no guest called `%s`", guest.Name)
 	assert.EqualErrorf(t, err, expectedErrorMsg, "Error should be %v but found %v", err, expectedErrorMsg)
 	assert.Nil(t, checkedInGuest, "Expected checkedInGuest to not have value")
@@ -184,7 +279,7 @@ func TestGetAllCheckedInGuests(t *testing.T) {
 	// Create a new table
 	var table entity.Table
 	table.Capacity = 5
-	newTable, err := guestListService.CreateTable(&table)
+	newTable, err := guestListService.CreateTable(ctx, entity.DefaultEventID, &table)
 	assert.Nil(t, err, "Error while creating a new table, %v", err)
 	assert.NotNil(t, newTable, "Expected table to have value but found nil")
 
@@ -193,7 +288,7 @@ func TestGetAllCheckedInGuests(t *testing.T) {
 	john.Name = "john"
 	john.AccompanyingGuests = 0
 	john.TableID = newTable.ID
-	newGuest, err := guestListService.AddGuest(&john)
+	newGuest, err := guestListService.AddGuest(ctx, entity.DefaultEventID, &john)
 	assert.Nil(t, err, "Error while creating a new guest, %v", err)
 	assert.NotNil(t, newGuest, "Expected guest to have value but found nil")
 
@@ -201,21 +296,21 @@ func TestGetAllCheckedInGuests(t *testing.T) {
 	rob.Name = "rob"
 	rob.AccompanyingGuests = 0
 	rob.TableID = newTable.ID
-	newGuest, err = guestListService.AddGuest(&rob)
+	newGuest, err = guestListService.AddGuest(ctx, entity.DefaultEventID, &rob)
 	assert.Nil(t, err, "Error while creating a new guest, %v", err)
 	assert.NotNil(t, newGuest, "Expected guest to have value but found nil")
 
 	// Check in john
-	checkedInGuest, err := guestListService.CheckInGuest(&john)
+	checkedInGuest, err := guestListService.CheckInGuest(ctx, entity.DefaultEventID, &john)
 	assert.Nil(t, err, "Error while checking in the guest, %v", err)
 	assert.NotNil(t, checkedInGuest, "Expected `checkedInGuest` to have value but found nil")
 
 	// Test getting checked in guests
-	var checkedInGuests []entity.GetAllCheckedInGuestsElement
-	checkedInGuests, err = guestListService.GetAllCheckedInGuests()
+	var checkedInGuests *entity.GetAllCheckedInGuestsResponseBody
+	checkedInGuests, err = guestListService.GetAllCheckedInGuests(ctx, entity.DefaultEventID, entity.GuestFilter{})
 	assert.Nil(t, err, "Error while getting all checked in guests, %v", err)
 	assert.NotNil(t, checkedInGuests, "Expected guests to have value but found nil")
-	assert.Equalf(t, 1, len(checkedInGuests), "Expected the number of guests to be 2 but found %d", len(checkedInGuests))
+	assert.Equalf(t, 1, len(checkedInGuests.Guests), "Expected the number of guests to be 2 but found %d", len(checkedInGuests.Guests))
 }
 
 func TestCountEmptySeats(t *testing.T) {
@@ -226,7 +321,7 @@ func TestCountEmptySeats(t *testing.T) {
 	// Create a new table
 	var table entity.Table
 	table.Capacity = 5
-	newTable, err := guestListService.CreateTable(&table)
+	newTable, err := guestListService.CreateTable(ctx, entity.DefaultEventID, &table)
 	assert.Nil(t, err, "Error while creating a new table, %v", err)
 	assert.NotNil(t, newTable, "Expected table to have value but found nil")
 
@@ -235,19 +330,19 @@ func TestCountEmptySeats(t *testing.T) {
 	guest.Name = "john"
 	guest.AccompanyingGuests = 0
 	guest.TableID = newTable.ID
-	newGuest, err := guestListService.AddGuest(&guest)
+	newGuest, err := guestListService.AddGuest(ctx, entity.DefaultEventID, &guest)
 	assert.Nil(t, err, "Error while creating a new guest, %v", err)
 	assert.NotNil(t, newGuest, "Expected guest to have value but found nil")
 
 	guest.Name = "rob"
 	guest.AccompanyingGuests = 0
 	guest.TableID = newTable.ID
-	newGuest, err = guestListService.AddGuest(&guest)
+	newGuest, err = guestListService.AddGuest(ctx, entity.DefaultEventID, &guest)
 	assert.Nil(t, err, "Error while creating a new guest, %v", err)
 	assert.NotNil(t, newGuest, "Expected guest to have value but found nil")
 
 	// Test counting the number of empty seats
-	emptySeats, err := guestListService.CountEmptySeats()
+	emptySeats, err := guestListService.CountEmptySeats(ctx, entity.DefaultEventID)
 	assert.Nil(t, err, "Error while counting empty seats, %v", err)
 	assert.NotNil(t, emptySeats, "Expected emptySeats to have value but found nil")
 	assert.Equalf(t, 3, emptySeats, "Expected the number of guests to be 3 but found %d", emptySeats)
@@ -261,7 +356,7 @@ func TestCheckoutGuest(t *testing.T) {
 	// Create a new table
 	var table entity.Table
 	table.Capacity = 5
-	newTable, err := guestListService.CreateTable(&table)
+	newTable, err := guestListService.CreateTable(ctx, entity.DefaultEventID, &table)
 	assert.Nil(t, err, "Error while creating a new table, %v", err)
 	assert.NotNil(t, newTable, "Expected table to have value but found nil")
 
@@ -270,27 +365,791 @@ func TestCheckoutGuest(t *testing.T) {
 	guest.Name = "john"
 	guest.AccompanyingGuests = 0
 	guest.TableID = newTable.ID
-	newGuest, err := guestListService.AddGuest(&guest)
+	newGuest, err := guestListService.AddGuest(ctx, entity.DefaultEventID, &guest)
 	assert.Nil(t, err, "Error while creating a new guest, %v", err)
 	assert.NotNil(t, newGuest, "Expected guest to have value but found nil")
 
 	// Check out the guest without checking them in
-	err = guestListService.CheckoutGuest(&guest)
+	err = guestListService.CheckoutGuest(ctx, entity.DefaultEventID, &guest)
 	expectedErrorMsg := fmt.Sprintf("guest `%s` is not checked in", guest.Name)
 	assert.EqualErrorf(t, err, expectedErrorMsg, "Error should be %v but found %v", err, expectedErrorMsg)
 
 	// Check in guest
-	checkedInGuest, err := guestListService.CheckInGuest(&guest)
+	checkedInGuest, err := guestListService.CheckInGuest(ctx, entity.DefaultEventID, &guest)
 	assert.Nil(t, err, "Error while checking in the guest, %v", err)
 	assert.NotNil(t, checkedInGuest, "Expected `checkedInGuest` to have value but found nil")
 
 	// Check out the guest
-	err = guestListService.CheckoutGuest(&guest)
+	err = guestListService.CheckoutGuest(ctx, entity.DefaultEventID, &guest)
 	assert.Nil(t, err, "Error while checking out guest, %v", err)
 
 	// Count empty seats
-	emptySeats, err := guestListService.CountEmptySeats()
+	emptySeats, err := guestListService.CountEmptySeats(ctx, entity.DefaultEventID)
 	assert.Nil(t, err, "Error while getting all checked in guests, %v", err)
 	assert.NotNil(t, emptySeats, "Expected guests to have value but found nil")
 	assert.Equalf(t, 5, emptySeats, "Expected the number of guests to be 2 but found %d", emptySeats)
 }
+
+func TestGetTables(t *testing.T) {
+	// Setup database
+	setupServiceTest()
+	defer dbClient.Close()
+
+	// Create new tables
+	var table entity.Table
+	table.Capacity = 5
+	firstTable, err := guestListService.CreateTable(ctx, entity.DefaultEventID, &table)
+	assert.Nil(t, err, "Error while creating a new table, %v", err)
+	table.Capacity = 8
+	secondTable, err := guestListService.CreateTable(ctx, entity.DefaultEventID, &table)
+	assert.Nil(t, err, "Error while creating a new table, %v", err)
+
+	// Test listing all tables
+	tables, err := guestListService.GetAllTables(ctx, entity.DefaultEventID)
+	assert.Nil(t, err, "Error while getting all tables, %v", err)
+	assert.Equalf(t, 2, len(tables), "Expected the number of tables to be 2 but found %d", len(tables))
+
+	// Test getting a single table
+	retrievedTable, err := guestListService.GetTable(ctx, entity.DefaultEventID, secondTable.ID)
+	assert.Nil(t, err, "Error while getting table, %v", err)
+	assert.Equal(t, entity.Table{ID: secondTable.ID, EventID: entity.DefaultEventID, Capacity: 8, ReservedSeats: 0}, *retrievedTable)
+
+	// Test getting a table that does not exist
+	_, err = guestListService.GetTable(ctx, entity.DefaultEventID, firstTable.ID+secondTable.ID)
+	assert.ErrorIs(t, err, ErrTableNotFound)
+}
+
+func TestUpdateTable(t *testing.T) {
+	// Setup database
+	setupServiceTest()
+	defer dbClient.Close()
+
+	// Create a new table with a guest
+	var table entity.Table
+	table.Capacity = 5
+	newTable, err := guestListService.CreateTable(ctx, entity.DefaultEventID, &table)
+	assert.Nil(t, err, "Error while creating a new table, %v", err)
+
+	var guest entity.Guest
+	guest.Name = "john"
+	guest.AccompanyingGuests = 2
+	guest.TableID = newTable.ID
+	_, err = guestListService.AddGuest(ctx, entity.DefaultEventID, &guest)
+	assert.Nil(t, err, "Error while creating a new guest, %v", err)
+
+	// Test growing the table
+	capacity := 10
+	updatedTable, err := guestListService.UpdateTable(ctx, entity.DefaultEventID, newTable.ID, &entity.UpdateTableRequestBody{Capacity: &capacity})
+	assert.Nil(t, err, "Error while updating table, %v", err)
+	assert.Equal(t, 10, updatedTable.Capacity)
+	assert.Equal(t, 3, updatedTable.ReservedSeats)
+
+	// Test shrinking the table down to its reserved seats
+	capacity = 3
+	_, err = guestListService.UpdateTable(ctx, entity.DefaultEventID, newTable.ID, &entity.UpdateTableRequestBody{Capacity: &capacity})
+	assert.Nil(t, err, "Error while updating table, %v", err)
+
+	// Test shrinking the table below its reserved seats
+	capacity = 2
+	_, err = guestListService.UpdateTable(ctx, entity.DefaultEventID, newTable.ID, &entity.UpdateTableRequestBody{Capacity: &capacity})
+	assert.ErrorIs(t, err, ErrCapacityTooSmall)
+
+	emptySeats, err := guestListService.CountEmptySeats(ctx, entity.DefaultEventID)
+	assert.Nil(t, err, "Error while counting empty seats, %v", err)
+	assert.Equalf(t, 0, emptySeats, "Expected the number of empty seats to be 0 but found %d", emptySeats)
+}
+
+func TestDeleteTable(t *testing.T) {
+	// Setup database
+	setupServiceTest()
+	defer dbClient.Close()
+
+	// Create new tables with guests
+	var table entity.Table
+	table.Capacity = 5
+	firstTable, err := guestListService.CreateTable(ctx, entity.DefaultEventID, &table)
+	assert.Nil(t, err, "Error while creating a new table, %v", err)
+	secondTable, err := guestListService.CreateTable(ctx, entity.DefaultEventID, &table)
+	assert.Nil(t, err, "Error while creating a new table, %v", err)
+	table.Capacity = 2
+	smallTable, err := guestListService.CreateTable(ctx, entity.DefaultEventID, &table)
+	assert.Nil(t, err, "Error while creating a new table, %v", err)
+
+	var guest entity.Guest
+	guest.Name = "john"
+	guest.AccompanyingGuests = 2
+	guest.TableID = firstTable.ID
+	_, err = guestListService.AddGuest(ctx, entity.DefaultEventID, &guest)
+	assert.Nil(t, err, "Error while creating a new guest, %v", err)
+
+	guest.Name = "rob"
+	guest.AccompanyingGuests = 0
+	guest.TableID = secondTable.ID
+	_, err = guestListService.AddGuest(ctx, entity.DefaultEventID, &guest)
+	assert.Nil(t, err, "Error while creating a new guest, %v", err)
+
+	// Seat guests who already left at the first and small tables
+	for _, departed := range []entity.Guest{{Name: "amy", TableID: firstTable.ID}, {Name: "kim", TableID: smallTable.ID}} {
+		_, err = guestListService.AddGuest(ctx, entity.DefaultEventID, &departed)
+		assert.Nil(t, err, "Error while creating a new guest, %v", err)
+		_, err = guestListService.CheckInGuest(ctx, entity.DefaultEventID, &departed)
+		assert.Nil(t, err, "Error while checking in the guest, %v", err)
+		err = guestListService.CheckoutGuest(ctx, entity.DefaultEventID, &departed)
+		assert.Nil(t, err, "Error while checking out guest, %v", err)
+	}
+
+	// Test deleting a table with guests without choosing what to do with them
+	err = guestListService.DeleteTable(ctx, entity.DefaultEventID, firstTable.ID, entity.DeleteTableOptions{})
+	assert.ErrorIs(t, err, ErrTableNotEmpty)
+
+	// Test reassigning guests to a table without enough seats
+	err = guestListService.DeleteTable(ctx, entity.DefaultEventID, firstTable.ID, entity.DeleteTableOptions{
+		Guests:     entity.DeleteTableReassign,
+		ReassignTo: smallTable.ID,
+	})
+	assert.ErrorIs(t, err, ErrNoSeats)
+
+	// Test reassigning guests to another table
+	err = guestListService.DeleteTable(ctx, entity.DefaultEventID, firstTable.ID, entity.DeleteTableOptions{
+		Guests:     entity.DeleteTableReassign,
+		ReassignTo: secondTable.ID,
+	})
+	assert.Nil(t, err, "Error while deleting table, %v", err)
+
+	var john entity.Guest
+	err = dbClient.FindUnique(ctx, &john, "guest", guestKey(entity.DefaultEventID, "john"))
+	assert.Nil(t, err, "Error while getting guest, %v", err)
+	assert.Equal(t, secondTable.ID, john.TableID)
+
+	retrievedTable, err := guestListService.GetTable(ctx, entity.DefaultEventID, secondTable.ID)
+	assert.Nil(t, err, "Error while getting table, %v", err)
+	assert.Equal(t, 4, retrievedTable.ReservedSeats)
+
+	// Test that departed guests keep the table they sat at
+	var amy entity.Guest
+	err = dbClient.FindUnique(ctx, &amy, "guest", guestKey(entity.DefaultEventID, "amy"))
+	assert.Nil(t, err, "Error while getting guest, %v", err)
+	assert.Equal(t, firstTable.ID, amy.TableID)
+
+	// Test cascading the delete to the guests
+	err = guestListService.DeleteTable(ctx, entity.DefaultEventID, secondTable.ID, entity.DeleteTableOptions{Guests: entity.DeleteTableCascade})
+	assert.Nil(t, err, "Error while deleting table, %v", err)
+
+	guests, err := guestListService.GetAllGuests(ctx, entity.DefaultEventID, entity.GuestFilter{})
+	assert.Nil(t, err, "Error while getting all guests, %v", err)
+	assert.Equalf(t, 2, len(guests.Guests), "Expected the number of guests to be 2 but found %d", len(guests.Guests))
+	assert.Equal(t, "amy", guests.Guests[0].Name)
+	assert.Equal(t, "kim", guests.Guests[1].Name)
+
+	// Test deleting a table whose guests all left
+	err = guestListService.DeleteTable(ctx, entity.DefaultEventID, smallTable.ID, entity.DeleteTableOptions{})
+	assert.Nil(t, err, "Error while deleting table, %v", err)
+
+	_, err = guestListService.GetTable(ctx, entity.DefaultEventID, smallTable.ID)
+	assert.ErrorIs(t, err, ErrTableNotFound)
+
+	// Test that the visits of departed guests are still on record
+	history, err := guestListService.GetGuestHistory(ctx, entity.DefaultEventID)
+	assert.Nil(t, err, "Error while getting guest history, %v", err)
+	assert.Equalf(t, 2, len(history), "Expected the number of visits to be 2 but found %d", len(history))
+}
+
+func TestUpdateGuest(t *testing.T) {
+	// Setup database
+	setupServiceTest()
+	defer dbClient.Close()
+
+	// Create new tables with a guest
+	var table entity.Table
+	table.Capacity = 5
+	firstTable, err := guestListService.CreateTable(ctx, entity.DefaultEventID, &table)
+	assert.Nil(t, err, "Error while creating a new table, %v", err)
+	table.Capacity = 3
+	secondTable, err := guestListService.CreateTable(ctx, entity.DefaultEventID, &table)
+	assert.Nil(t, err, "Error while creating a new table, %v", err)
+
+	var guest entity.Guest
+	guest.Name = "john"
+	guest.AccompanyingGuests = 1
+	guest.TableID = firstTable.ID
+	_, err = guestListService.AddGuest(ctx, entity.DefaultEventID, &guest)
+	assert.Nil(t, err, "Error while creating a new guest, %v", err)
+
+	// Test growing the party on the same table
+	accompanyingGuests := 3
+	updatedGuest, err := guestListService.UpdateGuest(ctx, entity.DefaultEventID, "john", &entity.UpdateGuestRequestBody{
+		AccompanyingGuests: &accompanyingGuests,
+	})
+	assert.Nil(t, err, "Error while updating guest, %v", err)
+	assert.Equal(t, entity.UpdateGuestResponseBody{Name: "john", Table: firstTable.ID, AccompanyingGuests: 3}, *updatedGuest)
+
+	retrievedTable, err := guestListService.GetTable(ctx, entity.DefaultEventID, firstTable.ID)
+	assert.Nil(t, err, "Error while getting table, %v", err)
+	assert.Equal(t, 4, retrievedTable.ReservedSeats)
+
+	// Test moving the party to a table without enough seats
+	_, err = guestListService.UpdateGuest(ctx, entity.DefaultEventID, "john", &entity.UpdateGuestRequestBody{
+		Table: &secondTable.ID,
+	})
+	assert.ErrorIs(t, err, ErrNoSeats)
+
+	// Test moving a smaller party to the other table
+	accompanyingGuests = 2
+	_, err = guestListService.UpdateGuest(ctx, entity.DefaultEventID, "john", &entity.UpdateGuestRequestBody{
+		Table:              &secondTable.ID,
+		AccompanyingGuests: &accompanyingGuests,
+	})
+	assert.Nil(t, err, "Error while updating guest, %v", err)
+
+	retrievedTable, err = guestListService.GetTable(ctx, entity.DefaultEventID, firstTable.ID)
+	assert.Nil(t, err, "Error while getting table, %v", err)
+	assert.Equal(t, 0, retrievedTable.ReservedSeats)
+	retrievedTable, err = guestListService.GetTable(ctx, entity.DefaultEventID, secondTable.ID)
+	assert.Nil(t, err, "Error while getting table, %v", err)
+	assert.Equal(t, 3, retrievedTable.ReservedSeats)
+
+	// Test moving the guest to a table that does not exist
+	missingTableID := firstTable.ID + secondTable.ID
+	_, err = guestListService.UpdateGuest(ctx, entity.DefaultEventID, "john", &entity.UpdateGuestRequestBody{
+		Table: &missingTableID,
+	})
+	assert.ErrorIs(t, err, ErrTableNotFound)
+
+	// Test updating a guest that has already arrived
+	guest.AccompanyingGuests = 2
+	_, err = guestListService.CheckInGuest(ctx, entity.DefaultEventID, &guest)
+	assert.Nil(t, err, "Error while checking in the guest, %v", err)
+	_, err = guestListService.UpdateGuest(ctx, entity.DefaultEventID, "john", &entity.UpdateGuestRequestBody{
+		Table: &firstTable.ID,
+	})
+	assert.ErrorIs(t, err, ErrAlreadyCheckedIn)
+}
+
+func TestRemoveGuest(t *testing.T) {
+	// Setup database
+	setupServiceTest()
+	defer dbClient.Close()
+
+	// Create a new table with guests
+	var table entity.Table
+	table.Capacity = 5
+	newTable, err := guestListService.CreateTable(ctx, entity.DefaultEventID, &table)
+	assert.Nil(t, err, "Error while creating a new table, %v", err)
+
+	var guest entity.Guest
+	guest.Name = "john"
+	guest.AccompanyingGuests = 2
+	guest.TableID = newTable.ID
+	_, err = guestListService.AddGuest(ctx, entity.DefaultEventID, &guest)
+	assert.Nil(t, err, "Error while creating a new guest, %v", err)
+
+	guest.Name = "rob"
+	guest.AccompanyingGuests = 0
+	_, err = guestListService.AddGuest(ctx, entity.DefaultEventID, &guest)
+	assert.Nil(t, err, "Error while creating a new guest, %v", err)
+
+	// Test removing a guest before arrival
+	err = guestListService.RemoveGuest(ctx, entity.DefaultEventID, "john")
+	assert.Nil(t, err, "Error while removing guest, %v", err)
+
+	emptySeats, err := guestListService.CountEmptySeats(ctx, entity.DefaultEventID)
+	assert.Nil(t, err, "Error while counting empty seats, %v", err)
+	assert.Equalf(t, 4, emptySeats, "Expected the number of empty seats to be 4 but found %d", emptySeats)
+
+	// Test removing a guest that does not exist
+	err = guestListService.RemoveGuest(ctx, entity.DefaultEventID, "john")
+	assert.ErrorIs(t, err, ErrGuestNotFound)
+
+	// Test removing a guest that has already arrived
+	_, err = guestListService.CheckInGuest(ctx, entity.DefaultEventID, &guest)
+	assert.Nil(t, err, "Error while checking in the guest, %v", err)
+	err = guestListService.RemoveGuest(ctx, entity.DefaultEventID, "rob")
+	assert.ErrorIs(t, err, ErrAlreadyCheckedIn)
+}
+
+func TestGuestHistoryAndReentry(t *testing.T) {
+	// Setup database
+	setupServiceTest()
+	defer dbClient.Close()
+
+	// Create a new table with a guest
+	var table entity.Table
+	table.Capacity = 3
+	newTable, err := guestListService.CreateTable(ctx, entity.DefaultEventID, &table)
+	assert.Nil(t, err, "Error while creating a new table, %v", err)
+
+	var guest entity.Guest
+	guest.Name = "john"
+	guest.AccompanyingGuests = 1
+	guest.TableID = newTable.ID
+	_, err = guestListService.AddGuest(ctx, entity.DefaultEventID, &guest)
+	assert.Nil(t, err, "Error while creating a new guest, %v", err)
+
+	// Check the guest in and out
+	_, err = guestListService.CheckInGuest(ctx, entity.DefaultEventID, &guest)
+	assert.Nil(t, err, "Error while checking in the guest, %v", err)
+	arrived := clock.now
+	clock.now = clock.now.Add(2 * time.Hour)
+	err = guestListService.CheckoutGuest(ctx, entity.DefaultEventID, &guest)
+	assert.Nil(t, err, "Error while checking out guest, %v", err)
+
+	// Test that the visit is kept on record
+	var retrievedGuest entity.Guest
+	err = dbClient.FindUnique(ctx, &retrievedGuest, "guest", guestKey(entity.DefaultEventID, "john"))
+	assert.Nil(t, err, "Error while getting guest, %v", err)
+	assert.Equal(t, entity.GuestStatusDeparted, retrievedGuest.Status)
+	assert.NotNil(t, retrievedGuest.TimeArrived, "Expected `time_arrived` to have value but found nil")
+	assert.NotNil(t, retrievedGuest.TimeLeft, "Expected `time_left` to have value but found nil")
+
+	history, err := guestListService.GetGuestHistory(ctx, entity.DefaultEventID)
+	assert.Nil(t, err, "Error while getting guest history, %v", err)
+	assert.Equalf(t, 1, len(history), "Expected the number of departed guests to be 1 but found %d", len(history))
+	assert.Equal(t, arrived, history[0].TimeArrived)
+	assert.Equal(t, clock.now, history[0].TimeLeft)
+
+	checkedInGuests, err := guestListService.GetAllCheckedInGuests(ctx, entity.DefaultEventID, entity.GuestFilter{})
+	assert.Nil(t, err, "Error while getting all checked in guests, %v", err)
+	assert.Equalf(t, 0, len(checkedInGuests.Guests), "Expected the number of guests to be 0 but found %d", len(checkedInGuests.Guests))
+
+	// Test checking out a guest that already left
+	err = guestListService.CheckoutGuest(ctx, entity.DefaultEventID, &guest)
+	assert.ErrorIs(t, err, ErrNotCheckedIn)
+
+	// Test re-admitting a bigger party than the table can seat
+	guest.AccompanyingGuests = 3
+	_, err = guestListService.CheckInGuest(ctx, entity.DefaultEventID, &guest)
+	assert.ErrorIs(t, err, ErrNoSeats)
+
+	// Test re-admitting the guest
+	guest.AccompanyingGuests = 2
+	_, err = guestListService.CheckInGuest(ctx, entity.DefaultEventID, &guest)
+	assert.Nil(t, err, "Error while checking in the guest, %v", err)
+
+	err = dbClient.FindUnique(ctx, &retrievedGuest, "guest", guestKey(entity.DefaultEventID, "john"))
+	assert.Nil(t, err, "Error while getting guest, %v", err)
+	assert.Equal(t, entity.GuestStatusArrived, retrievedGuest.Status)
+	assert.Nil(t, retrievedGuest.TimeLeft, "Expected `time_left` to be cleared")
+
+	emptySeats, err := guestListService.CountEmptySeats(ctx, entity.DefaultEventID)
+	assert.Nil(t, err, "Error while counting empty seats, %v", err)
+	assert.Equalf(t, 0, emptySeats, "Expected the number of empty seats to be 0 but found %d", emptySeats)
+
+	// Test that the first visit is still on record
+	left := clock.now
+	history, err = guestListService.GetGuestHistory(ctx, entity.DefaultEventID)
+	assert.Nil(t, err, "Error while getting guest history, %v", err)
+	assert.Equalf(t, 1, len(history), "Expected the number of visits to be 1 but found %d", len(history))
+	assert.Equal(t, arrived, history[0].TimeArrived)
+	assert.Equal(t, left, history[0].TimeLeft)
+
+	// Test that every visit is on record once the guest leaves again
+	rearrived := clock.now
+	clock.now = clock.now.Add(time.Hour)
+	err = guestListService.CheckoutGuest(ctx, entity.DefaultEventID, &guest)
+	assert.Nil(t, err, "Error while checking out guest, %v", err)
+
+	history, err = guestListService.GetGuestHistory(ctx, entity.DefaultEventID)
+	assert.Nil(t, err, "Error while getting guest history, %v", err)
+	assert.Equalf(t, 2, len(history), "Expected the number of visits to be 2 but found %d", len(history))
+	assert.Equal(t, 1, history[0].AccompanyingGuests)
+	assert.Equal(t, arrived, history[0].TimeArrived)
+	assert.Equal(t, left, history[0].TimeLeft)
+	assert.Equal(t, 2, history[1].AccompanyingGuests)
+	assert.Equal(t, rearrived, history[1].TimeArrived)
+	assert.Equal(t, clock.now, history[1].TimeLeft)
+}
+
+func TestCreateEvent(t *testing.T) {
+	// Setup database
+	setupServiceTest()
+	defer dbClient.Close()
+
+	// Create an event
+	start := time.Date(2023, 6, 1, 18, 0, 0, 0, time.UTC)
+	end := start.Add(6 * time.Hour)
+	event, err := guestListService.CreateEvent(ctx, &entity.CreateEventRequestBody{
+		Name:      "Summer party",
+		Venue:     "Rooftop",
+		StartTime: &start,
+		EndTime:   &end,
+	})
+	assert.Nil(t, err, "Error while creating an event, %v", err)
+
+	// Retrieve it again
+	retrievedEvent, err := guestListService.GetEvent(ctx, event.ID)
+	assert.Nil(t, err, "Error while getting event, %v", err)
+	assert.Equal(t, "Summer party", retrievedEvent.Name)
+	assert.Equal(t, "Rooftop", retrievedEvent.Venue)
+	assert.True(t, start.Equal(*retrievedEvent.StartTime), "Expected start time %v but found %v", start, retrievedEvent.StartTime)
+
+	// List the default event along with the new one
+	events, err := guestListService.GetAllEvents(ctx)
+	assert.Nil(t, err, "Error while getting events, %v", err)
+	assert.Len(t, events, 2)
+
+	// Events cannot end before they start
+	_, err = guestListService.CreateEvent(ctx, &entity.CreateEventRequestBody{
+		Name:      "Backwards party",
+		StartTime: &end,
+		EndTime:   &start,
+	})
+	assert.ErrorIs(t, err, ErrInvalidEvent)
+
+	// Unknown events are reported as not found
+	_, err = guestListService.GetEvent(ctx, event.ID+1)
+	assert.ErrorIs(t, err, ErrEventNotFound)
+}
+
+func TestEventScoping(t *testing.T) {
+	// Setup database
+	setupServiceTest()
+	defer dbClient.Close()
+
+	event, err := guestListService.CreateEvent(ctx, &entity.CreateEventRequestBody{Name: "Afterparty"})
+	assert.Nil(t, err, "Error while creating an event, %v", err)
+
+	// Create a table in each event
+	defaultTable, err := guestListService.CreateTable(ctx, entity.DefaultEventID, &entity.Table{Capacity: 10})
+	assert.Nil(t, err, "Error while creating a new table, %v", err)
+	eventTable, err := guestListService.CreateTable(ctx, event.ID, &entity.Table{Capacity: 4})
+	assert.Nil(t, err, "Error while creating a new table, %v", err)
+
+	// Tables of another event are not visible
+	_, err = guestListService.GetTable(ctx, event.ID, defaultTable.ID)
+	assert.ErrorIs(t, err, ErrTableNotFound)
+	tables, err := guestListService.GetAllTables(ctx, event.ID)
+	assert.Nil(t, err, "Error while getting tables, %v", err)
+	assert.Len(t, tables, 1)
+
+	// Guests cannot be seated at a table of another event
+	_, err = guestListService.AddGuest(ctx, event.ID, &entity.Guest{Name: "john", TableID: defaultTable.ID})
+	assert.ErrorIs(t, err, ErrTableNotFound)
+
+	// The same name can be on the guest list of both events
+	_, err = guestListService.AddGuest(ctx, entity.DefaultEventID, &entity.Guest{Name: "john", TableID: defaultTable.ID, AccompanyingGuests: 2})
+	assert.Nil(t, err, "Error while adding guest, %v", err)
+	_, err = guestListService.AddGuest(ctx, event.ID, &entity.Guest{Name: "john", TableID: eventTable.ID})
+	assert.Nil(t, err, "Error while adding guest, %v", err)
+
+	// Empty seats are counted per event
+	emptySeats, err := guestListService.CountEmptySeats(ctx, entity.DefaultEventID)
+	assert.Nil(t, err, "Error while counting empty seats, %v", err)
+	assert.Equal(t, 7, emptySeats)
+	emptySeats, err = guestListService.CountEmptySeats(ctx, event.ID)
+	assert.Nil(t, err, "Error while counting empty seats, %v", err)
+	assert.Equal(t, 3, emptySeats)
+
+	// Checking in at one event leaves the other untouched
+	_, err = guestListService.CheckInGuest(ctx, event.ID, &entity.Guest{Name: "john"})
+	assert.Nil(t, err, "Error while checking in guest, %v", err)
+	arrived, err := guestListService.GetAllCheckedInGuests(ctx, entity.DefaultEventID, entity.GuestFilter{})
+	assert.Nil(t, err, "Error while getting checked in guests, %v", err)
+	assert.Len(t, arrived.Guests, 0)
+	arrived, err = guestListService.GetAllCheckedInGuests(ctx, event.ID, entity.GuestFilter{})
+	assert.Nil(t, err, "Error while getting checked in guests, %v", err)
+	assert.Len(t, arrived.Guests, 1)
+
+	// Tables cannot be created for unknown events
+	_, err = guestListService.CreateTable(ctx, event.ID+1, &entity.Table{Capacity: 4})
+	assert.ErrorIs(t, err, ErrEventNotFound)
+}
+
+func TestWaitlist(t *testing.T) {
+	// Setup database
+	setupServiceTest()
+	defer dbClient.Close()
+
+	// Record promotions through the hook
+	var promotions []string
+	guestListService = NewGuestListService(dbClient, WithClock(clock), WithPromotionHook(func(entry entity.WaitlistEntry) {
+		promotions = append(promotions, entry.Name)
+	}))
+
+	// Create a table with one free seat left
+	newTable, err := guestListService.CreateTable(ctx, entity.DefaultEventID, &entity.Table{Capacity: 4})
+	assert.Nil(t, err, "Error while creating a new table, %v", err)
+	tableID := newTable.ID
+	_, err = guestListService.AddGuest(ctx, entity.DefaultEventID, &entity.Guest{Name: "john", TableID: tableID, AccompanyingGuests: 2})
+	assert.Nil(t, err, "Error while adding guest, %v", err)
+
+	// A party that fits is seated straight away
+	entry, err := guestListService.JoinWaitlist(ctx, entity.DefaultEventID, "rob", &entity.JoinWaitlistRequestBody{AccompanyingGuests: 0})
+	assert.Nil(t, err, "Error while joining the waitlist, %v", err)
+	assert.Equal(t, entity.WaitlistStatusPromoted, entry.Status)
+	assert.Equal(t, tableID, *entry.TableID)
+
+	// Parties that don't fit wait in line
+	entry, err = guestListService.JoinWaitlist(ctx, entity.DefaultEventID, "mary", &entity.JoinWaitlistRequestBody{Table: &tableID, AccompanyingGuests: 1})
+	assert.Nil(t, err, "Error while joining the waitlist, %v", err)
+	assert.Equal(t, entity.WaitlistStatusWaiting, entry.Status)
+	_, err = guestListService.JoinWaitlist(ctx, entity.DefaultEventID, "anna", &entity.JoinWaitlistRequestBody{AccompanyingGuests: 0})
+	assert.Nil(t, err, "Error while joining the waitlist, %v", err)
+	_, err = guestListService.JoinWaitlist(ctx, entity.DefaultEventID, "paul", &entity.JoinWaitlistRequestBody{Table: &tableID, AccompanyingGuests: 3})
+	assert.Nil(t, err, "Error while joining the waitlist, %v", err)
+
+	// Test joining twice, with a party too large for the table and as a guest
+	_, err = guestListService.JoinWaitlist(ctx, entity.DefaultEventID, "mary", &entity.JoinWaitlistRequestBody{AccompanyingGuests: 0})
+	assert.ErrorIs(t, err, ErrGuestExists)
+	_, err = guestListService.JoinWaitlist(ctx, entity.DefaultEventID, "liz", &entity.JoinWaitlistRequestBody{Table: &tableID, AccompanyingGuests: 4})
+	assert.ErrorIs(t, err, ErrNoSeats)
+	_, err = guestListService.JoinWaitlist(ctx, entity.DefaultEventID, "john", &entity.JoinWaitlistRequestBody{AccompanyingGuests: 0})
+	assert.ErrorIs(t, err, ErrGuestExists)
+
+	// Checking out john frees three seats for mary and anna, paul still waits
+	_, err = guestListService.CheckInGuest(ctx, entity.DefaultEventID, &entity.Guest{Name: "john", AccompanyingGuests: 2})
+	assert.Nil(t, err, "Error while checking in guest, %v", err)
+	err = guestListService.CheckoutGuest(ctx, entity.DefaultEventID, &entity.Guest{Name: "john"})
+	assert.Nil(t, err, "Error while checking out guest, %v", err)
+	assert.Equal(t, []string{"rob", "mary", "anna"}, promotions)
+
+	var table entity.Table
+	err = dbClient.FindUnique(ctx, &table, "table", database.By("id", tableID))
+	assert.Nil(t, err, "Error while retrieving table, %v", err)
+	assert.Equal(t, 4, table.ReservedSeats)
+
+	var mary entity.Guest
+	err = dbClient.FindUnique(ctx, &mary, "guest", guestKey(entity.DefaultEventID, "mary"))
+	assert.Nil(t, err, "Error while retrieving guest, %v", err)
+	assert.Equal(t, 1, mary.AccompanyingGuests)
+
+	// Growing the table makes room for paul
+	capacity := 8
+	_, err = guestListService.UpdateTable(ctx, entity.DefaultEventID, tableID, &entity.UpdateTableRequestBody{Capacity: &capacity})
+	assert.Nil(t, err, "Error while updating table, %v", err)
+	assert.Equal(t, []string{"rob", "mary", "anna", "paul"}, promotions)
+
+	// The waitlist keeps promoted entries on record
+	entries, err := guestListService.GetWaitlist(ctx, entity.DefaultEventID)
+	assert.Nil(t, err, "Error while getting the waitlist, %v", err)
+	assert.Equal(t, 4, len(entries))
+	for _, e := range entries {
+		assert.Equal(t, entity.WaitlistStatusPromoted, e.Status)
+		assert.Equal(t, clock.now, *e.TimePromoted)
+	}
+
+	// Removing a guest promotes the next party, leaving drops them from the line
+	_, err = guestListService.JoinWaitlist(ctx, entity.DefaultEventID, "liz", &entity.JoinWaitlistRequestBody{Table: &tableID, AccompanyingGuests: 1})
+	assert.Nil(t, err, "Error while joining the waitlist, %v", err)
+	_, err = guestListService.JoinWaitlist(ctx, entity.DefaultEventID, "tom", &entity.JoinWaitlistRequestBody{AccompanyingGuests: 0})
+	assert.Nil(t, err, "Error while joining the waitlist, %v", err)
+	err = guestListService.LeaveWaitlist(ctx, entity.DefaultEventID, "liz")
+	assert.Nil(t, err, "Error while leaving the waitlist, %v", err)
+	err = guestListService.RemoveGuest(ctx, entity.DefaultEventID, "rob")
+	assert.Nil(t, err, "Error while removing guest, %v", err)
+	assert.Equal(t, []string{"rob", "mary", "anna", "paul", "tom"}, promotions)
+
+	// Promoted guests can't leave the waitlist
+	err = guestListService.LeaveWaitlist(ctx, entity.DefaultEventID, "tom")
+	assert.ErrorIs(t, err, ErrNotWaitlisted)
+
+	// Waiting parties added to the guest list directly leave the waitlist
+	// once seats come free instead of waiting forever
+	_, err = guestListService.JoinWaitlist(ctx, entity.DefaultEventID, "sam", &entity.JoinWaitlistRequestBody{Table: &tableID, AccompanyingGuests: 0})
+	assert.Nil(t, err, "Error while joining the waitlist, %v", err)
+	otherTable, err := guestListService.CreateTable(ctx, entity.DefaultEventID, &entity.Table{Capacity: 2})
+	assert.Nil(t, err, "Error while creating a new table, %v", err)
+	_, err = guestListService.AddGuest(ctx, entity.DefaultEventID, &entity.Guest{Name: "sam", TableID: otherTable.ID})
+	assert.Nil(t, err, "Error while adding guest, %v", err)
+	err = guestListService.RemoveGuest(ctx, entity.DefaultEventID, "tom")
+	assert.Nil(t, err, "Error while removing guest, %v", err)
+	assert.Equal(t, []string{"rob", "mary", "anna", "paul", "tom"}, promotions)
+
+	entries, err = guestListService.GetWaitlist(ctx, entity.DefaultEventID)
+	assert.Nil(t, err, "Error while getting the waitlist, %v", err)
+	for _, e := range entries {
+		assert.NotEqual(t, "sam", e.Name)
+	}
+}
+
+func TestAddGuestWithoutTable(t *testing.T) {
+	// Setup database
+	setupServiceTest()
+	defer dbClient.Close()
+
+	// Create tables of different sizes
+	small, err := guestListService.CreateTable(ctx, entity.DefaultEventID, &entity.Table{Capacity: 2})
+	assert.Nil(t, err, "Error while creating a new table, %v", err)
+	large, err := guestListService.CreateTable(ctx, entity.DefaultEventID, &entity.Table{Capacity: 6})
+	assert.Nil(t, err, "Error while creating a new table, %v", err)
+
+	// Best-fit picks the table leaving the fewest seats free
+	newGuest, err := guestListService.AddGuest(ctx, entity.DefaultEventID, &entity.Guest{Name: "john", AccompanyingGuests: 1})
+	assert.Nil(t, err, "Error while adding guest, %v", err)
+	assert.Equal(t, small.ID, newGuest.Table)
+	newGuest, err = guestListService.AddGuest(ctx, entity.DefaultEventID, &entity.Guest{Name: "rob", AccompanyingGuests: 0})
+	assert.Nil(t, err, "Error while adding guest, %v", err)
+	assert.Equal(t, large.ID, newGuest.Table)
+
+	var table entity.Table
+	err = dbClient.FindUnique(ctx, &table, "table", database.By("id", large.ID))
+	assert.Nil(t, err, "Error while retrieving table, %v", err)
+	assert.Equal(t, 1, table.ReservedSeats)
+
+	// Test a party no table has room for
+	_, err = guestListService.AddGuest(ctx, entity.DefaultEventID, &entity.Guest{Name: "mary", AccompanyingGuests: 5})
+	assert.ErrorIs(t, err, ErrNoSeats)
+
+	// First-fit picks the first table with room
+	guestListService = NewGuestListService(dbClient, WithTableAssigner(TableAssignerFunc(firstFit)))
+	_, err = guestListService.CreateTable(ctx, entity.DefaultEventID, &entity.Table{Capacity: 2})
+	assert.Nil(t, err, "Error while creating a new table, %v", err)
+	newGuest, err = guestListService.AddGuest(ctx, entity.DefaultEventID, &entity.Guest{Name: "mary", AccompanyingGuests: 1})
+	assert.Nil(t, err, "Error while adding guest, %v", err)
+	assert.Equal(t, large.ID, newGuest.Table)
+}
+
+func TestSeatingPlan(t *testing.T) {
+	// Setup database
+	setupServiceTest()
+	defer dbClient.Close()
+
+	// Create a plain and a quiet table
+	plain, err := guestListService.CreateTable(ctx, entity.DefaultEventID, &entity.Table{Capacity: 4})
+	assert.Nil(t, err, "Error while creating a new table, %v", err)
+	quiet, err := guestListService.CreateTable(ctx, entity.DefaultEventID, &entity.Table{Capacity: 4, Attributes: entity.Attributes{"quiet"}})
+	assert.Nil(t, err, "Error while creating a new table, %v", err)
+
+	// Test rejecting attributes that can't be stored
+	_, err = guestListService.CreateTable(ctx, entity.DefaultEventID, &entity.Table{Capacity: 4, Attributes: entity.Attributes{"a,b"}})
+	assert.ErrorIs(t, err, ErrInvalidAttribute)
+
+	guests := []entity.Guest{
+		{Name: "john", TableID: plain.ID, AccompanyingGuests: 0},
+		{Name: "rob", TableID: quiet.ID, AccompanyingGuests: 0},
+		{Name: "mary", TableID: plain.ID, AccompanyingGuests: 1},
+		{Name: "anna", TableID: quiet.ID, AccompanyingGuests: 0},
+	}
+	for i := range guests {
+		_, err = guestListService.AddGuest(ctx, entity.DefaultEventID, &guests[i])
+		assert.Nil(t, err, "Error while adding guest, %v", err)
+	}
+
+	// rob has arrived so john has to join him
+	_, err = guestListService.CheckInGuest(ctx, entity.DefaultEventID, &entity.Guest{Name: "rob"})
+	assert.Nil(t, err, "Error while checking in guest, %v", err)
+
+	robName, maryName, quietAttribute := "rob", "mary", "quiet"
+	requests := []entity.CreateSeatingConstraintRequestBody{
+		{Kind: entity.ConstraintTogether, Guest: "john", OtherGuest: &robName},
+		{Kind: entity.ConstraintApart, Guest: "john", OtherGuest: &maryName},
+		{Kind: entity.ConstraintRequires, Guest: "john", Attribute: &quietAttribute},
+	}
+	for i := range requests {
+		_, err = guestListService.CreateSeatingConstraint(ctx, entity.DefaultEventID, &requests[i])
+		assert.Nil(t, err, "Error while creating seating constraint, %v", err)
+	}
+
+	// Test invalid constraints
+	_, err = guestListService.CreateSeatingConstraint(ctx, entity.DefaultEventID, &entity.CreateSeatingConstraintRequestBody{Kind: entity.ConstraintApart, Guest: "john"})
+	assert.ErrorIs(t, err, ErrInvalidConstraint)
+	_, err = guestListService.CreateSeatingConstraint(ctx, entity.DefaultEventID, &entity.CreateSeatingConstraintRequestBody{Kind: "near", Guest: "john"})
+	assert.ErrorIs(t, err, ErrInvalidConstraint)
+	_, err = guestListService.CreateSeatingConstraint(ctx, entity.DefaultEventID, &entity.CreateSeatingConstraintRequestBody{Kind: entity.ConstraintTogether, Guest: "liz", OtherGuest: &robName})
+	assert.ErrorIs(t, err, ErrGuestNotFound)
+
+	constraints, err := guestListService.GetSeatingConstraints(ctx, entity.DefaultEventID)
+	assert.Nil(t, err, "Error while getting seating constraints, %v", err)
+	assert.Equal(t, 3, len(constraints))
+	assert.Equal(t, "rob", *constraints[0].OtherGuest)
+
+	// The preview moves john without touching the tables
+	plan, err := guestListService.OptimizeSeating(ctx, entity.DefaultEventID)
+	assert.Nil(t, err, "Error while optimizing seating, %v", err)
+	assert.Equal(t, 1, plan.Moves)
+	assert.Equal(t, 0, len(plan.Violations))
+	assert.False(t, plan.Applied)
+
+	var john entity.Guest
+	err = dbClient.FindUnique(ctx, &john, "guest", guestKey(entity.DefaultEventID, "john"))
+	assert.Nil(t, err, "Error while retrieving guest, %v", err)
+	assert.Equal(t, plain.ID, john.TableID)
+
+	// Applying the plan moves john and recomputes the reserved seats
+	plan, err = guestListService.ApplySeatingPlan(ctx, entity.DefaultEventID)
+	assert.Nil(t, err, "Error while applying seating plan, %v", err)
+	assert.True(t, plan.Applied)
+
+	err = dbClient.FindUnique(ctx, &john, "guest", guestKey(entity.DefaultEventID, "john"))
+	assert.Nil(t, err, "Error while retrieving guest, %v", err)
+	assert.Equal(t, quiet.ID, john.TableID)
+
+	tables, err := guestListService.GetAllTables(ctx, entity.DefaultEventID)
+	assert.Nil(t, err, "Error while getting all tables, %v", err)
+	for _, table := range tables {
+		if table.ID == plain.ID {
+			assert.Equal(t, 2, table.ReservedSeats)
+		} else {
+			assert.Equal(t, 3, table.ReservedSeats)
+			assert.Equal(t, entity.Attributes{"quiet"}, table.Attributes)
+		}
+	}
+
+	// Nothing is left to improve
+	plan, err = guestListService.OptimizeSeating(ctx, entity.DefaultEventID)
+	assert.Nil(t, err, "Error while optimizing seating, %v", err)
+	assert.Equal(t, 0, plan.Moves)
+
+	// Removing a guest drops their constraints
+	err = guestListService.RemoveGuest(ctx, entity.DefaultEventID, "mary")
+	assert.Nil(t, err, "Error while removing guest, %v", err)
+	constraints, err = guestListService.GetSeatingConstraints(ctx, entity.DefaultEventID)
+	assert.Nil(t, err, "Error while getting seating constraints, %v", err)
+	assert.Equal(t, 2, len(constraints))
+
+	err = guestListService.DeleteSeatingConstraint(ctx, entity.DefaultEventID, constraints[0].ID)
+	assert.Nil(t, err, "Error while deleting seating constraint, %v", err)
+	err = guestListService.DeleteSeatingConstraint(ctx, entity.DefaultEventID, constraints[0].ID)
+	assert.ErrorIs(t, err, ErrConstraintNotFound)
+}
+
+func TestImportGuests(t *testing.T) {
+	// Setup database
+	setupServiceTest()
+	defer dbClient.Close()
+
+	newTable, err := guestListService.CreateTable(ctx, entity.DefaultEventID, &entity.Table{Capacity: 4})
+	assert.Nil(t, err, "Error while creating a new table, %v", err)
+	tableID := newTable.ID
+	missingTableID := tableID + 1
+
+	rows := []entity.ImportGuestRow{
+		{Line: 1, Name: "john", Table: &tableID, AccompanyingGuests: 1},
+		{Line: 2, Name: "rob", AccompanyingGuests: 0},
+		{Line: 3, Name: "john", Table: &tableID},
+		{Line: 4, Name: "mary", Table: &missingTableID},
+		{Line: 5, Name: "anna", Table: &tableID, AccompanyingGuests: 1},
+	}
+
+	// An atomic import with failing rows adds nobody
+	result, err := guestListService.ImportGuests(ctx, entity.DefaultEventID, rows, entity.ImportAtomic)
+	assert.Nil(t, err, "Error while importing guests, %v", err)
+	assert.Equal(t, 0, result.Imported)
+	assert.Equal(t, 3, result.Failed)
+	assert.Equal(t, "guest with name john already exists", *result.Rows[2].Error)
+	assert.Nil(t, result.Rows[0].Table)
+	guests, err := guestListService.GetAllGuests(ctx, entity.DefaultEventID, entity.GuestFilter{})
+	assert.Nil(t, err, "Error while getting all guests, %v", err)
+	assert.Equal(t, 0, len(guests.Guests))
+
+	// A best-effort import keeps the rows that fit
+	result, err = guestListService.ImportGuests(ctx, entity.DefaultEventID, rows, entity.ImportBestEffort)
+	assert.Nil(t, err, "Error while importing guests, %v", err)
+	assert.Equal(t, 2, result.Imported)
+	assert.Equal(t, 3, result.Failed)
+	assert.Equal(t, tableID, *result.Rows[1].Table)
+	assert.Nil(t, result.Rows[1].Error)
+	assert.NotNil(t, result.Rows[4].Error, "Expected no seats for anna")
+
+	emptySeats, err := guestListService.CountEmptySeats(ctx, entity.DefaultEventID)
+	assert.Nil(t, err, "Error while counting empty seats, %v", err)
+	assert.Equal(t, 1, emptySeats)
+
+	// An atomic import without failures adds everyone
+	result, err = guestListService.ImportGuests(ctx, entity.DefaultEventID, []entity.ImportGuestRow{{Line: 1, Name: "liz", Table: &tableID}}, entity.ImportAtomic)
+	assert.Nil(t, err, "Error while importing guests, %v", err)
+	assert.Equal(t, 1, result.Imported)
+
+	// Test an unknown mode
+	_, err = guestListService.ImportGuests(ctx, entity.DefaultEventID, rows, "some")
+	assert.ErrorIs(t, err, ErrInvalidImport)
+}
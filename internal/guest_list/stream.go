@@ -0,0 +1,147 @@
+package guest_list
+
+import (
+	"sync"
+
+	"github.com/getground/tech-tasks/backend/internal/entity"
+)
+
+// DefaultStreamHistory is how many recent events a Bus keeps so clients
+// reconnecting to the stream can resume where they left off.
+const DefaultStreamHistory = 1024
+
+// subscriberBuffer is how many events a subscriber may fall behind before it
+// is dropped.
+const subscriberBuffer = 64
+
+// Bus fans the events of the guest list out to the subscribers of the live
+// stream. Events keep the id of their outbox entry.
+type Bus struct {
+	mu          sync.Mutex
+	history     []entity.StreamEvent
+	size        int
+	subscribers map[*Subscription]struct{}
+	closed      bool
+}
+
+// NewBus returns a bus remembering the last history events.
+func NewBus(history int) *Bus {
+	return &Bus{size: history, subscribers: map[*Subscription]struct{}{}}
+}
+
+// Publish hands event to every subscriber of its event. Subscribers too slow
+// to keep up are dropped rather than holding up the relay, and can resume
+// from the history once they reconnect.
+func (b *Bus) Publish(event entity.StreamEvent) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.history = append(b.history, event)
+	if len(b.history) > b.size {
+		b.history = b.history[len(b.history)-b.size:]
+	}
+
+	for sub := range b.subscribers {
+		if sub.eventID != event.EventID {
+			continue
+		}
+		select {
+		case sub.events <- event:
+		default:
+			b.remove(sub)
+		}
+	}
+}
+
+// Subscribe returns a subscription to the events of an event published after
+// the one numbered lastID, replaying those still in the history. A lastID of
+// 0 only receives new events.
+func (b *Bus) Subscribe(eventID int, lastID int64) *Subscription {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	var replay []entity.StreamEvent
+	if lastID > 0 {
+		// Retried events may be published out of order, so replay what
+		// followed lastID rather than the greater ids when it is known
+		start := -1
+		for i, event := range b.history {
+			if event.ID == lastID {
+				start = i + 1
+			}
+		}
+		for i, event := range b.history {
+			if event.EventID != eventID {
+				continue
+			}
+			if (start >= 0 && i >= start) || (start < 0 && event.ID > lastID) {
+				replay = append(replay, event)
+			}
+		}
+	}
+
+	sub := &Subscription{
+		bus:     b,
+		eventID: eventID,
+		events:  make(chan entity.StreamEvent, subscriberBuffer+len(replay)),
+	}
+	for _, event := range replay {
+		sub.events <- event
+	}
+	if b.closed {
+		close(sub.events)
+		return sub
+	}
+	b.subscribers[sub] = struct{}{}
+	return sub
+}
+
+// Close ends every subscription, for instance to let streams finish on
+// shutdown. Events published afterwards are only kept in the history.
+func (b *Bus) Close() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.closed = true
+	for sub := range b.subscribers {
+		b.remove(sub)
+	}
+}
+
+func (b *Bus) remove(sub *Subscription) {
+	if _, ok := b.subscribers[sub]; !ok {
+		return
+	}
+	delete(b.subscribers, sub)
+	close(sub.events)
+}
+
+// Subscription receives the events of a single event from a Bus.
+type Subscription struct {
+	bus     *Bus
+	eventID int
+	events  chan entity.StreamEvent
+}
+
+// Events is closed once the subscription ends.
+func (s *Subscription) Events() <-chan entity.StreamEvent {
+	return s.events
+}
+
+func (s *Subscription) Close() {
+	s.bus.mu.Lock()
+	defer s.bus.mu.Unlock()
+	s.bus.remove(s)
+}
+
+// WithBus makes the service publish its events on bus instead of a bus of
+// its own.
+func WithBus(bus *Bus) Option {
+	return func(s *service) {
+		s.bus = bus
+	}
+}
+
+func (s *service) Subscribe(eventID int, lastEventID int64) *Subscription {
+	return s.bus.Subscribe(eventID, lastEventID)
+}
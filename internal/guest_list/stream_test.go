@@ -0,0 +1,162 @@
+package guest_list
+
+import (
+	"bufio"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/getground/tech-tasks/backend/internal/entity"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBus(t *testing.T) {
+	bus := NewBus(3)
+	sub := bus.Subscribe(1, 0)
+
+	// Test events only reach subscribers of their event
+	for i := 1; i <= 4; i++ {
+		bus.Publish(entity.StreamEvent{ID: int64(i), Type: entity.StreamTableChanged, EventID: 1})
+	}
+	bus.Publish(entity.StreamEvent{ID: 5, Type: entity.StreamTableChanged, EventID: 2})
+	for i := 1; i <= 4; i++ {
+		event := <-sub.Events()
+		assert.Equal(t, int64(i), event.ID)
+	}
+	assert.Equal(t, 0, len(sub.Events()))
+
+	// Test resuming replays the events still in the history
+	resumed := bus.Subscribe(1, 1)
+	assert.Equal(t, 2, len(resumed.Events()))
+	assert.Equal(t, int64(3), (<-resumed.Events()).ID)
+	assert.Equal(t, 0, len(bus.Subscribe(1, 99).Events()))
+
+	// Test resuming replays what followed the last event seen even when
+	// retried events come out of order
+	bus.Publish(entity.StreamEvent{ID: 7, EventID: 1})
+	bus.Publish(entity.StreamEvent{ID: 6, EventID: 1})
+	resumed = bus.Subscribe(1, 7)
+	assert.Equal(t, 1, len(resumed.Events()))
+	assert.Equal(t, int64(6), (<-resumed.Events()).ID)
+	for i := 0; i < 2; i++ {
+		<-sub.Events()
+	}
+
+	// Test subscribers falling behind are dropped
+	for i := 0; i < subscriberBuffer+1; i++ {
+		bus.Publish(entity.StreamEvent{ID: int64(8 + i), EventID: 1})
+	}
+	count := 0
+	for range sub.Events() {
+		count++
+	}
+	assert.Equal(t, subscriberBuffer, count)
+
+	// Test closing the bus ends the subscriptions
+	live := bus.Subscribe(1, 0)
+	bus.Close()
+	_, ok := <-live.Events()
+	assert.False(t, ok)
+	live.Close()
+}
+
+func TestStream(t *testing.T) {
+	setupServiceTest()
+	defer dbClient.Close()
+
+	// Relay to a second sink ahead of the bus of the stream
+	var relayed []string
+	bus := NewBus(DefaultStreamHistory)
+	sinks := []Sink{NewSink("test", func(ctx context.Context, event entity.StreamEvent) error {
+		relayed = append(relayed, event.Type)
+		return nil
+	}), BusSink(bus)}
+	relay := NewRelay(dbClient, sinks)
+	runCtx, stop := context.WithCancel(ctx)
+	defer stop()
+	go relay.Run(runCtx)
+	guestListService := NewGuestListService(dbClient, WithBus(bus), WithRelay(relay))
+	r := mux.NewRouter()
+	RegisterHandlers(r, guestListService)
+	server := httptest.NewServer(r)
+	defer server.Close()
+
+	sub := guestListService.Subscribe(entity.DefaultEventID, 0)
+	defer sub.Close()
+
+	// Test every change is published along with the empty seats
+	table := entity.Table{Capacity: 4}
+	response, err := guestListService.CreateTable(ctx, entity.DefaultEventID, &table)
+	assert.Nil(t, err, "Error while creating table, %v", err)
+	_, err = guestListService.AddGuest(ctx, entity.DefaultEventID, &entity.Guest{Name: "john", TableID: response.ID, AccompanyingGuests: 1})
+	assert.Nil(t, err, "Error while adding guest, %v", err)
+	_, err = guestListService.CheckInGuest(ctx, entity.DefaultEventID, &entity.Guest{Name: "john", AccompanyingGuests: 1})
+	assert.Nil(t, err, "Error while checking in guest, %v", err)
+	err = guestListService.CheckoutGuest(ctx, entity.DefaultEventID, &entity.Guest{Name: "john"})
+	assert.Nil(t, err, "Error while checking out guest, %v", err)
+
+	expected := []struct {
+		kind       string
+		emptySeats int
+	}{
+		{entity.StreamTableChanged, 4},
+		{entity.StreamGuestAdded, 2},
+		{entity.StreamGuestCheckedIn, 2},
+		{entity.StreamGuestCheckedOut, 4},
+	}
+	var firstID int64
+	for i, e := range expected {
+		event := <-sub.Events()
+		if i == 0 {
+			firstID = event.ID
+		}
+		assert.Equal(t, e.kind, event.Type)
+		assert.Equal(t, e.emptySeats, event.EmptySeats)
+		assert.Equal(t, response.ID, *event.Table)
+	}
+
+	assert.Equal(t, 4, len(relayed))
+
+	// Test the stream resumes after Last-Event-ID
+	req, err := http.NewRequest(http.MethodGet, server.URL+"/events/stream", nil)
+	assert.Nil(t, err)
+	req.Header.Set("Last-Event-ID", strconv.FormatInt(firstID, 10))
+	res, err := http.DefaultClient.Do(req)
+	assert.Nil(t, err, "Error while opening stream, %v", err)
+	defer res.Body.Close()
+	assert.Equal(t, "text/event-stream", res.Header.Get("Content-Type"))
+
+	lines := make(chan string)
+	go func() {
+		scanner := bufio.NewScanner(res.Body)
+		for scanner.Scan() {
+			lines <- scanner.Text()
+		}
+		close(lines)
+	}()
+	var received []string
+	timeout := time.After(5 * time.Second)
+	for len(received) < 3 {
+		select {
+		case line := <-lines:
+			if strings.HasPrefix(line, "event: ") {
+				received = append(received, strings.TrimPrefix(line, "event: "))
+			}
+		case <-timeout:
+			t.Fatalf("Timed out waiting for events, got %v", received)
+		}
+	}
+	assert.Equal(t, []string{entity.StreamGuestAdded, entity.StreamGuestCheckedIn, entity.StreamGuestCheckedOut}, received)
+
+	// Test a malformed Last-Event-ID is refused
+	req.Header.Set("Last-Event-ID", "abc")
+	badRes, err := http.DefaultClient.Do(req)
+	assert.Nil(t, err)
+	badRes.Body.Close()
+	assert.Equal(t, http.StatusBadRequest, badRes.StatusCode)
+}
@@ -0,0 +1,268 @@
+package guest_list
+
+import (
+	"context"
+	"errors"
+
+	"github.com/getground/tech-tasks/backend/internal/entity"
+	"github.com/getground/tech-tasks/backend/pkg/database"
+)
+
+// PromotionHook is called with every waitlist entry promoted onto the guest
+// list, once the promotion is committed.
+type PromotionHook func(entry entity.WaitlistEntry)
+
+// WithPromotionHook registers hook to be told about waitlist promotions.
+func WithPromotionHook(hook PromotionHook) Option {
+	return func(s *service) {
+		s.promotionHooks = append(s.promotionHooks, hook)
+	}
+}
+
+func (s *service) notifyPromotions(promoted []entity.WaitlistEntry) {
+	for _, entry := range promoted {
+		for _, hook := range s.promotionHooks {
+			hook(entry)
+		}
+	}
+}
+
+func (s *service) JoinWaitlist(ctx context.Context, eventID int, name string, request *entity.JoinWaitlistRequestBody) (*entity.WaitlistEntry, error) {
+	var entry entity.WaitlistEntry
+	var promoted []entity.WaitlistEntry
+	err := s.dbClient.Transaction(ctx, func(tx database.Tx) error {
+		// Tables the party may be seated at, locked in id order
+		var tables []entity.Table
+		if request.Table != nil {
+			var table entity.Table
+			err := lockTable(ctx, tx, eventID, *request.Table, &table)
+			if err != nil {
+				return err
+			}
+			if request.AccompanyingGuests+1 > table.Capacity {
+				return newError(ErrNoSeats, "table %d can never seat a party of %d", table.ID, request.AccompanyingGuests+1)
+			}
+			tables = append(tables, table)
+		} else {
+			var err error
+			tables, err = lockEventTables(ctx, tx, eventID)
+			if err != nil {
+				return err
+			}
+		}
+
+		guestExists, err := tx.Exists(ctx, "guest", guestKey(eventID, name))
+		if err != nil {
+			return err
+		}
+		if guestExists {
+			return newError(ErrGuestExists, "guest with name %s is already on the guest list", name)
+		}
+
+		// A promoted entry whose guest was removed since can be replaced
+		var existing entity.WaitlistEntry
+		var replaced *entity.WaitlistEntry
+		err = tx.FindUniqueForUpdate(ctx, &existing, "waitlist", guestKey(eventID, name))
+		if err == nil {
+			if existing.Status == entity.WaitlistStatusWaiting {
+				return newError(ErrGuestExists, "guest with name %s is already on the waitlist", name)
+			}
+			err = tx.Delete(ctx, "waitlist", database.By("id", existing.ID))
+			if err != nil {
+				return err
+			}
+			replaced = &existing
+		} else if !errors.Is(err, database.ErrNotFound) {
+			return err
+		}
+
+		timeJoined := s.now()
+		columns := []string{"event_id", "name", "accompanying_guests", "table_id", "time_joined"}
+		values := []interface{}{eventID, name, request.AccompanyingGuests, request.Table, timeJoined}
+		id, err := tx.Create(ctx, "waitlist", columns, values...)
+		if errors.Is(err, database.ErrDuplicate) {
+			return newError(ErrGuestExists, "guest with name %s is already on the waitlist", name)
+		} else if err != nil {
+			return err
+		}
+
+		entry = entity.WaitlistEntry{
+			ID:                 id,
+			EventID:            eventID,
+			Name:               name,
+			AccompanyingGuests: request.AccompanyingGuests,
+			TableID:            request.Table,
+			Status:             entity.WaitlistStatusWaiting,
+			TimeJoined:         timeJoined,
+		}
+		err = s.auditWaitlist(ctx, tx, eventID, entity.AuditWaitlistJoined, replaced, &entry)
+		if err != nil {
+			return err
+		}
+
+		// Seat the party straight away when a table already has room
+		for i := range tables {
+			tablePromoted, err := s.promoteWaitlist(ctx, tx, &tables[i])
+			if err != nil {
+				return err
+			}
+			promoted = append(promoted, tablePromoted...)
+		}
+		for _, p := range promoted {
+			if p.ID == entry.ID {
+				entry = p
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	s.relayOutbox()
+	s.notifyPromotions(promoted)
+
+	return &entry, nil
+}
+
+func (s *service) GetWaitlist(ctx context.Context, eventID int) ([]entity.WaitlistEntry, error) {
+	entries := []entity.WaitlistEntry{}
+	query := eventQuery(eventID).OrderBy(database.Asc("id"))
+
+	err := s.dbClient.FindMany(ctx, &entries, "waitlist", query)
+	if err != nil {
+		return nil, err
+	}
+
+	return entries, nil
+}
+
+func (s *service) LeaveWaitlist(ctx context.Context, eventID int, name string) error {
+	return s.dbClient.Transaction(ctx, func(tx database.Tx) error {
+		var entry entity.WaitlistEntry
+		err := tx.FindUniqueForUpdate(ctx, &entry, "waitlist", guestKey(eventID, name))
+		if errors.Is(err, database.ErrNotFound) || (err == nil && entry.Status != entity.WaitlistStatusWaiting) {
+			return newError(ErrNotWaitlisted, "guest `%s` is not on the waitlist", name)
+		} else if err != nil {
+			return err
+		}
+
+		err = tx.Delete(ctx, "waitlist", database.By("id", entry.ID))
+		if err != nil {
+			return err
+		}
+
+		return s.auditWaitlist(ctx, tx, eventID, entity.AuditWaitlistLeft, &entry, nil)
+	})
+}
+
+// promoteWaitlist seats the waiting parties that fit in the free seats of
+// table, oldest first, and returns the promoted entries. The table must be
+// locked by tx and hold its current reserved seats.
+func (s *service) promoteWaitlist(ctx context.Context, tx database.Tx, table *entity.Table) ([]entity.WaitlistEntry, error) {
+	if table.ReservedSeats >= table.Capacity {
+		return nil, nil
+	}
+
+	entries := []entity.WaitlistEntry{}
+	query := eventQuery(table.EventID, database.Eq("status", entity.WaitlistStatusWaiting)).OrderBy(database.Asc("id"))
+	err := tx.FindMany(ctx, &entries, "waitlist", query)
+	if err != nil {
+		return nil, err
+	}
+
+	// The promoted entries as they were while waiting, for the audit log
+	var promoted, waiting []entity.WaitlistEntry
+	reservedSeats := table.ReservedSeats
+	for _, candidate := range entries {
+		if candidate.TableID != nil && *candidate.TableID != table.ID {
+			continue
+		}
+		if reservedSeats+candidate.AccompanyingGuests+1 > table.Capacity {
+			continue
+		}
+
+		// Re-read the entry under lock in case it left or was seated meanwhile
+		var entry entity.WaitlistEntry
+		err = tx.FindUniqueForUpdate(ctx, &entry, "waitlist", database.By("id", candidate.ID))
+		if errors.Is(err, database.ErrNotFound) {
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+		if entry.Status != entity.WaitlistStatusWaiting {
+			continue
+		}
+
+		// Guests added to the guest list directly keep their own seat and
+		// leave the waitlist
+		guestExists, err := tx.Exists(ctx, "guest", guestKey(entry.EventID, entry.Name))
+		if err != nil {
+			return nil, err
+		}
+		if guestExists {
+			err = tx.Delete(ctx, "waitlist", database.By("id", entry.ID))
+			if err != nil {
+				return nil, err
+			}
+			err = s.auditWaitlist(ctx, tx, entry.EventID, entity.AuditWaitlistLeft, &entry, nil)
+			if err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		columns := []string{"event_id", "name", "accompanying_guests", "table_id"}
+		values := []interface{}{entry.EventID, entry.Name, entry.AccompanyingGuests, table.ID}
+		_, err = tx.Create(ctx, "guest", columns, values...)
+		if err != nil {
+			return nil, err
+		}
+
+		timePromoted := s.now()
+		tableID := table.ID
+		columnsToUpdate := []string{"status", "table_id", "time_promoted"}
+		values = []interface{}{entity.WaitlistStatusPromoted, tableID, timePromoted}
+		err = tx.Update(ctx, "waitlist", database.By("id", entry.ID), columnsToUpdate, values...)
+		if err != nil {
+			return nil, err
+		}
+
+		waiting = append(waiting, entry)
+		entry.Status = entity.WaitlistStatusPromoted
+		entry.TableID = &tableID
+		entry.TimePromoted = &timePromoted
+		promoted = append(promoted, entry)
+		reservedSeats += entry.AccompanyingGuests + 1
+	}
+
+	if len(promoted) == 0 {
+		return nil, nil
+	}
+	before := *table
+	table.ReservedSeats = reservedSeats
+	err = updateReservedSeats(ctx, tx, table.ID, reservedSeats)
+	if err != nil {
+		return nil, err
+	}
+	err = s.auditReservedSeats(ctx, tx, before)
+	if err != nil {
+		return nil, err
+	}
+
+	// Promoted parties join the guest list like any added guest
+	for i := range promoted {
+		err = s.auditWaitlist(ctx, tx, table.EventID, entity.AuditWaitlistPromoted, &waiting[i], &promoted[i])
+		if err != nil {
+			return nil, err
+		}
+		err = s.auditGuest(ctx, tx, table.EventID, entity.AuditGuestAdded, promoted[i].Name, nil)
+		if err != nil {
+			return nil, err
+		}
+		err = s.record(ctx, tx, table.EventID, entity.StreamGuestAdded, &promoted[i].Name, promoted[i].TableID)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return promoted, nil
+}
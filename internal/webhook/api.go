@@ -0,0 +1,102 @@
+package webhook
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/getground/tech-tasks/backend/internal/entity"
+	"github.com/getground/tech-tasks/backend/pkg/validate"
+	"github.com/gorilla/mux"
+)
+
+// RegisterHandlers registers the routes managing the webhooks of the default
+// event and, under /events/{eventID}, of any event.
+func RegisterHandlers(r *mux.Router, service Service) {
+	h := handler{service}
+	registerEventRoutes(r, h)
+	registerEventRoutes(r.PathPrefix("/events/{eventID:[0-9]+}").Subrouter(), h)
+}
+
+func registerEventRoutes(r *mux.Router, h handler) {
+	r.HandleFunc("/webhooks", h.createWebhook).Methods(http.MethodPost)
+	r.HandleFunc("/webhooks", h.getWebhooks).Methods(http.MethodGet)
+	r.HandleFunc("/webhooks/{id:[0-9]+}", h.deleteWebhook).Methods(http.MethodDelete)
+	r.HandleFunc("/webhooks/{id:[0-9]+}/deliveries", h.getDeliveries).Methods(http.MethodGet)
+}
+
+type handler struct {
+	service Service
+}
+
+// eventID returns the event addressed by the request, falling back to the
+// default event on the unscoped routes.
+func eventID(r *http.Request) int {
+	id, err := strconv.Atoi(mux.Vars(r)["eventID"])
+	if err != nil {
+		return entity.DefaultEventID
+	}
+	return id
+}
+
+func (h handler) createWebhook(w http.ResponseWriter, r *http.Request) {
+	var requestBody entity.CreateWebhookRequestBody
+	err := validate.Decode(r, &requestBody)
+	if err != nil {
+		validate.Error(w, r, err)
+		return
+	}
+
+	webhook, err := h.service.CreateWebhook(r.Context(), eventID(r), &requestBody)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(webhook)
+}
+
+func (h handler) getWebhooks(w http.ResponseWriter, r *http.Request) {
+	webhooks, err := h.service.GetWebhooks(r.Context(), eventID(r))
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	responseBody := entity.GetWebhooksResponseBody{
+		Webhooks: webhooks,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(responseBody)
+}
+
+func (h handler) deleteWebhook(w http.ResponseWriter, r *http.Request) {
+	id, _ := strconv.Atoi(mux.Vars(r)["id"])
+
+	err := h.service.DeleteWebhook(r.Context(), eventID(r), id)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h handler) getDeliveries(w http.ResponseWriter, r *http.Request) {
+	id, _ := strconv.Atoi(mux.Vars(r)["id"])
+
+	deliveries, err := h.service.GetDeliveries(r.Context(), eventID(r), id)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	responseBody := entity.GetWebhookDeliveriesResponseBody{
+		Deliveries: deliveries,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(responseBody)
+}
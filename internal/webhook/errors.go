@@ -0,0 +1,56 @@
+package webhook
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+
+	"github.com/getground/tech-tasks/backend/pkg/problem"
+)
+
+var (
+	ErrEventNotFound   = errors.New("event not found")
+	ErrWebhookNotFound = errors.New("webhook not found")
+	ErrInvalidWebhook  = errors.New("invalid webhook")
+)
+
+// statusCodes maps each service error onto the HTTP status it is reported as.
+var statusCodes = map[error]int{
+	ErrEventNotFound:   http.StatusNotFound,
+	ErrWebhookNotFound: http.StatusNotFound,
+	ErrInvalidWebhook:  http.StatusUnprocessableEntity,
+}
+
+// serviceError carries a descriptive message while still matching one of the
+// sentinel errors above with errors.Is.
+type serviceError struct {
+	kind error
+	msg  string
+}
+
+func (e *serviceError) Error() string {
+	return e.msg
+}
+
+func (e *serviceError) Unwrap() error {
+	return e.kind
+}
+
+func newError(kind error, format string, args ...interface{}) error {
+	return &serviceError{kind, fmt.Sprintf(format, args...)}
+}
+
+// writeError responds with the problem details matching err. Unexpected
+// errors are logged and reported as a 500 without leaking their message.
+func writeError(w http.ResponseWriter, r *http.Request, err error) {
+	for kind, status := range statusCodes {
+		if errors.Is(err, kind) {
+			problem.Error(w, r, status, err.Error())
+			return
+		}
+	}
+
+	log.Printf("Error %s when handling %s %s", err, r.Method, r.URL.Path)
+	problem.Error(w, r, http.StatusInternalServerError, "internal server error")
+}
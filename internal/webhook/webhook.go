@@ -0,0 +1,219 @@
+// Package webhook delivers the stream events of the guest list to the URLs
+// subscribed to them.
+package webhook
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/url"
+	"time"
+
+	"github.com/getground/tech-tasks/backend/internal/entity"
+	"github.com/getground/tech-tasks/backend/pkg/database"
+)
+
+// minSecretLength keeps signatures from being guessed.
+const minSecretLength = 16
+
+type Service interface {
+	CreateWebhook(ctx context.Context, eventID int, request *entity.CreateWebhookRequestBody) (*entity.CreateWebhookResponseBody, error)
+	GetWebhooks(ctx context.Context, eventID int) ([]entity.Webhook, error)
+	DeleteWebhook(ctx context.Context, eventID int, id int) error
+	GetDeliveries(ctx context.Context, eventID int, id int) ([]entity.WebhookDeliveryElement, error)
+	Enqueue(ctx context.Context, event entity.StreamEvent) error
+}
+
+// Clock tells the current time. Tests replace it to control when deliveries
+// are due.
+type Clock interface {
+	Now() time.Time
+}
+
+type systemClock struct{}
+
+func (systemClock) Now() time.Time {
+	return time.Now()
+}
+
+type service struct {
+	dbClient database.Client
+	clock    Clock
+}
+
+// Option configures the service returned by NewService.
+type Option func(*service)
+
+// WithClock makes the service read the current time from clock.
+func WithClock(clock Clock) Option {
+	return func(s *service) {
+		s.clock = clock
+	}
+}
+
+func NewService(dbClient database.Client, opts ...Option) Service {
+	s := &service{dbClient: dbClient, clock: systemClock{}}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+func (s *service) CreateWebhook(ctx context.Context, eventID int, request *entity.CreateWebhookRequestBody) (*entity.CreateWebhookResponseBody, error) {
+	err := validateWebhook(request)
+	if err != nil {
+		return nil, err
+	}
+
+	var secret string
+	if request.Secret != nil {
+		secret = *request.Secret
+	} else {
+		secret, err = generateSecret()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	webhook := entity.Webhook{
+		EventID:     eventID,
+		URL:         request.URL,
+		Secret:      secret,
+		EventTypes:  request.EventTypes,
+		TimeCreated: now(s.clock),
+	}
+	columns := []string{"event_id", "url", "secret", "event_types", "time_created"}
+	values := []interface{}{eventID, webhook.URL, webhook.Secret, webhook.EventTypes, webhook.TimeCreated}
+	webhook.ID, err = s.dbClient.Create(ctx, "webhook", columns, values...)
+	if errors.Is(err, database.ErrForeignKey) {
+		return nil, newError(ErrEventNotFound, "found no event with id %d", eventID)
+	} else if err != nil {
+		return nil, err
+	}
+
+	return &entity.CreateWebhookResponseBody{Webhook: webhook, Secret: secret}, nil
+}
+
+func validateWebhook(request *entity.CreateWebhookRequestBody) error {
+	u, err := url.Parse(request.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return newError(ErrInvalidWebhook, "url must be an absolute http or https URL")
+	}
+	if request.Secret != nil && len(*request.Secret) < minSecretLength {
+		return newError(ErrInvalidWebhook, "secret must be at least %d characters long", minSecretLength)
+	}
+
+	if len(request.EventTypes) == 0 {
+		return newError(ErrInvalidWebhook, "event_types must name at least one event type")
+	}
+	for _, eventType := range request.EventTypes {
+		if !entity.Attributes(entity.StreamEventTypes).Has(eventType) {
+			return newError(ErrInvalidWebhook, "unknown event type `%s`", eventType)
+		}
+	}
+	return nil
+}
+
+func generateSecret() (string, error) {
+	b := make([]byte, 32)
+	_, err := rand.Read(b)
+	if err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+func (s *service) GetWebhooks(ctx context.Context, eventID int) ([]entity.Webhook, error) {
+	webhooks := []entity.Webhook{}
+	query := database.Where(database.Eq("event_id", eventID)).OrderBy(database.Asc("id"))
+	err := s.dbClient.FindMany(ctx, &webhooks, "webhook", query)
+	if err != nil {
+		return nil, err
+	}
+
+	return webhooks, nil
+}
+
+func (s *service) DeleteWebhook(ctx context.Context, eventID int, id int) error {
+	key := database.Key{"event_id": eventID, "id": id}
+	exists, err := s.dbClient.Exists(ctx, "webhook", key)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return newError(ErrWebhookNotFound, "found no webhook with id %d", id)
+	}
+
+	return s.dbClient.Delete(ctx, "webhook", key)
+}
+
+// GetDeliveries returns the deliveries of a webhook, newest first, along with
+// their attempts.
+func (s *service) GetDeliveries(ctx context.Context, eventID int, id int) ([]entity.WebhookDeliveryElement, error) {
+	exists, err := s.dbClient.Exists(ctx, "webhook", database.Key{"event_id": eventID, "id": id})
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, newError(ErrWebhookNotFound, "found no webhook with id %d", id)
+	}
+
+	deliveries := []entity.WebhookDelivery{}
+	query := database.Where(database.Eq("webhook_id", id)).OrderBy(database.Desc("id"))
+	err = s.dbClient.FindMany(ctx, &deliveries, "webhook_delivery", query)
+	if err != nil {
+		return nil, err
+	}
+
+	elements := make([]entity.WebhookDeliveryElement, 0, len(deliveries))
+	for _, delivery := range deliveries {
+		attempts := []entity.WebhookAttempt{}
+		query := database.Where(database.Eq("delivery_id", delivery.ID)).OrderBy(database.Asc("id"))
+		err = s.dbClient.FindMany(ctx, &attempts, "webhook_attempt", query)
+		if err != nil {
+			return nil, err
+		}
+
+		elements = append(elements, entity.WebhookDeliveryElement{WebhookDelivery: delivery, AttemptLog: attempts})
+	}
+	return elements, nil
+}
+
+// Enqueue queues a delivery of event to every webhook of its event
+// subscribed to its type. The Worker sends them.
+func (s *service) Enqueue(ctx context.Context, event entity.StreamEvent) error {
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	return s.dbClient.Transaction(ctx, func(tx database.Tx) error {
+		webhooks := []entity.Webhook{}
+		query := database.Where(database.Eq("event_id", event.EventID))
+		err := tx.FindMany(ctx, &webhooks, "webhook", query)
+		if err != nil {
+			return err
+		}
+
+		timeCreated := now(s.clock)
+		for _, webhook := range webhooks {
+			if !webhook.EventTypes.Has(event.Type) {
+				continue
+			}
+			columns := []string{"webhook_id", "event_type", "payload", "next_attempt", "time_created"}
+			values := []interface{}{webhook.ID, event.Type, string(payload), timeCreated, timeCreated}
+			_, err = tx.Create(ctx, "webhook_delivery", columns, values...)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// now returns the current time at the precision stored in DATETIME columns.
+func now(clock Clock) time.Time {
+	return clock.Now().UTC().Truncate(time.Second)
+}
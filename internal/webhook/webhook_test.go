@@ -0,0 +1,174 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"net/http"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/getground/tech-tasks/backend/internal/entity"
+	"github.com/getground/tech-tasks/backend/internal/test"
+	"github.com/getground/tech-tasks/backend/pkg/database"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+)
+
+var ctx = context.Background()
+
+// newTestClient connects to the MySQL database in TEST_MYSQL_DSN when set and
+// falls back to the in-memory backend otherwise.
+func newTestClient() database.Client {
+	if dsn := os.Getenv("TEST_MYSQL_DSN"); dsn != "" {
+		dbClient, err := database.NewClient(dsn)
+		if err != nil {
+			log.Fatalf("Error while connecting to the DB, %v", err)
+		}
+		return dbClient
+	}
+	return database.NewMemoryClient()
+}
+
+func cleanupWebhooks(dbClient database.Client) {
+	for _, table := range []string{"webhook_attempt", "webhook_delivery", "webhook"} {
+		err := dbClient.DeleteAll(ctx, table)
+		if err != nil {
+			log.Fatalf("Error while cleaning table %s, %v", table, err)
+		}
+	}
+}
+
+// testClock reports a time tests move forward by hand.
+type testClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func (c *testClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *testClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+}
+
+// mustField returns the raw JSON of field in payload.
+func mustField(t *testing.T, payload []byte, field string) json.RawMessage {
+	var fields map[string]json.RawMessage
+	err := json.Unmarshal(payload, &fields)
+	assert.Nil(t, err, "Error while decoding payload, %v", err)
+	return fields[field]
+}
+
+func TestWebhooks(t *testing.T) {
+	dbClient := newTestClient()
+	defer dbClient.Close()
+	cleanupWebhooks(dbClient)
+
+	webhookService := NewService(dbClient)
+
+	// Test creating a webhook generates a secret when none is given
+	created, err := webhookService.CreateWebhook(ctx, entity.DefaultEventID, &entity.CreateWebhookRequestBody{
+		URL:        "https://crm.example.com/hooks",
+		EventTypes: entity.EventTypes{entity.StreamGuestAdded},
+	})
+	assert.Nil(t, err, "Error while creating webhook, %v", err)
+	assert.Equal(t, 64, len(created.Secret))
+
+	// Test invalid webhooks are refused
+	secret := "short"
+	invalid := []entity.CreateWebhookRequestBody{
+		{URL: "ftp://crm.example.com", EventTypes: entity.EventTypes{entity.StreamGuestAdded}},
+		{URL: "https://crm.example.com", EventTypes: entity.EventTypes{"guest_danced"}},
+		{URL: "https://crm.example.com"},
+		{URL: "https://crm.example.com", Secret: &secret, EventTypes: entity.EventTypes{entity.StreamGuestAdded}},
+	}
+	for _, request := range invalid {
+		_, err = webhookService.CreateWebhook(ctx, entity.DefaultEventID, &request)
+		assert.ErrorIs(t, err, ErrInvalidWebhook)
+	}
+	_, err = webhookService.CreateWebhook(ctx, 999, &entity.CreateWebhookRequestBody{
+		URL:        "https://crm.example.com/hooks",
+		EventTypes: entity.EventTypes{entity.StreamGuestAdded},
+	})
+	assert.ErrorIs(t, err, ErrEventNotFound)
+
+	// Test only subscribed event types are queued
+	err = webhookService.Enqueue(ctx, entity.StreamEvent{ID: 1, Type: entity.StreamGuestAdded, EventID: entity.DefaultEventID})
+	assert.Nil(t, err, "Error while queueing event, %v", err)
+	err = webhookService.Enqueue(ctx, entity.StreamEvent{ID: 2, Type: entity.StreamTableChanged, EventID: entity.DefaultEventID})
+	assert.Nil(t, err, "Error while queueing event, %v", err)
+
+	deliveries, err := webhookService.GetDeliveries(ctx, entity.DefaultEventID, created.ID)
+	assert.Nil(t, err, "Error while getting deliveries, %v", err)
+	assert.Equal(t, 1, len(deliveries))
+	assert.Equal(t, entity.DeliveryStatusPending, deliveries[0].Status)
+	assert.JSONEq(t, `"guest_added"`, string(mustField(t, deliveries[0].Payload, "type")))
+
+	webhooks, err := webhookService.GetWebhooks(ctx, entity.DefaultEventID)
+	assert.Nil(t, err, "Error while getting webhooks, %v", err)
+	assert.Equal(t, 1, len(webhooks))
+
+	err = webhookService.DeleteWebhook(ctx, entity.DefaultEventID, created.ID)
+	assert.Nil(t, err, "Error while deleting webhook, %v", err)
+	err = webhookService.DeleteWebhook(ctx, entity.DefaultEventID, created.ID)
+	assert.ErrorIs(t, err, ErrWebhookNotFound)
+}
+
+func TestAPI(t *testing.T) {
+	dbClient := newTestClient()
+	defer dbClient.Close()
+	cleanupWebhooks(dbClient)
+
+	r := mux.NewRouter()
+	RegisterHandlers(r, NewService(dbClient))
+
+	tests := []test.APITestCase{
+		{
+			Name:   "Create webhook",
+			Method: "POST",
+			URL:    "/webhooks",
+			Body: entity.CreateWebhookRequestBody{
+				URL:        "https://catering.example.com/hooks",
+				EventTypes: entity.EventTypes{entity.StreamGuestCheckedIn, entity.StreamGuestCheckedOut},
+			},
+			ExpectedStatus: http.StatusOK,
+			ExpectedResponse: map[string]interface{}{
+				"url":      "https://catering.example.com/hooks",
+				"event_id": entity.DefaultEventID,
+			},
+		},
+		{
+			Name:   "Create invalid webhook",
+			Method: "POST",
+			URL:    "/webhooks",
+			Body: entity.CreateWebhookRequestBody{
+				URL: "https://catering.example.com/hooks",
+			},
+			ExpectedStatus: http.StatusBadRequest,
+		},
+		{
+			Name:           "Get deliveries of unknown webhook",
+			Method:         "GET",
+			URL:            "/webhooks/999/deliveries",
+			ExpectedStatus: http.StatusNotFound,
+		},
+		{
+			Name:           "Delete unknown webhook",
+			Method:         "DELETE",
+			URL:            "/events/1/webhooks/999",
+			ExpectedStatus: http.StatusNotFound,
+		},
+	}
+
+	for _, tc := range tests {
+		test.Endpoint(t, r, tc)
+	}
+}
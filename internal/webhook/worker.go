@@ -0,0 +1,297 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/getground/tech-tasks/backend/internal/entity"
+	"github.com/getground/tech-tasks/backend/internal/guest_list"
+	"github.com/getground/tech-tasks/backend/pkg/database"
+)
+
+// Defaults of the Worker, which gives up on a delivery after about a day.
+const (
+	DefaultMaxAttempts  = 12
+	DefaultBackoff      = 30 * time.Second
+	DefaultMaxBackoff   = 6 * time.Hour
+	DefaultPollInterval = 5 * time.Second
+	DefaultTimeout      = 10 * time.Second
+)
+
+// Headers sent along with every delivery besides the SignatureHeader.
+const (
+	EventHeader    = "X-Webhook-Event"
+	DeliveryHeader = "X-Webhook-Delivery"
+)
+
+// maxErrorLength keeps recorded errors within their column.
+const maxErrorLength = 1024
+
+// Worker sends the queued deliveries, retrying failed ones with exponential
+// backoff. Deliveries are sent at least once, receivers can tell retries
+// apart by the DeliveryHeader.
+type Worker struct {
+	dbClient     database.Client
+	client       *http.Client
+	clock        Clock
+	maxAttempts  int
+	backoff      time.Duration
+	maxBackoff   time.Duration
+	pollInterval time.Duration
+	wake         chan struct{}
+}
+
+// WorkerOption configures the Worker returned by NewWorker.
+type WorkerOption func(*Worker)
+
+// WithHTTPClient makes the worker send deliveries with client.
+func WithHTTPClient(client *http.Client) WorkerOption {
+	return func(w *Worker) {
+		w.client = client
+	}
+}
+
+// WithRetries makes the worker try each delivery up to maxAttempts times,
+// waiting backoff after the first failure and doubling the wait after each
+// further one up to maxBackoff.
+func WithRetries(maxAttempts int, backoff time.Duration, maxBackoff time.Duration) WorkerOption {
+	return func(w *Worker) {
+		w.maxAttempts = maxAttempts
+		w.backoff = backoff
+		w.maxBackoff = maxBackoff
+	}
+}
+
+// WithPollInterval sets how often the worker looks for due deliveries when
+// it isn't notified of new ones.
+func WithPollInterval(interval time.Duration) WorkerOption {
+	return func(w *Worker) {
+		w.pollInterval = interval
+	}
+}
+
+// WithWorkerClock makes the worker read the current time from clock.
+func WithWorkerClock(clock Clock) WorkerOption {
+	return func(w *Worker) {
+		w.clock = clock
+	}
+}
+
+func NewWorker(dbClient database.Client, opts ...WorkerOption) *Worker {
+	w := &Worker{
+		dbClient:     dbClient,
+		client:       &http.Client{Timeout: DefaultTimeout},
+		clock:        systemClock{},
+		maxAttempts:  DefaultMaxAttempts,
+		backoff:      DefaultBackoff,
+		maxBackoff:   DefaultMaxBackoff,
+		pollInterval: DefaultPollInterval,
+		wake:         make(chan struct{}, 1),
+	}
+	for _, opt := range opts {
+		opt(w)
+	}
+	return w
+}
+
+// Notify wakes the worker up to send newly queued deliveries.
+func (w *Worker) Notify() {
+	select {
+	case w.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Run sends due deliveries until ctx is done.
+func (w *Worker) Run(ctx context.Context) {
+	ticker := time.NewTicker(w.pollInterval)
+	defer ticker.Stop()
+
+	for {
+		_, err := w.DeliverDue(ctx)
+		if err != nil && ctx.Err() == nil {
+			log.Printf("Error %s when delivering webhooks", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-w.wake:
+		}
+	}
+}
+
+// DeliverDue makes one attempt at every pending delivery that is due and
+// returns how many it attempted.
+func (w *Worker) DeliverDue(ctx context.Context) (int, error) {
+	deliveries := []entity.WebhookDelivery{}
+	query := database.Where(database.Eq("status", entity.DeliveryStatusPending)).OrderBy(database.Asc("id"))
+	err := w.dbClient.FindMany(ctx, &deliveries, "webhook_delivery", query)
+	if err != nil {
+		return 0, err
+	}
+
+	attempted := 0
+	for _, delivery := range deliveries {
+		if delivery.NextAttempt == nil || delivery.NextAttempt.After(w.clock.Now()) {
+			continue
+		}
+
+		var webhook entity.Webhook
+		claimed, err := w.claim(ctx, &delivery, &webhook)
+		if err != nil {
+			return attempted, err
+		}
+		if !claimed {
+			continue
+		}
+
+		err = w.attempt(ctx, delivery, webhook)
+		if err != nil {
+			return attempted, err
+		}
+		attempted++
+	}
+	return attempted, nil
+}
+
+// claim locks delivery and pushes its next attempt past the time sending it
+// can take, so other workers leave it alone and a crashed worker's delivery
+// is retried. It reports false when the delivery is no longer due.
+func (w *Worker) claim(ctx context.Context, delivery *entity.WebhookDelivery, webhook *entity.Webhook) (bool, error) {
+	claimed := false
+	err := w.dbClient.Transaction(ctx, func(tx database.Tx) error {
+		err := tx.FindUniqueForUpdate(ctx, delivery, "webhook_delivery", database.By("id", delivery.ID))
+		if errors.Is(err, database.ErrNotFound) {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		if delivery.Status != entity.DeliveryStatusPending || delivery.NextAttempt == nil || delivery.NextAttempt.After(w.clock.Now()) {
+			return nil
+		}
+
+		err = tx.FindUnique(ctx, webhook, "webhook", database.By("id", delivery.WebhookID))
+		if err != nil {
+			return err
+		}
+
+		lease := now(w.clock).Add(2*w.client.Timeout + time.Minute)
+		err = tx.Update(ctx, "webhook_delivery", database.By("id", delivery.ID), []string{"next_attempt"}, lease)
+		if err != nil {
+			return err
+		}
+		claimed = true
+		return nil
+	})
+	return claimed, err
+}
+
+// attempt sends delivery once and records the outcome.
+func (w *Worker) attempt(ctx context.Context, delivery entity.WebhookDelivery, webhook entity.Webhook) error {
+	started := w.clock.Now()
+	statusCode, sendErr := w.send(ctx, delivery, webhook)
+	if ctx.Err() != nil {
+		// Shutting down, the lease brings the delivery back later
+		return ctx.Err()
+	}
+	duration := w.clock.Now().Sub(started)
+
+	var errorMessage *string
+	if sendErr != nil {
+		msg := sendErr.Error()
+		if len(msg) > maxErrorLength {
+			msg = msg[:maxErrorLength]
+		}
+		errorMessage = &msg
+	}
+
+	attempts := delivery.Attempts + 1
+	timeAttempted := now(w.clock)
+	status := entity.DeliveryStatusPending
+	var nextAttempt, timeDelivered *time.Time
+	switch {
+	case sendErr == nil:
+		status = entity.DeliveryStatusDelivered
+		timeDelivered = &timeAttempted
+	case attempts >= w.maxAttempts:
+		status = entity.DeliveryStatusFailed
+	default:
+		next := timeAttempted.Add(w.backoffAfter(attempts))
+		nextAttempt = &next
+	}
+
+	return w.dbClient.Transaction(ctx, func(tx database.Tx) error {
+		columns := []string{"delivery_id", "status_code", "error", "duration_ms", "time_attempted"}
+		values := []interface{}{delivery.ID, statusCode, errorMessage, int(duration / time.Millisecond), timeAttempted}
+		_, err := tx.Create(ctx, "webhook_attempt", columns, values...)
+		if err != nil {
+			return err
+		}
+
+		columnsToUpdate := []string{"status", "attempts", "next_attempt", "time_delivered"}
+		values = []interface{}{status, attempts, nextAttempt, timeDelivered}
+		return tx.Update(ctx, "webhook_delivery", database.By("id", delivery.ID), columnsToUpdate, values...)
+	})
+}
+
+// send posts the signed payload of delivery and returns the status code of
+// the response, or nil when there was none. Any status outside 2xx fails.
+func (w *Worker) send(ctx context.Context, delivery entity.WebhookDelivery, webhook entity.Webhook) (*int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, webhook.URL, bytes.NewReader(delivery.Payload))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(EventHeader, delivery.EventType)
+	req.Header.Set(DeliveryHeader, strconv.Itoa(delivery.ID))
+	req.Header.Set(SignatureHeader, Sign(webhook.Secret, w.clock.Now(), delivery.Payload))
+
+	res, err := w.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+	// Drain a little of the body so the connection can be reused
+	io.Copy(ioutil.Discard, io.LimitReader(res.Body, 4096))
+
+	statusCode := res.StatusCode
+	if statusCode < 200 || statusCode > 299 {
+		return &statusCode, fmt.Errorf("receiver responded with %d", statusCode)
+	}
+	return &statusCode, nil
+}
+
+// backoffAfter returns the wait after the given number of failed attempts.
+func (w *Worker) backoffAfter(attempts int) time.Duration {
+	wait := w.backoff
+	for i := 1; i < attempts; i++ {
+		wait *= 2
+		if wait >= w.maxBackoff {
+			return w.maxBackoff
+		}
+	}
+	return wait
+}
+
+// NewSink returns an outbox sink queueing the events for the webhooks
+// subscribed to them and waking worker up to send them.
+func NewSink(service Service, worker *Worker) guest_list.Sink {
+	return guest_list.NewSink("webhook", func(ctx context.Context, event entity.StreamEvent) error {
+		err := service.Enqueue(ctx, event)
+		if err != nil {
+			return err
+		}
+		worker.Notify()
+		return nil
+	})
+}
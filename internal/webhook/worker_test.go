@@ -0,0 +1,119 @@
+package webhook
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/getground/tech-tasks/backend/internal/entity"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSign(t *testing.T) {
+	sentAt := time.Unix(1700000000, 0)
+	body := []byte(`{"type":"guest_added"}`)
+	header := Sign("0123456789abcdef", sentAt, body)
+
+	assert.Nil(t, Verify("0123456789abcdef", header, body, sentAt.Add(time.Minute), 5*time.Minute))
+	assert.NotNil(t, Verify("fedcba9876543210", header, body, sentAt, 5*time.Minute))
+	assert.NotNil(t, Verify("0123456789abcdef", header, []byte(`{}`), sentAt, 5*time.Minute))
+	assert.NotNil(t, Verify("0123456789abcdef", header, body, sentAt.Add(time.Hour), 5*time.Minute))
+	assert.NotNil(t, Verify("0123456789abcdef", "v1=abc", body, sentAt, 5*time.Minute))
+}
+
+func TestWorker(t *testing.T) {
+	dbClient := newTestClient()
+	defer dbClient.Close()
+	cleanupWebhooks(dbClient)
+
+	clock := &testClock{now: time.Date(2024, 6, 1, 18, 0, 0, 0, time.UTC)}
+	webhookService := NewService(dbClient, WithClock(clock))
+	worker := NewWorker(dbClient, WithWorkerClock(clock), WithRetries(3, time.Minute, time.Hour))
+
+	// The receiver fails the first request and verifies the signature of the
+	// others
+	var mu sync.Mutex
+	var requests int
+	var verifyErr error
+	var secret string
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		requests++
+		if requests == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		body, _ := ioutil.ReadAll(r.Body)
+		verifyErr = Verify(secret, r.Header.Get(SignatureHeader), body, clock.Now(), time.Minute)
+		assert.Equal(t, entity.StreamGuestCheckedIn, r.Header.Get(EventHeader))
+	}))
+	defer receiver.Close()
+
+	created, err := webhookService.CreateWebhook(ctx, entity.DefaultEventID, &entity.CreateWebhookRequestBody{
+		URL:        receiver.URL,
+		EventTypes: entity.EventTypes{entity.StreamGuestCheckedIn},
+	})
+	assert.Nil(t, err, "Error while creating webhook, %v", err)
+	secret = created.Secret
+
+	err = webhookService.Enqueue(ctx, entity.StreamEvent{ID: 1, Type: entity.StreamGuestCheckedIn, EventID: entity.DefaultEventID})
+	assert.Nil(t, err, "Error while queueing event, %v", err)
+
+	// Test a failed attempt is retried after the backoff
+	attempted, err := worker.DeliverDue(ctx)
+	assert.Nil(t, err, "Error while delivering, %v", err)
+	assert.Equal(t, 1, attempted)
+
+	deliveries, err := webhookService.GetDeliveries(ctx, entity.DefaultEventID, created.ID)
+	assert.Nil(t, err, "Error while getting deliveries, %v", err)
+	assert.Equal(t, entity.DeliveryStatusPending, deliveries[0].Status)
+	assert.Equal(t, clock.Now().Add(time.Minute), *deliveries[0].NextAttempt)
+	assert.Equal(t, http.StatusServiceUnavailable, *deliveries[0].AttemptLog[0].StatusCode)
+
+	attempted, err = worker.DeliverDue(ctx)
+	assert.Nil(t, err, "Error while delivering, %v", err)
+	assert.Equal(t, 0, attempted)
+
+	clock.Advance(time.Minute)
+	attempted, err = worker.DeliverDue(ctx)
+	assert.Nil(t, err, "Error while delivering, %v", err)
+	assert.Equal(t, 1, attempted)
+	assert.Nil(t, verifyErr, "Error while verifying signature, %v", verifyErr)
+
+	deliveries, err = webhookService.GetDeliveries(ctx, entity.DefaultEventID, created.ID)
+	assert.Nil(t, err, "Error while getting deliveries, %v", err)
+	assert.Equal(t, entity.DeliveryStatusDelivered, deliveries[0].Status)
+	assert.Equal(t, 2, deliveries[0].Attempts)
+	assert.Equal(t, 2, len(deliveries[0].AttemptLog))
+	assert.Nil(t, deliveries[0].NextAttempt)
+
+	// Test deliveries fail for good once out of attempts
+	receiver.Close()
+	err = webhookService.Enqueue(ctx, entity.StreamEvent{ID: 2, Type: entity.StreamGuestCheckedIn, EventID: entity.DefaultEventID})
+	assert.Nil(t, err, "Error while queueing event, %v", err)
+	for _, wait := range []time.Duration{0, time.Minute, 2 * time.Minute} {
+		clock.Advance(wait)
+		attempted, err = worker.DeliverDue(ctx)
+		assert.Nil(t, err, "Error while delivering, %v", err)
+		assert.Equal(t, 1, attempted)
+	}
+
+	deliveries, err = webhookService.GetDeliveries(ctx, entity.DefaultEventID, created.ID)
+	assert.Nil(t, err, "Error while getting deliveries, %v", err)
+	assert.Equal(t, entity.DeliveryStatusFailed, deliveries[0].Status)
+	assert.Nil(t, deliveries[0].AttemptLog[2].StatusCode)
+	assert.NotNil(t, deliveries[0].AttemptLog[2].Error)
+}
+
+func TestBackoff(t *testing.T) {
+	worker := NewWorker(nil, WithRetries(10, time.Second, 5*time.Second))
+	var waits []time.Duration
+	for attempts := 1; attempts <= 4; attempts++ {
+		waits = append(waits, worker.backoffAfter(attempts))
+	}
+	assert.Equal(t, []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 5 * time.Second}, waits)
+}
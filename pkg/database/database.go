@@ -8,41 +8,108 @@ import (
 	"strings"
 	"time"
 
-	_ "github.com/go-sql-driver/mysql"
+	"github.com/go-sql-driver/mysql"
 	"github.com/jmoiron/sqlx"
 )
 
+// DefaultQueryTimeout is the deadline applied to every query when no other
+// timeout is configured with WithQueryTimeout.
+const DefaultQueryTimeout = 5 * time.Second
+
+// Queryer groups the row level operations that can run either directly
+// against the database or inside a transaction.
+type Queryer interface {
+	Create(ctx context.Context, tableName string, columns []string, values ...interface{}) (int, error)
+	Update(ctx context.Context, tableName string, key Key, columns []string, values ...interface{}) error
+	Exists(ctx context.Context, tableName string, key Key) (bool, error)
+	FindUnique(ctx context.Context, resultStruct interface{}, tableName string, key Key) error
+	// FindUniqueForUpdate behaves like FindUnique but locks the selected row
+	// until the surrounding transaction ends.
+	FindUniqueForUpdate(ctx context.Context, resultStruct interface{}, tableName string, key Key) error
+	// FindMany loads the rows selected by query into resultStruct, a pointer
+	// to a slice.
+	FindMany(ctx context.Context, resultStruct interface{}, tableName string, query Query) error
+	Delete(ctx context.Context, tableName string, key Key) error
+	DeleteAll(ctx context.Context, tableName string) error
+}
+
 type Client interface {
+	Queryer
 	Close()
-	Create(tableName string, columns []string, values ...interface{}) (int, error)
-	Update(
-		tableName string,
-		uniqueFieldName string,
-		uniqueFieldValue interface{},
-		columns []string,
-		values ...interface{}) error
-	Exists(tableName string, uniqueFiledName string, uniqueFieldValue interface{}) (bool, error)
-	FindUnique(resultStruct interface{}, tableName string, uniqueFiledName string, uniqueFieldValue interface{}) error
-	FindMany(resultStruct interface{}, tableName string, condition *string, limit *int) error
-	Delete(tableName string, uniqueFieldName string, uniqueFieldValue interface{}) error
-	DeleteAll(tableName string) error
-	GetDB() *sqlx.DB
+	Begin(ctx context.Context) (Tx, error)
+	// Transaction runs fn inside a transaction which is committed when fn
+	// returns nil and rolled back otherwise.
+	Transaction(ctx context.Context, fn func(tx Tx) error) error
+}
+
+type Tx interface {
+	Queryer
+	Commit() error
+	Rollback() error
+}
+
+// Option configures a Client created by NewClient.
+type Option func(*options)
+
+type options struct {
+	queryTimeout time.Duration
+}
+
+// WithQueryTimeout sets the deadline applied to each query. Deadlines already
+// present on the caller's context still apply when they are shorter.
+func WithQueryTimeout(timeout time.Duration) Option {
+	return func(o *options) {
+		o.queryTimeout = timeout
+	}
+}
+
+// executor is implemented by both *sqlx.DB and *sqlx.Tx.
+type executor interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+	GetContext(ctx context.Context, dest interface{}, query string, args ...interface{}) error
+	SelectContext(ctx context.Context, dest interface{}, query string, args ...interface{}) error
+}
+
+type queryer struct {
+	ext     executor
+	timeout time.Duration
 }
 
 type client struct {
+	queryer
 	db *sqlx.DB
 }
 
-func NewClient(dsn string) (Client, error) {
-	db, err := connect(dsn)
+type tx struct {
+	queryer
+	tx *sqlx.Tx
+}
+
+func NewClient(dsn string, opts ...Option) (Client, error) {
+	o := options{queryTimeout: DefaultQueryTimeout}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	db, err := connect(dsn, o.queryTimeout)
 	if err != nil {
 		return nil, err
 	}
-	return &client{db}, nil
+	udb := db.Unsafe()
+	return &client{queryer{udb, o.queryTimeout}, udb}, nil
 }
 
-func connect(dsn string) (*sqlx.DB, error) {
-	db, err := sqlx.Open("mysql", dsn)
+func connect(dsn string, timeout time.Duration) (*sqlx.DB, error) {
+	// DATETIME columns are scanned into time.Time
+	config, err := mysql.ParseDSN(dsn)
+	if err != nil {
+		log.Printf("Error %s when parsing DSN\n", err)
+		return nil, err
+	}
+	config.ParseTime = true
+
+	db, err := sqlx.Open("mysql", config.FormatDSN())
 	if err != nil {
 		log.Printf("Error %s when opening DB\n", err)
 		return nil, err
@@ -52,7 +119,7 @@ func connect(dsn string) (*sqlx.DB, error) {
 	db.SetMaxIdleConns(5)
 	db.SetConnMaxLifetime(time.Minute * 5)
 
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
 	defer cancel()
 	err = db.PingContext(ctx)
 	if err != nil {
@@ -64,36 +131,92 @@ func connect(dsn string) (*sqlx.DB, error) {
 	return db, nil
 }
 
-// Should only be used to execute raw queries in services
-func (c *client) GetDB() *sqlx.DB {
-	return c.db
-}
-
 func (c *client) Close() {
 	if c.db != nil {
 		c.db.Close()
 	}
 }
 
-func (c *client) Create(tableName string, columns []string, values ...interface{}) (int, error) {
-	placeholders := make([]string, len(columns))
-	for i := range columns {
-		placeholders[i] = "?"
+func (c *client) Begin(ctx context.Context) (Tx, error) {
+	t, err := c.db.BeginTxx(ctx, nil)
+	if err != nil {
+		log.Printf("Error %s when starting transaction", err)
+		return nil, err
+	}
+	return &tx{queryer{t, c.timeout}, t}, nil
+}
+
+func (c *client) Transaction(ctx context.Context, fn func(tx Tx) error) (err error) {
+	t, err := c.Begin(ctx)
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		if p := recover(); p != nil {
+			t.Rollback()
+			panic(p)
+		}
+	}()
+
+	if err = fn(t); err != nil {
+		if rbErr := t.Rollback(); rbErr != nil {
+			log.Printf("Error %s when rolling back transaction", rbErr)
+		}
+		return err
+	}
+
+	return t.Commit()
+}
+
+func (t *tx) Commit() error {
+	if err := t.tx.Commit(); err != nil {
+		log.Printf("Error %s when committing transaction", err)
+		return err
+	}
+	return nil
+}
+
+func (t *tx) Rollback() error {
+	return t.tx.Rollback()
+}
+
+// withTimeout derives the context used for a single query.
+func (q *queryer) withTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	return context.WithTimeout(ctx, q.timeout)
+}
+
+func (q *queryer) Create(ctx context.Context, tableName string, columns []string, values ...interface{}) (int, error) {
+	stmt, err := newStatement(tableName)
+	if err != nil {
+		return 0, err
+	}
+	quoted, err := stmt.columns(columns...)
+	if err != nil {
+		return 0, err
+	}
+	if len(columns) != len(values) {
+		return 0, fmt.Errorf("%w: %d values for %d columns", ErrInvalidQuery, len(values), len(columns))
+	}
+
+	placeholders := make([]string, len(values))
+	for i, value := range values {
+		placeholders[i] = stmt.bind(value)
 	}
 
 	query := fmt.Sprintf(
-		"INSERT INTO `%s` (%s) VALUES (%s)",
-		tableName,
-		strings.Join(columns, ", "),
+		"INSERT INTO %s (%s) VALUES (%s)",
+		stmt.tableSQL(),
+		strings.Join(quoted, ", "),
 		strings.Join(placeholders, ","))
 
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	ctx, cancel := q.withTimeout(ctx)
 	defer cancel()
 
-	res, err := c.db.ExecContext(ctx, query, values...)
+	res, err := q.ext.ExecContext(ctx, query, stmt.args...)
 	if err != nil {
 		log.Printf("Error %s when inserting row into table", err)
-		return 0, err
+		return 0, translateError(err)
 	}
 
 	id, err := res.LastInsertId()
@@ -106,32 +229,45 @@ func (c *client) Create(tableName string, columns []string, values ...interface{
 	return int(id), nil
 }
 
-func (c *client) Update(
-	tableName string,
-	uniqueFieldName string,
-	uniqueFieldValue interface{},
-	columns []string,
-	values ...interface{}) error {
-	placeholders := make([]string, len(columns))
+func (q *queryer) Update(ctx context.Context, tableName string, key Key, columns []string, values ...interface{}) error {
+	condition, err := key.condition()
+	if err != nil {
+		return err
+	}
+	stmt, err := newStatement(tableName)
+	if err != nil {
+		return err
+	}
+	quoted, err := stmt.columns(columns...)
+	if err != nil {
+		return err
+	}
+	if len(columns) != len(values) {
+		return fmt.Errorf("%w: %d values for %d columns", ErrInvalidQuery, len(values), len(columns))
+	}
+
+	assignments := make([]string, len(columns))
 	for i := range columns {
-		placeholders[i] = fmt.Sprintf("%s = ?", columns[i])
+		assignments[i] = fmt.Sprintf("%s = %s", quoted[i], stmt.bind(values[i]))
+	}
+	where, err := stmt.where(condition)
+	if err != nil {
+		return err
 	}
 
 	query := fmt.Sprintf(
-		"UPDATE `%s` SET %s WHERE %s = ?",
-		tableName,
-		strings.Join(placeholders, ", "),
-		uniqueFieldName)
+		"UPDATE %s SET %s WHERE %s",
+		stmt.tableSQL(),
+		strings.Join(assignments, ", "),
+		where)
 
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	ctx, cancel := q.withTimeout(ctx)
 	defer cancel()
 
-	values = append(values, uniqueFieldValue)
-
-	_, err := c.db.ExecContext(ctx, query, values...)
+	_, err = q.ext.ExecContext(ctx, query, stmt.args...)
 	if err != nil {
-		log.Printf("Error %s when inserting row into table", err)
-		return err
+		log.Printf("Error %s when updating row in table", err)
+		return translateError(err)
 	}
 
 	log.Printf("Updated row with table %s", tableName)
@@ -139,58 +275,89 @@ func (c *client) Update(
 	return nil
 }
 
-func (c *client) Exists(tableName string, columnName string, value interface{}) (bool, error) {
-	query := fmt.Sprintf("SELECT 1 FROM `%s` WHERE %s = ?", tableName, columnName)
+// selectKey renders a SELECT of the first row of key.
+func selectKey(tableName string, key Key) (*statement, string, error) {
+	condition, err := key.condition()
+	if err != nil {
+		return nil, "", err
+	}
+	stmt, err := newStatement(tableName)
+	if err != nil {
+		return nil, "", err
+	}
+	query, err := stmt.selectSQL(Where(condition).Limit(1))
+	if err != nil {
+		return nil, "", err
+	}
+	return stmt, query, nil
+}
+
+func (q *queryer) Exists(ctx context.Context, tableName string, key Key) (bool, error) {
+	stmt, query, err := selectKey(tableName, key)
+	if err != nil {
+		return false, err
+	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	ctx, cancel := q.withTimeout(ctx)
 	defer cancel()
 
-	row := c.db.QueryRowContext(ctx, query, value)
+	row := q.ext.QueryRowContext(ctx, "SELECT EXISTS ("+query+")", stmt.args...)
 
-	var exists int
-	err := row.Scan(&exists)
-	if err == sql.ErrNoRows {
-		// No rows found, the row doesn't exist
-		return false, nil
-	} else if err != nil {
+	var exists bool
+	err = row.Scan(&exists)
+	if err != nil {
 		log.Printf("Error %s when querying the database", err)
 		return false, err
 	}
 
-	return true, nil
+	return exists, nil
 }
 
-func (c *client) FindUnique(resultStruct interface{}, tableName string, columnName string, value interface{}) error {
-	query := fmt.Sprintf("SELECT * FROM `%s` WHERE %s = ? LIMIT 1", tableName, columnName)
+func (q *queryer) FindUnique(ctx context.Context, resultStruct interface{}, tableName string, key Key) error {
+	return q.findUnique(ctx, resultStruct, tableName, key, false)
+}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-	defer cancel()
+func (q *queryer) FindUniqueForUpdate(ctx context.Context, resultStruct interface{}, tableName string, key Key) error {
+	return q.findUnique(ctx, resultStruct, tableName, key, true)
+}
 
-	udb := c.db.Unsafe()
-	if err := udb.GetContext(ctx, resultStruct, query, value); err != nil {
-		log.Printf("Error %s when executing query", err)
+func (q *queryer) findUnique(ctx context.Context, resultStruct interface{}, tableName string, key Key, lock bool) error {
+	stmt, query, err := selectKey(tableName, key)
+	if err != nil {
 		return err
 	}
 
-	return nil
-}
+	if lock {
+		query += " FOR UPDATE"
+	}
 
-func (c *client) FindMany(resultStruct interface{}, tableName string, condition *string, limit *int) error {
-	query := fmt.Sprintf("SELECT * FROM `%s`", tableName)
+	ctx, cancel := q.withTimeout(ctx)
+	defer cancel()
 
-	if condition != nil {
-		query += fmt.Sprintf(" WHERE %s", *condition)
+	if err := q.ext.GetContext(ctx, resultStruct, query, stmt.args...); err != nil {
+		if err != sql.ErrNoRows {
+			log.Printf("Error %s when executing query", err)
+		}
+		return translateError(err)
 	}
 
-	if limit != nil {
-		query += fmt.Sprintf(" LIMIT %d", *limit)
+	return nil
+}
+
+func (q *queryer) FindMany(ctx context.Context, resultStruct interface{}, tableName string, query Query) error {
+	stmt, err := newStatement(tableName)
+	if err != nil {
+		return err
+	}
+	selectQuery, err := stmt.selectSQL(query)
+	if err != nil {
+		return err
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	ctx, cancel := q.withTimeout(ctx)
 	defer cancel()
 
-	udb := c.db.Unsafe()
-	if err := udb.SelectContext(ctx, resultStruct, query); err != nil {
+	if err := q.ext.SelectContext(ctx, resultStruct, selectQuery, stmt.args...); err != nil {
 		log.Printf("Error %s when executing query", err)
 		return err
 	}
@@ -198,34 +365,40 @@ func (c *client) FindMany(resultStruct interface{}, tableName string, condition
 	return nil
 }
 
-func (c *client) Delete(tableName string, uniqueFieldName string, uniqueFieldValue interface{}) error {
-	return c.delete(tableName, &uniqueFieldName, uniqueFieldValue)
+func (q *queryer) Delete(ctx context.Context, tableName string, key Key) error {
+	condition, err := key.condition()
+	if err != nil {
+		return err
+	}
+	return q.delete(ctx, tableName, condition)
 }
 
-func (c *client) DeleteAll(tableName string) error {
-	return c.delete(tableName, nil, nil)
+func (q *queryer) DeleteAll(ctx context.Context, tableName string) error {
+	return q.delete(ctx, tableName, And())
 }
 
-func (c *client) delete(tableName string, uniqueFieldName *string, uniqueFieldValue interface{}) error {
-	query := fmt.Sprintf("DELETE FROM `%s`", tableName)
+func (q *queryer) delete(ctx context.Context, tableName string, condition Condition) error {
+	stmt, err := newStatement(tableName)
+	if err != nil {
+		return err
+	}
+	where, err := stmt.where(condition)
+	if err != nil {
+		return err
+	}
 
-	if uniqueFieldName != nil && uniqueFieldValue != nil {
-		query += fmt.Sprintf(" WHERE %s = ?", *uniqueFieldName)
+	query := "DELETE FROM " + stmt.tableSQL()
+	if where != "TRUE" {
+		query += " WHERE " + where
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	ctx, cancel := q.withTimeout(ctx)
 	defer cancel()
 
-	var err error
-	if uniqueFieldValue != nil {
-		_, err = c.db.ExecContext(ctx, query, uniqueFieldValue)
-	} else {
-		_, err = c.db.ExecContext(ctx, query)
-	}
-
+	_, err = q.ext.ExecContext(ctx, query, stmt.args...)
 	if err != nil {
 		log.Printf("Error %s when executing query", err)
-		return err
+		return translateError(err)
 	}
 
 	return nil
@@ -1,14 +1,148 @@
 package database
 
 import (
+	"context"
+	"errors"
+	"os"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 )
 
 func TestInsertIntoTable(t *testing.T) {
-	dbClient, err := NewClient("username:password@/getground")
+	dsn := os.Getenv("TEST_MYSQL_DSN")
+	if dsn == "" {
+		t.Skip("TEST_MYSQL_DSN is not set")
+	}
+
+	dbClient, err := NewClient(dsn)
 	assert.Nil(t, err)
 	assert.NotNil(t, dbClient)
 	defer dbClient.Close()
 }
+
+func TestSelectSQL(t *testing.T) {
+	arrived := time.Date(2023, 1, 1, 10, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name  string
+		table string
+		query Query
+		sql   string
+		args  []interface{}
+		err   error
+	}{
+		{
+			name:  "every row",
+			table: "guest",
+			sql:   "SELECT * FROM `guest`",
+		},
+		{
+			name:  "conditions",
+			table: "guest",
+			query: Where(Eq("event_id", 1), Like("name", "jo%"), Or(IsNull("time_left"), Ge("time_left", arrived))),
+			sql:   "SELECT * FROM `guest` WHERE (`event_id` = ? AND `name` LIKE ? AND (`time_left` IS NULL OR `time_left` >= ?))",
+			args:  []interface{}{1, "jo%", arrived},
+		},
+		{
+			name:  "values are bound",
+			table: "guest",
+			query: Where(Eq("name", "' OR 1=1 --")),
+			sql:   "SELECT * FROM `guest` WHERE `name` = ?",
+			args:  []interface{}{"' OR 1=1 --"},
+		},
+		{
+			name:  "order, limit and offset",
+			table: "guest",
+			query: Query{}.OrderBy(Asc("name"), Desc("id")).Limit(20).Offset(40),
+			sql:   "SELECT * FROM `guest` ORDER BY `name`, `id` DESC LIMIT 20 OFFSET 40",
+		},
+		{
+			name:  "offset without limit",
+			table: "guest",
+			query: Query{}.Offset(40),
+			sql:   "SELECT * FROM `guest` LIMIT 18446744073709551615 OFFSET 40",
+		},
+		{
+			name:  "after a row",
+			table: "guest",
+			query: Where(Eq("event_id", 1)).OrderBy(Asc("time_arrived"), Desc("id")).After(arrived, 7),
+			sql: "SELECT * FROM `guest` WHERE (`event_id` = ? AND (`time_arrived` > ? OR (`time_arrived` = ? AND (`id` < ? OR `id` IS NULL))))" +
+				" ORDER BY `time_arrived`, `id` DESC",
+			args: []interface{}{1, arrived, arrived, 7},
+		},
+		{
+			name:  "after a NULL",
+			table: "guest",
+			query: Query{}.OrderBy(Asc("time_arrived"), Asc("id")).After((*time.Time)(nil), 7),
+			sql:   "SELECT * FROM `guest` WHERE (`time_arrived` IS NOT NULL OR (`time_arrived` IS NULL AND `id` > ?)) ORDER BY `time_arrived`, `id`",
+			args:  []interface{}{7},
+		},
+		{
+			name:  "after values not matching the order",
+			table: "guest",
+			query: Query{}.After(7),
+			err:   ErrInvalidQuery,
+		},
+		{
+			name:  "unknown table",
+			table: "guest` WHERE 1=1; --",
+			err:   ErrUnknownIdentifier,
+		},
+		{
+			name:  "unknown column",
+			table: "guest",
+			query: Where(Eq("name = name OR 1", 1)),
+			err:   ErrUnknownIdentifier,
+		},
+		{
+			name:  "unknown order column",
+			table: "guest",
+			query: Query{}.OrderBy(Asc("RAND()")),
+			err:   ErrUnknownIdentifier,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			stmt, err := newStatement(tc.table)
+			var sql string
+			if err == nil {
+				sql, err = stmt.selectSQL(tc.query)
+			}
+			if tc.err != nil {
+				assert.True(t, errors.Is(err, tc.err), "expected %v, got %v", tc.err, err)
+				return
+			}
+			assert.Nil(t, err)
+			assert.Equal(t, tc.sql, sql)
+			assert.Equal(t, tc.args, stmt.args)
+		})
+	}
+}
+
+func TestMismatchedValues(t *testing.T) {
+	// MySQL statements are checked before reaching the database, the
+	// in-memory client updates the default event when it isn't checked
+	ctx := context.Background()
+	clients := map[string]Queryer{
+		"mysql":  &queryer{},
+		"memory": NewMemoryClient(),
+	}
+
+	for name, q := range clients {
+		_, err := q.Create(ctx, "event", []string{"name", "venue"}, "party")
+		assert.Truef(t, errors.Is(err, ErrInvalidQuery), "%s create with fewer values, %v", name, err)
+		_, err = q.Create(ctx, "event", []string{"name"}, "party", "hall")
+		assert.Truef(t, errors.Is(err, ErrInvalidQuery), "%s create with more values, %v", name, err)
+		err = q.Update(ctx, "event", By("id", 1), []string{"name", "venue"}, "party")
+		assert.Truef(t, errors.Is(err, ErrInvalidQuery), "%s update with fewer values, %v", name, err)
+		err = q.Update(ctx, "event", By("id", 1), []string{"name"}, "party", "hall")
+		assert.Truef(t, errors.Is(err, ErrInvalidQuery), "%s update with more values, %v", name, err)
+	}
+}
+
+func TestQuote(t *testing.T) {
+	assert.Equal(t, "`guest`", quote("guest"))
+	assert.Equal(t, "`a``b`", quote("a`b"))
+}
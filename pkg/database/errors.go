@@ -0,0 +1,42 @@
+package database
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+var (
+	// ErrNotFound is returned when a query expected a row but found none.
+	ErrNotFound = errors.New("record not found")
+	// ErrDuplicate is returned when a write violates a unique key.
+	ErrDuplicate = errors.New("duplicate record")
+	// ErrForeignKey is returned when a write references a missing row.
+	ErrForeignKey = errors.New("referenced record does not exist")
+)
+
+// translateError maps driver specific errors onto the package errors so
+// callers don't depend on the backend in use.
+func translateError(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrNotFound
+	}
+
+	var mysqlErr *mysql.MySQLError
+	if errors.As(err, &mysqlErr) {
+		switch mysqlErr.Number {
+		case mysqlErrDupEntry:
+			return fmt.Errorf("%w: %s", ErrDuplicate, mysqlErr.Message)
+		case mysqlErrNoReferenced:
+			return fmt.Errorf("%w: %s", ErrForeignKey, mysqlErr.Message)
+		}
+	}
+
+	return err
+}
@@ -0,0 +1,43 @@
+package database
+
+import (
+	"errors"
+	"sort"
+)
+
+// ErrEmptyKey is returned when a row level operation is given no columns to
+// identify its rows with.
+var ErrEmptyKey = errors.New("key must name at least one column")
+
+// Key identifies rows by the values of one or more columns, which must all
+// match.
+type Key map[string]interface{}
+
+// By returns a Key matching rows whose column equals value.
+func By(column string, value interface{}) Key {
+	return Key{column: value}
+}
+
+// columns returns the key columns in a stable order.
+func (k Key) columns() []string {
+	columns := make([]string, 0, len(k))
+	for column := range k {
+		columns = append(columns, column)
+	}
+	sort.Strings(columns)
+	return columns
+}
+
+// condition returns the condition matching the rows of the key.
+func (k Key) condition() (Condition, error) {
+	if len(k) == 0 {
+		return Condition{}, ErrEmptyKey
+	}
+
+	columns := k.columns()
+	conditions := make([]Condition, len(columns))
+	for i, column := range columns {
+		conditions[i] = Eq(column, k[column])
+	}
+	return And(conditions...), nil
+}
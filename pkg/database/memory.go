@@ -0,0 +1,860 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+// MySQL error numbers reproduced by the in-memory backend so callers see the
+// same failures they would get from a real server.
+const (
+	mysqlErrDupEntry     = 1062
+	mysqlErrNoReferenced = 1452
+)
+
+type memoryTable struct {
+	*tableSchema
+	rows   []map[string]interface{}
+	nextID int64
+}
+
+type memoryStore struct {
+	tables map[string]*memoryTable
+}
+
+// newMemoryStore returns an empty store of the tables of the schema, holding
+// the default event like the migrations do.
+func newMemoryStore() *memoryStore {
+	s := &memoryStore{tables: make(map[string]*memoryTable, len(schema))}
+	for name, t := range schema {
+		s.tables[name] = &memoryTable{tableSchema: t, nextID: 1}
+	}
+
+	event := s.tables["event"]
+	event.rows = []map[string]interface{}{
+		{"id": int64(1), "name": "Default", "venue": "", "start_time": nil, "end_time": nil},
+	}
+	event.nextID = 2
+	return s
+}
+
+func (s *memoryStore) clone() *memoryStore {
+	c := &memoryStore{tables: make(map[string]*memoryTable, len(s.tables))}
+	for name, t := range s.tables {
+		ct := *t
+		ct.rows = make([]map[string]interface{}, len(t.rows))
+		for i, row := range t.rows {
+			cr := make(map[string]interface{}, len(row))
+			for k, v := range row {
+				cr[k] = v
+			}
+			ct.rows[i] = cr
+		}
+		c.tables[name] = &ct
+	}
+	return c
+}
+
+type memoryQueryer struct {
+	store func() *memoryStore
+	// acquire serializes access to the store. Transactions already own it
+	// and only guard against being used after they finish.
+	acquire func(ctx context.Context) (func(), error)
+}
+
+type memoryClient struct {
+	memoryQueryer
+	data *memoryStore
+	sem  chan struct{}
+}
+
+type memoryTx struct {
+	memoryQueryer
+	client *memoryClient
+	data   *memoryStore
+	mu     sync.Mutex
+	done   bool
+	stop   chan struct{}
+}
+
+// NewMemoryClient returns a Client that keeps every row in process memory.
+// It enforces the same auto-increment ids, unique keys and cascading foreign
+// keys as the MySQL schema and is meant for tests and local development.
+// Transactions are serialized, so at most one runs at a time.
+func NewMemoryClient() Client {
+	c := &memoryClient{data: newMemoryStore(), sem: make(chan struct{}, 1)}
+	c.memoryQueryer = memoryQueryer{
+		store:   func() *memoryStore { return c.data },
+		acquire: c.lock,
+	}
+	return c
+}
+
+func (c *memoryClient) lock(ctx context.Context) (func(), error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	select {
+	case c.sem <- struct{}{}:
+		return func() { <-c.sem }, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+func (c *memoryClient) Close() {}
+
+func (c *memoryClient) Begin(ctx context.Context) (Tx, error) {
+	// The lock is held until the transaction finishes
+	if _, err := c.lock(ctx); err != nil {
+		return nil, err
+	}
+
+	t := &memoryTx{client: c, data: c.data.clone(), stop: make(chan struct{})}
+	t.memoryQueryer = memoryQueryer{
+		store:   func() *memoryStore { return t.data },
+		acquire: t.lock,
+	}
+
+	// Roll back automatically when the caller's context is cancelled
+	go func() {
+		select {
+		case <-ctx.Done():
+			t.finish(false)
+		case <-t.stop:
+		}
+	}()
+
+	return t, nil
+}
+
+func (c *memoryClient) Transaction(ctx context.Context, fn func(tx Tx) error) (err error) {
+	t, err := c.Begin(ctx)
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		if p := recover(); p != nil {
+			t.Rollback()
+			panic(p)
+		}
+	}()
+
+	if err = fn(t); err != nil {
+		t.Rollback()
+		return err
+	}
+
+	return t.Commit()
+}
+
+func (t *memoryTx) lock(ctx context.Context) (func(), error) {
+	t.mu.Lock()
+	if t.done {
+		t.mu.Unlock()
+		return nil, sql.ErrTxDone
+	}
+	if err := ctx.Err(); err != nil {
+		t.mu.Unlock()
+		return nil, err
+	}
+	return t.mu.Unlock, nil
+}
+
+func (t *memoryTx) Commit() error {
+	return t.finish(true)
+}
+
+func (t *memoryTx) Rollback() error {
+	return t.finish(false)
+}
+
+// finish ends the transaction, publishing its changes when commit is true.
+func (t *memoryTx) finish(commit bool) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.done {
+		return sql.ErrTxDone
+	}
+	t.done = true
+	close(t.stop)
+
+	if commit {
+		t.client.data = t.data
+	}
+	<-t.client.sem
+
+	return nil
+}
+
+func (s *memoryStore) table(name string) (*memoryTable, error) {
+	t, ok := s.tables[name]
+	if !ok {
+		return nil, fmt.Errorf("%w: table %s", ErrUnknownIdentifier, name)
+	}
+	return t, nil
+}
+
+// checkConstraints validates row against the unique and foreign keys of the
+// table, ignoring the row at index skip.
+func (s *memoryStore) checkConstraints(t *memoryTable, row map[string]interface{}, skip int) error {
+	for _, key := range t.unique {
+		for i, other := range t.rows {
+			if i == skip {
+				continue
+			}
+			same := true
+			for _, c := range key {
+				if row[c] == nil || compareValues(row[c], other[c]) != 0 {
+					same = false
+					break
+				}
+			}
+			if same {
+				return &mysql.MySQLError{
+					Number:  mysqlErrDupEntry,
+					Message: fmt.Sprintf("Duplicate entry '%v' for key '%s'", row[key[0]], strings.Join(key, "_")),
+				}
+			}
+		}
+	}
+
+	for _, fk := range t.foreignKeys {
+		if row[fk.column] == nil {
+			continue
+		}
+		ref := s.tables[fk.refTable]
+		found := false
+		for _, other := range ref.rows {
+			if compareValues(row[fk.column], other[fk.refColumn]) == 0 {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return &mysql.MySQLError{
+				Number:  mysqlErrNoReferenced,
+				Message: fmt.Sprintf("Cannot add or update a child row: a foreign key constraint fails (`%s`)", fk.column),
+			}
+		}
+	}
+
+	return nil
+}
+
+// deleteRows removes the rows at the given indexes and cascades the delete to
+// every row referencing them.
+func (s *memoryStore) deleteRows(tableName string, t *memoryTable, indexes []int) {
+	if len(indexes) == 0 {
+		return
+	}
+
+	drop := make(map[int]bool, len(indexes))
+	for _, i := range indexes {
+		drop[i] = true
+	}
+
+	var removed []map[string]interface{}
+	kept := t.rows[:0:0]
+	for i, row := range t.rows {
+		if drop[i] {
+			removed = append(removed, row)
+		} else {
+			kept = append(kept, row)
+		}
+	}
+	t.rows = kept
+
+	for childName, child := range s.tables {
+		for _, fk := range child.foreignKeys {
+			if fk.refTable != tableName {
+				continue
+			}
+			var childIndexes []int
+			for i, row := range child.rows {
+				for _, parent := range removed {
+					if compareValues(row[fk.column], parent[fk.refColumn]) == 0 {
+						childIndexes = append(childIndexes, i)
+						break
+					}
+				}
+			}
+			s.deleteRows(childName, child, childIndexes)
+		}
+	}
+}
+
+func (q *memoryQueryer) Create(ctx context.Context, tableName string, columns []string, values ...interface{}) (int, error) {
+	release, err := q.acquire(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer release()
+
+	store := q.store()
+	t, err := store.table(tableName)
+	if err != nil {
+		return 0, err
+	}
+	if err := t.checkColumns(columns...); err != nil {
+		return 0, err
+	}
+	if len(columns) != len(values) {
+		return 0, fmt.Errorf("%w: %d values for %d columns", ErrInvalidQuery, len(values), len(columns))
+	}
+
+	row := make(map[string]interface{}, len(t.columns))
+	for _, c := range t.columns {
+		row[c] = t.defaults[c]
+	}
+	for i, c := range columns {
+		if row[c], err = normalizeValue(values[i]); err != nil {
+			return 0, err
+		}
+	}
+
+	if row["id"] == nil {
+		row["id"] = t.nextID
+	}
+	if err := store.checkConstraints(t, row, -1); err != nil {
+		return 0, translateError(err)
+	}
+
+	id := row["id"].(int64)
+	if id >= t.nextID {
+		t.nextID = id + 1
+	}
+	t.rows = append(t.rows, row)
+
+	return int(id), nil
+}
+
+func (q *memoryQueryer) Update(ctx context.Context, tableName string, key Key, columns []string, values ...interface{}) error {
+	release, err := q.acquire(ctx)
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	store := q.store()
+	t, err := store.table(tableName)
+	if err != nil {
+		return err
+	}
+	if err := t.checkColumns(columns...); err != nil {
+		return err
+	}
+	if len(columns) != len(values) {
+		return fmt.Errorf("%w: %d values for %d columns", ErrInvalidQuery, len(values), len(columns))
+	}
+	match, err := t.matchKey(key)
+	if err != nil {
+		return err
+	}
+
+	for i, row := range t.rows {
+		if !match(row) {
+			continue
+		}
+
+		updated := make(map[string]interface{}, len(row))
+		for k, v := range row {
+			updated[k] = v
+		}
+		for j, c := range columns {
+			if updated[c], err = normalizeValue(values[j]); err != nil {
+				return err
+			}
+		}
+		if err := store.checkConstraints(t, updated, i); err != nil {
+			return translateError(err)
+		}
+		t.rows[i] = updated
+	}
+
+	return nil
+}
+
+func (q *memoryQueryer) Exists(ctx context.Context, tableName string, key Key) (bool, error) {
+	row, err := q.findRow(ctx, tableName, key)
+	if err != nil {
+		return false, err
+	}
+	return row != nil, nil
+}
+
+func (q *memoryQueryer) FindUnique(ctx context.Context, resultStruct interface{}, tableName string, key Key) error {
+	row, err := q.findRow(ctx, tableName, key)
+	if err != nil {
+		return err
+	}
+	if row == nil {
+		return ErrNotFound
+	}
+	return scanRow(resultStruct, row)
+}
+
+// FindUniqueForUpdate is identical to FindUnique since transactions on the
+// in-memory store are already serialized.
+func (q *memoryQueryer) FindUniqueForUpdate(ctx context.Context, resultStruct interface{}, tableName string, key Key) error {
+	return q.FindUnique(ctx, resultStruct, tableName, key)
+}
+
+func (q *memoryQueryer) findRow(ctx context.Context, tableName string, key Key) (map[string]interface{}, error) {
+	release, err := q.acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	t, err := q.store().table(tableName)
+	if err != nil {
+		return nil, err
+	}
+	match, err := t.matchKey(key)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, row := range t.rows {
+		if match(row) {
+			return row, nil
+		}
+	}
+	return nil, nil
+}
+
+// matchKey returns a function reporting whether a row matches every column
+// of key.
+func (t *memoryTable) matchKey(key Key) (func(row map[string]interface{}) bool, error) {
+	condition, err := key.condition()
+	if err != nil {
+		return nil, err
+	}
+	return t.compile(condition)
+}
+
+// compile returns a function reporting whether a row matches condition,
+// once its columns are checked and its values normalized.
+func (t *memoryTable) compile(c Condition) (func(row map[string]interface{}) bool, error) {
+	switch c.op {
+	case opAnd, opOr:
+		matchers := make([]func(row map[string]interface{}) bool, len(c.conditions))
+		for i, condition := range c.conditions {
+			match, err := t.compile(condition)
+			if err != nil {
+				return nil, err
+			}
+			matchers[i] = match
+		}
+		// AND stops at the first row not matching, OR at the first matching
+		isOr := c.op == opOr
+		return func(row map[string]interface{}) bool {
+			for _, match := range matchers {
+				if match(row) == isOr {
+					return isOr
+				}
+			}
+			return !isOr
+		}, nil
+	case "":
+		return nil, fmt.Errorf("%w: empty condition", ErrInvalidQuery)
+	}
+
+	if err := t.checkColumns(c.column); err != nil {
+		return nil, err
+	}
+	column := c.column
+	switch c.op {
+	case opIsNull:
+		return func(row map[string]interface{}) bool { return row[column] == nil }, nil
+	case opIsNotNull:
+		return func(row map[string]interface{}) bool { return row[column] != nil }, nil
+	}
+
+	value, err := normalizeValue(c.value)
+	if err != nil {
+		return nil, err
+	}
+	if c.op == opLike {
+		pattern := likePattern(value.(string))
+		return func(row map[string]interface{}) bool {
+			s, ok := row[column].(string)
+			return ok && pattern.MatchString(s)
+		}, nil
+	}
+
+	op := c.op
+	return func(row map[string]interface{}) bool {
+		// NULL never compares to anything in SQL
+		if row[column] == nil || value == nil {
+			return false
+		}
+		c := compareValues(row[column], value)
+		switch op {
+		case opEq:
+			return c == 0
+		case opNe:
+			return c != 0
+		case opLt:
+			return c < 0
+		case opLe:
+			return c <= 0
+		case opGt:
+			return c > 0
+		case opGe:
+			return c >= 0
+		}
+		return false
+	}, nil
+}
+
+func (q *memoryQueryer) FindMany(ctx context.Context, resultStruct interface{}, tableName string, query Query) error {
+	condition, err := query.condition()
+	if err != nil {
+		return err
+	}
+
+	release, err := q.acquire(ctx)
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	t, err := q.store().table(tableName)
+	if err != nil {
+		return err
+	}
+	match, err := t.compile(condition)
+	if err != nil {
+		return err
+	}
+	for _, order := range query.orderBy {
+		if err := t.checkColumns(order.Column); err != nil {
+			return err
+		}
+	}
+
+	var rows []map[string]interface{}
+	for _, row := range t.rows {
+		if match(row) {
+			rows = append(rows, row)
+		}
+	}
+
+	sort.SliceStable(rows, func(i, j int) bool {
+		return compareRows(rows[i], rows[j], query.orderBy) < 0
+	})
+	if query.offset >= len(rows) {
+		rows = nil
+	} else {
+		rows = rows[query.offset:]
+	}
+	if query.limit > 0 && len(rows) > query.limit {
+		rows = rows[:query.limit]
+	}
+
+	return scanRows(resultStruct, rows)
+}
+
+// compareRows orders two rows by the columns of orderBy in turn.
+func compareRows(a, b map[string]interface{}, orderBy []Order) int {
+	for _, order := range orderBy {
+		c := compareValues(a[order.Column], b[order.Column])
+		if order.Desc {
+			c = -c
+		}
+		if c != 0 {
+			return c
+		}
+	}
+	return 0
+}
+
+func (q *memoryQueryer) Delete(ctx context.Context, tableName string, key Key) error {
+	condition, err := key.condition()
+	if err != nil {
+		return err
+	}
+	return q.delete(ctx, tableName, condition)
+}
+
+func (q *memoryQueryer) DeleteAll(ctx context.Context, tableName string) error {
+	return q.delete(ctx, tableName, And())
+}
+
+func (q *memoryQueryer) delete(ctx context.Context, tableName string, condition Condition) error {
+	release, err := q.acquire(ctx)
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	store := q.store()
+	t, err := store.table(tableName)
+	if err != nil {
+		return err
+	}
+
+	match, err := t.compile(condition)
+	if err != nil {
+		return err
+	}
+
+	var indexes []int
+	for i, row := range t.rows {
+		if match(row) {
+			indexes = append(indexes, i)
+		}
+	}
+	store.deleteRows(tableName, t, indexes)
+
+	return nil
+}
+
+// likePattern compiles a LIKE pattern, where % matches any run of characters,
+// _ any single character and \ escapes either. Matching ignores case like
+// MySQL's default collation does.
+func likePattern(pattern string) *regexp.Regexp {
+	var expr strings.Builder
+	expr.WriteString(`(?is)^`)
+	escaped := false
+	for _, r := range pattern {
+		switch {
+		case escaped:
+			expr.WriteString(regexp.QuoteMeta(string(r)))
+			escaped = false
+		case r == '\\':
+			escaped = true
+		case r == '%':
+			expr.WriteString(`.*`)
+		case r == '_':
+			expr.WriteString(`.`)
+		default:
+			expr.WriteString(regexp.QuoteMeta(string(r)))
+		}
+	}
+	expr.WriteString(`$`)
+	return regexp.MustCompile(expr.String())
+}
+
+// normalizeValue converts v into one of the types stored by the in-memory
+// backend: nil, int64, float64, bool, string or time.Time.
+func normalizeValue(v interface{}) (interface{}, error) {
+	if valuer, ok := v.(driver.Valuer); ok {
+		value, err := valuer.Value()
+		if err != nil {
+			return nil, err
+		}
+		v = value
+	}
+	if v == nil {
+		return nil, nil
+	}
+
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Ptr {
+		if rv.IsNil() {
+			return nil, nil
+		}
+		rv = rv.Elem()
+	}
+
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return rv.Int(), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return int64(rv.Uint()), nil
+	case reflect.Float32, reflect.Float64:
+		return rv.Float(), nil
+	case reflect.Bool:
+		return rv.Bool(), nil
+	case reflect.String:
+		return rv.String(), nil
+	case reflect.Slice:
+		if b, ok := rv.Interface().([]byte); ok {
+			return string(b), nil
+		}
+	case reflect.Struct:
+		if t, ok := rv.Interface().(time.Time); ok {
+			return t.UTC(), nil
+		}
+	}
+
+	return nil, fmt.Errorf("unsupported value type %T", v)
+}
+
+// compareValues orders two normalized values. NULL sorts before everything
+// and values of different types are compared by their string form.
+func compareValues(a, b interface{}) int {
+	switch {
+	case a == nil && b == nil:
+		return 0
+	case a == nil:
+		return -1
+	case b == nil:
+		return 1
+	}
+
+	switch av := a.(type) {
+	case int64:
+		switch bv := b.(type) {
+		case int64:
+			return compareOrdered(float64(av), float64(bv))
+		case float64:
+			return compareOrdered(float64(av), bv)
+		}
+	case float64:
+		switch bv := b.(type) {
+		case int64:
+			return compareOrdered(av, float64(bv))
+		case float64:
+			return compareOrdered(av, bv)
+		}
+	case time.Time:
+		if bv, ok := b.(time.Time); ok {
+			switch {
+			case av.Before(bv):
+				return -1
+			case av.After(bv):
+				return 1
+			}
+			return 0
+		}
+	case bool:
+		if bv, ok := b.(bool); ok && av == bv {
+			return 0
+		}
+	}
+
+	return strings.Compare(fmt.Sprint(a), fmt.Sprint(b))
+}
+
+func compareOrdered(a, b float64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+// scanRow copies the columns of row into the `db` tagged fields of dest,
+// which must be a pointer to a struct. Columns without a matching field are
+// ignored, like sqlx does in unsafe mode.
+func scanRow(dest interface{}, row map[string]interface{}) error {
+	rv := reflect.ValueOf(dest)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() || rv.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("expected a pointer to a struct but found %T", dest)
+	}
+	return scanStruct(rv.Elem(), row)
+}
+
+func scanStruct(v reflect.Value, row map[string]interface{}) error {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.PkgPath != "" {
+			continue
+		}
+
+		tag := strings.Split(field.Tag.Get("db"), ",")[0]
+		if tag == "-" {
+			continue
+		}
+		if tag == "" && field.Anonymous && field.Type.Kind() == reflect.Struct {
+			if err := scanStruct(v.Field(i), row); err != nil {
+				return err
+			}
+			continue
+		}
+		if tag == "" {
+			tag = strings.ToLower(field.Name)
+		}
+
+		value, ok := row[tag]
+		if !ok {
+			continue
+		}
+		if err := assignValue(v.Field(i), value); err != nil {
+			return fmt.Errorf("column %s: %w", tag, err)
+		}
+	}
+	return nil
+}
+
+func assignValue(field reflect.Value, value interface{}) error {
+	if scanner, ok := field.Addr().Interface().(sql.Scanner); ok {
+		return scanner.Scan(value)
+	}
+
+	if value == nil {
+		field.Set(reflect.Zero(field.Type()))
+		return nil
+	}
+
+	if field.Kind() == reflect.Ptr {
+		elem := reflect.New(field.Type().Elem())
+		if err := assignValue(elem.Elem(), value); err != nil {
+			return err
+		}
+		field.Set(elem)
+		return nil
+	}
+
+	rv := reflect.ValueOf(value)
+	if field.Kind() == reflect.String && rv.Kind() != reflect.String {
+		field.SetString(fmt.Sprint(value))
+		return nil
+	}
+	if !rv.Type().ConvertibleTo(field.Type()) {
+		return fmt.Errorf("cannot assign %T to %s", value, field.Type())
+	}
+	field.Set(rv.Convert(field.Type()))
+	return nil
+}
+
+// scanRows fills dest, a pointer to a slice of structs or struct pointers,
+// with one element per row.
+func scanRows(dest interface{}, rows []map[string]interface{}) error {
+	rv := reflect.ValueOf(dest)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() || rv.Elem().Kind() != reflect.Slice {
+		return fmt.Errorf("expected a pointer to a slice but found %T", dest)
+	}
+
+	slice := rv.Elem()
+	elemType := slice.Type().Elem()
+	isPtr := elemType.Kind() == reflect.Ptr
+	if isPtr {
+		elemType = elemType.Elem()
+	}
+
+	result := reflect.MakeSlice(slice.Type(), 0, len(rows))
+	for _, row := range rows {
+		elem := reflect.New(elemType)
+		if err := scanStruct(elem.Elem(), row); err != nil {
+			return err
+		}
+		if isPtr {
+			result = reflect.Append(result, elem)
+		} else {
+			result = reflect.Append(result, elem.Elem())
+		}
+	}
+	slice.Set(result)
+
+	return nil
+}
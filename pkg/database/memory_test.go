@@ -0,0 +1,227 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type memoryTestTable struct {
+	ID            int `db:"id"`
+	Capacity      int `db:"capacity"`
+	ReservedSeats int `db:"reserved_seats"`
+}
+
+type memoryTestGuest struct {
+	ID          int        `db:"id"`
+	Name        string     `db:"name"`
+	TableID     int        `db:"table_id"`
+	TimeArrived *time.Time `db:"time_arrived"`
+}
+
+func TestMemoryClientCreateAndFind(t *testing.T) {
+	ctx := context.Background()
+	dbClient := NewMemoryClient()
+	defer dbClient.Close()
+
+	// Test that ids are auto-incremented and defaults applied
+	first, err := dbClient.Create(ctx, "table", []string{"capacity"}, 4)
+	assert.Nil(t, err)
+	second, err := dbClient.Create(ctx, "table", []string{"capacity"}, 6)
+	assert.Nil(t, err)
+	assert.Equal(t, first+1, second)
+
+	var table memoryTestTable
+	err = dbClient.FindUnique(ctx, &table, "table", By("id", second))
+	assert.Nil(t, err)
+	assert.Equal(t, memoryTestTable{ID: second, Capacity: 6, ReservedSeats: 0}, table)
+
+	// Test updating a row
+	err = dbClient.Update(ctx, "table", By("id", second), []string{"reserved_seats"}, 3)
+	assert.Nil(t, err)
+	err = dbClient.FindUnique(ctx, &table, "table", By("id", second))
+	assert.Nil(t, err)
+	assert.Equal(t, 3, table.ReservedSeats)
+
+	// Test finding a missing row
+	err = dbClient.FindUnique(ctx, &table, "table", By("id", second+1))
+	assert.Equal(t, ErrNotFound, err)
+
+	exists, err := dbClient.Exists(ctx, "table", By("id", first))
+	assert.Nil(t, err)
+	assert.True(t, exists)
+}
+
+func TestMemoryClientConstraints(t *testing.T) {
+	ctx := context.Background()
+	dbClient := NewMemoryClient()
+	defer dbClient.Close()
+
+	tableID, err := dbClient.Create(ctx, "table", []string{"event_id", "capacity"}, 1, 4)
+	assert.Nil(t, err)
+
+	columns := []string{"event_id", "name", "accompanying_guests", "table_id"}
+	_, err = dbClient.Create(ctx, "guest", columns, 1, "john", 0, tableID)
+	assert.Nil(t, err)
+
+	// Test the unique name constraint
+	_, err = dbClient.Create(ctx, "guest", columns, 1, "john", 1, tableID)
+	assert.True(t, errors.Is(err, ErrDuplicate), "Expected duplicate error but found %v", err)
+
+	// Test that names are only unique within an event
+	eventID, err := dbClient.Create(ctx, "event", []string{"name"}, "Afterparty")
+	assert.Nil(t, err)
+	_, err = dbClient.Create(ctx, "guest", columns, eventID, "john", 1, tableID)
+	assert.Nil(t, err)
+
+	// Test the foreign key to the table
+	waitlistColumns := []string{"event_id", "name", "accompanying_guests", "table_id"}
+	_, err = dbClient.Create(ctx, "waitlist", waitlistColumns, 1, "rob", 0, tableID+1)
+	assert.True(t, errors.Is(err, ErrForeignKey), "Expected foreign key error but found %v", err)
+	_, err = dbClient.Create(ctx, "waitlist", waitlistColumns, 1, "rob", 0, tableID)
+	assert.Nil(t, err)
+
+	// Test that deleting the table cascades to its waitlist entries only
+	err = dbClient.Delete(ctx, "table", By("id", tableID))
+	assert.Nil(t, err)
+	exists, err := dbClient.Exists(ctx, "waitlist", By("name", "rob"))
+	assert.Nil(t, err)
+	assert.False(t, exists)
+	exists, err = dbClient.Exists(ctx, "guest", By("name", "john"))
+	assert.Nil(t, err)
+	assert.True(t, exists)
+}
+
+func TestMemoryClientFindMany(t *testing.T) {
+	ctx := context.Background()
+	dbClient := NewMemoryClient()
+	defer dbClient.Close()
+
+	tableID, err := dbClient.Create(ctx, "table", []string{"capacity"}, 4)
+	assert.Nil(t, err)
+
+	columns := []string{"name", "accompanying_guests", "table_id", "time_arrived"}
+	arrived := time.Date(2023, 1, 1, 10, 0, 0, 0, time.UTC)
+	_, err = dbClient.Create(ctx, "guest", columns, "john", 0, tableID, arrived)
+	assert.Nil(t, err)
+	_, err = dbClient.Create(ctx, "guest", columns, "rob", 2, tableID, nil)
+	assert.Nil(t, err)
+
+	var guests []memoryTestGuest
+	err = dbClient.FindMany(ctx, &guests, "guest", Where(IsNotNull("time_arrived")))
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(guests))
+	assert.Equal(t, "john", guests[0].Name)
+	assert.Equal(t, arrived, *guests[0].TimeArrived)
+
+	err = dbClient.FindMany(ctx, &guests, "guest", Where(Gt("accompanying_guests", 1), Eq("name", "rob")))
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(guests))
+	assert.Nil(t, guests[0].TimeArrived)
+
+	err = dbClient.FindMany(ctx, &guests, "guest", Where(Or(Eq("name", "john"), Eq("name", "rob")), Ne("time_arrived", nil)))
+	assert.Nil(t, err)
+	assert.Equal(t, 0, len(guests), "NULL never compares to anything")
+
+	err = dbClient.FindMany(ctx, &guests, "guest", Where(Or()))
+	assert.Nil(t, err)
+	assert.Equal(t, 0, len(guests))
+
+	err = dbClient.FindMany(ctx, &guests, "guest", Where(Eq("colour", "red")))
+	assert.True(t, errors.Is(err, ErrUnknownIdentifier))
+
+	err = dbClient.FindMany(ctx, &guests, "guest", Query{}.OrderBy(Asc("id")).Limit(1))
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"john"}, guestNames(guests))
+
+	err = dbClient.FindMany(ctx, &guests, "guest", Query{}.OrderBy(Asc("id")).Offset(1))
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"rob"}, guestNames(guests))
+
+	// Test LIKE patterns, which ignore case
+	_, err = dbClient.Create(ctx, "guest", columns, "Johanna", 1, tableID, arrived)
+	assert.Nil(t, err)
+	_, err = dbClient.Create(ctx, "guest", columns, "jo_", 1, tableID, nil)
+	assert.Nil(t, err)
+
+	err = dbClient.FindMany(ctx, &guests, "guest", Where(Like("name", "jo%")).OrderBy(Asc("id")))
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"john", "Johanna", "jo_"}, guestNames(guests))
+
+	err = dbClient.FindMany(ctx, &guests, "guest", Where(Like("name", `jo\_%`)))
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"jo_"}, guestNames(guests))
+
+	// Test sorting, NULL first, and paging after a row
+	byArrival := []Order{Asc("time_arrived"), Asc("id")}
+	err = dbClient.FindMany(ctx, &guests, "guest", Query{}.OrderBy(byArrival...))
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"rob", "jo_", "john", "Johanna"}, guestNames(guests))
+
+	err = dbClient.FindMany(ctx, &guests, "guest", Query{}.OrderBy(byArrival...).After((*time.Time)(nil), guests[0].ID).Limit(2))
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"jo_", "john"}, guestNames(guests))
+
+	err = dbClient.FindMany(ctx, &guests, "guest", Query{}.OrderBy(byArrival...).After(guests[1].TimeArrived, guests[1].ID))
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"Johanna"}, guestNames(guests))
+
+	err = dbClient.FindMany(ctx, &guests, "guest", Query{}.OrderBy(Desc("name")).After("jo_"))
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"Johanna"}, guestNames(guests))
+
+	err = dbClient.FindMany(ctx, &guests, "guest", Query{}.OrderBy(byArrival...).After(arrived))
+	assert.True(t, errors.Is(err, ErrInvalidQuery))
+}
+
+func guestNames(guests []memoryTestGuest) []string {
+	names := make([]string, len(guests))
+	for i, guest := range guests {
+		names[i] = guest.Name
+	}
+	return names
+}
+
+func TestMemoryClientTransaction(t *testing.T) {
+	ctx := context.Background()
+	dbClient := NewMemoryClient()
+	defer dbClient.Close()
+
+	// Test that a failed transaction is rolled back
+	rollbackErr := errors.New("rollback")
+	err := dbClient.Transaction(ctx, func(tx Tx) error {
+		if _, err := tx.Create(ctx, "table", []string{"capacity"}, 4); err != nil {
+			return err
+		}
+		return rollbackErr
+	})
+	assert.Equal(t, rollbackErr, err)
+
+	var tables []memoryTestTable
+	err = dbClient.FindMany(ctx, &tables, "table", Query{})
+	assert.Nil(t, err)
+	assert.Equal(t, 0, len(tables))
+
+	// Test that a successful transaction is committed
+	err = dbClient.Transaction(ctx, func(tx Tx) error {
+		_, err := tx.Create(ctx, "table", []string{"capacity"}, 4)
+		return err
+	})
+	assert.Nil(t, err)
+
+	err = dbClient.FindMany(ctx, &tables, "table", Query{})
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(tables))
+
+	// Test that a cancelled context aborts a waiting transaction
+	tx, err := dbClient.Begin(ctx)
+	assert.Nil(t, err)
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	_, err = dbClient.Begin(cancelled)
+	assert.Equal(t, context.Canceled, err)
+	assert.Nil(t, tx.Rollback())
+}
@@ -0,0 +1,75 @@
+package database
+
+import (
+	"context"
+	"os"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLoadMigrations(t *testing.T) {
+	fsys := fstest.MapFS{
+		"migrations/0002_add_column.up.sql":      {Data: []byte("ALTER TABLE `guest` ADD COLUMN `x` int;")},
+		"migrations/0002_add_column.down.sql":    {Data: []byte("ALTER TABLE `guest` DROP COLUMN `x`;")},
+		"migrations/0001_create_tables.up.sql":   {Data: []byte("CREATE TABLE `a` (`id` int);")},
+		"migrations/0001_create_tables.down.sql": {Data: []byte("DROP TABLE `a`;")},
+	}
+
+	migrations, err := LoadMigrations(fsys)
+	assert.Nil(t, err, "Error while loading migrations, %v", err)
+	assert.Equal(t, 2, len(migrations))
+	assert.Equal(t, 1, migrations[0].Version)
+	assert.Equal(t, "create_tables", migrations[0].Name)
+	assert.Equal(t, 2, migrations[1].Version)
+	assert.Len(t, migrations[0].Checksum, 64)
+
+	// Test that a migration without a down file is rejected
+	delete(fsys, "migrations/0002_add_column.down.sql")
+	_, err = LoadMigrations(fsys)
+	assert.EqualError(t, err, "migration 2 must have both an up and a down file")
+}
+
+func TestEmbeddedMigrations(t *testing.T) {
+	migrations, err := LoadMigrations(embeddedMigrations)
+	assert.Nil(t, err, "Error while loading migrations, %v", err)
+	for i, migration := range migrations {
+		assert.Equalf(t, i+1, migration.Version, "Expected migration %d to follow %d", migration.Version, i)
+	}
+}
+
+func TestSplitStatements(t *testing.T) {
+	statements := splitStatements(`
+-- comment
+CREATE TABLE a (id int);
+
+DROP TABLE b;
+`)
+	assert.Equal(t, []string{"CREATE TABLE a (id int)", "DROP TABLE b"}, statements)
+}
+
+func TestMigrator(t *testing.T) {
+	dsn := os.Getenv("TEST_MYSQL_DSN")
+	if dsn == "" {
+		t.Skip("TEST_MYSQL_DSN is not set")
+	}
+
+	ctx := context.Background()
+	db, err := Open(dsn)
+	assert.Nil(t, err)
+	defer db.Close()
+
+	migrator, err := NewMigrator(db)
+	assert.Nil(t, err)
+
+	_, err = migrator.Up(ctx)
+	assert.Nil(t, err, "Error while applying migrations, %v", err)
+
+	statuses, err := migrator.Status(ctx)
+	assert.Nil(t, err, "Error while getting migration status, %v", err)
+	for _, status := range statuses {
+		assert.NotNilf(t, status.AppliedAt, "Expected migration %d to be applied", status.Version)
+		assert.False(t, status.Modified)
+	}
+}
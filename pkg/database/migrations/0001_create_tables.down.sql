@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS `guest`;
+
+DROP TABLE IF EXISTS `table`;
@@ -1,14 +1,8 @@
-CREATE DATABASE IF NOT EXISTS `getground`;
-
-USE `getground`;
-
 --
 -- Table structure for table `table`
 --
 
-DROP TABLE IF EXISTS `table`;
-
-CREATE TABLE `table` (
+CREATE TABLE IF NOT EXISTS `table` (
   `id` int NOT NULL AUTO_INCREMENT,
   `capacity` int NOT NULL,
   `reserved_seats` int NOT NULL DEFAULT 0,
@@ -19,9 +13,7 @@ CREATE TABLE `table` (
 -- Table structure for table `guest`
 --
 
-DROP TABLE IF EXISTS `guest`;
-
-CREATE TABLE `guest` (
+CREATE TABLE IF NOT EXISTS `guest` (
   `id` int NOT NULL AUTO_INCREMENT,
   `name` varchar(255) NOT NULL UNIQUE,
   `table_id` int NOT NULL,
@@ -0,0 +1,5 @@
+DELETE FROM `guest` WHERE `status` = 'departed';
+
+ALTER TABLE `guest`
+  DROP COLUMN `status`,
+  DROP COLUMN `time_left`;
@@ -0,0 +1,9 @@
+--
+-- Keep departed guests instead of deleting them on checkout
+--
+
+ALTER TABLE `guest`
+  ADD COLUMN `time_left` VARCHAR(255) NULL AFTER `time_arrived`,
+  ADD COLUMN `status` VARCHAR(16) NOT NULL DEFAULT 'expected' AFTER `accompanying_guests`;
+
+UPDATE `guest` SET `status` = 'arrived' WHERE `time_arrived` IS NOT NULL;
@@ -0,0 +1,19 @@
+ALTER TABLE `guest`
+  ADD COLUMN `time_arrived_text` VARCHAR(255) NULL AFTER `time_arrived`,
+  ADD COLUMN `time_left_text` VARCHAR(255) NULL AFTER `time_left`;
+
+UPDATE `guest`
+  SET `time_arrived_text` = DATE_FORMAT(`time_arrived`, '%Y-%m-%d %H:%i:%s +0000 UTC')
+  WHERE `time_arrived` IS NOT NULL;
+
+UPDATE `guest`
+  SET `time_left_text` = DATE_FORMAT(`time_left`, '%Y-%m-%d %H:%i:%s +0000 UTC')
+  WHERE `time_left` IS NOT NULL;
+
+ALTER TABLE `guest`
+  DROP COLUMN `time_arrived`,
+  DROP COLUMN `time_left`;
+
+ALTER TABLE `guest`
+  CHANGE COLUMN `time_arrived_text` `time_arrived` VARCHAR(255) NULL,
+  CHANGE COLUMN `time_left_text` `time_left` VARCHAR(255) NULL;
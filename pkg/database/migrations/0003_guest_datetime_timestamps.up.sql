@@ -0,0 +1,24 @@
+--
+-- Store arrival and departure times as DATETIME instead of VARCHAR
+--
+
+ALTER TABLE `guest`
+  ADD COLUMN `time_arrived_at` DATETIME NULL AFTER `time_arrived`,
+  ADD COLUMN `time_left_at` DATETIME NULL AFTER `time_left`;
+
+-- Existing values look like `2006-01-02 15:04:05.999999999 +0000 UTC`
+UPDATE `guest`
+  SET `time_arrived_at` = STR_TO_DATE(LEFT(`time_arrived`, 19), '%Y-%m-%d %H:%i:%s')
+  WHERE `time_arrived` IS NOT NULL;
+
+UPDATE `guest`
+  SET `time_left_at` = STR_TO_DATE(LEFT(`time_left`, 19), '%Y-%m-%d %H:%i:%s')
+  WHERE `time_left` IS NOT NULL;
+
+ALTER TABLE `guest`
+  DROP COLUMN `time_arrived`,
+  DROP COLUMN `time_left`;
+
+ALTER TABLE `guest`
+  CHANGE COLUMN `time_arrived_at` `time_arrived` DATETIME NULL,
+  CHANGE COLUMN `time_left_at` `time_left` DATETIME NULL;
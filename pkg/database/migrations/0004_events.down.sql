@@ -0,0 +1,14 @@
+DELETE FROM `event` WHERE `id` <> 1;
+
+ALTER TABLE `guest`
+  DROP FOREIGN KEY `guest_event`,
+  DROP INDEX `guest_event_name`,
+  ADD UNIQUE KEY `name` (`name`),
+  DROP COLUMN `event_id`;
+
+ALTER TABLE `table`
+  DROP FOREIGN KEY `table_event`,
+  DROP INDEX `table_event_idx`,
+  DROP COLUMN `event_id`;
+
+DROP TABLE IF EXISTS `event`;
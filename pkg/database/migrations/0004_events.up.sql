@@ -0,0 +1,31 @@
+--
+-- Table structure for table `event`
+--
+
+CREATE TABLE `event` (
+  `id` int NOT NULL AUTO_INCREMENT,
+  `name` varchar(255) NOT NULL,
+  `venue` varchar(255) NOT NULL DEFAULT '',
+  `start_time` DATETIME NULL,
+  `end_time` DATETIME NULL,
+  PRIMARY KEY (`id`)
+) DEFAULT CHARSET=utf8;
+
+-- Existing tables and guests belong to the default event
+INSERT INTO `event` (`id`, `name`) VALUES (1, 'Default');
+
+ALTER TABLE `table`
+  ADD COLUMN `event_id` int NOT NULL DEFAULT 1 AFTER `id`,
+  ADD KEY `table_event_idx` (`event_id`),
+  ADD CONSTRAINT `table_event` FOREIGN KEY (`event_id`) REFERENCES `event` (`id`) ON DELETE CASCADE ON UPDATE CASCADE;
+
+ALTER TABLE `table` ALTER COLUMN `event_id` DROP DEFAULT;
+
+-- Guest names are unique per event instead of globally
+ALTER TABLE `guest`
+  ADD COLUMN `event_id` int NOT NULL DEFAULT 1 AFTER `id`,
+  DROP INDEX `name`,
+  ADD UNIQUE KEY `guest_event_name` (`event_id`, `name`),
+  ADD CONSTRAINT `guest_event` FOREIGN KEY (`event_id`) REFERENCES `event` (`id`) ON DELETE CASCADE ON UPDATE CASCADE;
+
+ALTER TABLE `guest` ALTER COLUMN `event_id` DROP DEFAULT;
@@ -0,0 +1,19 @@
+--
+-- Table structure for table `waitlist`
+--
+
+CREATE TABLE `waitlist` (
+  `id` int NOT NULL AUTO_INCREMENT,
+  `event_id` int NOT NULL,
+  `name` varchar(255) NOT NULL,
+  `accompanying_guests` int NOT NULL,
+  `table_id` int NULL,
+  `status` varchar(16) NOT NULL DEFAULT 'waiting',
+  `time_joined` DATETIME NOT NULL,
+  `time_promoted` DATETIME NULL,
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `waitlist_event_name` (`event_id`, `name`),
+  KEY `waitlist_table_idx` (`table_id`),
+  CONSTRAINT `waitlist_event` FOREIGN KEY (`event_id`) REFERENCES `event` (`id`) ON DELETE CASCADE ON UPDATE CASCADE,
+  CONSTRAINT `waitlist_table` FOREIGN KEY (`table_id`) REFERENCES `table` (`id`) ON DELETE CASCADE ON UPDATE CASCADE
+) DEFAULT CHARSET=utf8;
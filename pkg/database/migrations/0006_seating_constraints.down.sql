@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS `seating_constraint`;
+
+ALTER TABLE `table` DROP COLUMN `attributes`;
@@ -0,0 +1,22 @@
+-- Table features seating constraints can require, comma separated
+ALTER TABLE `table`
+  ADD COLUMN `attributes` varchar(255) NOT NULL DEFAULT '' AFTER `reserved_seats`;
+
+--
+-- Table structure for table `seating_constraint`
+--
+
+CREATE TABLE `seating_constraint` (
+  `id` int NOT NULL AUTO_INCREMENT,
+  `event_id` int NOT NULL,
+  `kind` varchar(16) NOT NULL,
+  `guest_id` int NOT NULL,
+  `other_guest_id` int NULL,
+  `attribute` varchar(255) NULL,
+  PRIMARY KEY (`id`),
+  KEY `seating_constraint_guest_idx` (`guest_id`),
+  KEY `seating_constraint_other_guest_idx` (`other_guest_id`),
+  CONSTRAINT `seating_constraint_event` FOREIGN KEY (`event_id`) REFERENCES `event` (`id`) ON DELETE CASCADE ON UPDATE CASCADE,
+  CONSTRAINT `seating_constraint_guest` FOREIGN KEY (`guest_id`) REFERENCES `guest` (`id`) ON DELETE CASCADE ON UPDATE CASCADE,
+  CONSTRAINT `seating_constraint_other_guest` FOREIGN KEY (`other_guest_id`) REFERENCES `guest` (`id`) ON DELETE CASCADE ON UPDATE CASCADE
+) DEFAULT CHARSET=utf8;
@@ -0,0 +1,5 @@
+DROP TABLE IF EXISTS `webhook_attempt`;
+
+DROP TABLE IF EXISTS `webhook_delivery`;
+
+DROP TABLE IF EXISTS `webhook`;
@@ -0,0 +1,49 @@
+--
+-- Table structure for table `webhook`
+--
+
+CREATE TABLE `webhook` (
+  `id` int NOT NULL AUTO_INCREMENT,
+  `event_id` int NOT NULL,
+  `url` varchar(2048) NOT NULL,
+  `secret` varchar(255) NOT NULL,
+  `event_types` varchar(255) NOT NULL,
+  `time_created` DATETIME NOT NULL,
+  PRIMARY KEY (`id`),
+  KEY `webhook_event_idx` (`event_id`),
+  CONSTRAINT `webhook_event` FOREIGN KEY (`event_id`) REFERENCES `event` (`id`) ON DELETE CASCADE ON UPDATE CASCADE
+) DEFAULT CHARSET=utf8;
+
+--
+-- Table structure for table `webhook_delivery`
+--
+
+CREATE TABLE `webhook_delivery` (
+  `id` int NOT NULL AUTO_INCREMENT,
+  `webhook_id` int NOT NULL,
+  `event_type` varchar(32) NOT NULL,
+  `payload` text NOT NULL,
+  `status` varchar(16) NOT NULL DEFAULT 'pending',
+  `attempts` int NOT NULL DEFAULT 0,
+  `next_attempt` DATETIME NULL,
+  `time_created` DATETIME NOT NULL,
+  `time_delivered` DATETIME NULL,
+  PRIMARY KEY (`id`),
+  KEY `webhook_delivery_status_idx` (`status`, `next_attempt`),
+  CONSTRAINT `webhook_delivery_webhook` FOREIGN KEY (`webhook_id`) REFERENCES `webhook` (`id`) ON DELETE CASCADE ON UPDATE CASCADE
+) DEFAULT CHARSET=utf8;
+
+--
+-- Table structure for table `webhook_attempt`
+--
+
+CREATE TABLE `webhook_attempt` (
+  `id` int NOT NULL AUTO_INCREMENT,
+  `delivery_id` int NOT NULL,
+  `status_code` int NULL,
+  `error` varchar(1024) NULL,
+  `duration_ms` int NOT NULL,
+  `time_attempted` DATETIME NOT NULL,
+  PRIMARY KEY (`id`),
+  CONSTRAINT `webhook_attempt_delivery` FOREIGN KEY (`delivery_id`) REFERENCES `webhook_delivery` (`id`) ON DELETE CASCADE ON UPDATE CASCADE
+) DEFAULT CHARSET=utf8;
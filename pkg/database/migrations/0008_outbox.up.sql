@@ -0,0 +1,25 @@
+--
+-- Table structure for table `outbox`
+--
+-- Rows are written in the same transaction as the change they report and
+-- relayed to the sinks afterwards. There are no foreign keys so the events
+-- of deleted tables and events are still relayed.
+--
+
+CREATE TABLE `outbox` (
+  `id` int NOT NULL AUTO_INCREMENT,
+  `event_id` int NOT NULL,
+  `type` varchar(32) NOT NULL,
+  `guest` varchar(255) NULL,
+  `table_id` int NULL,
+  `empty_seats` int NOT NULL,
+  `status` varchar(16) NOT NULL DEFAULT 'pending',
+  `attempts` int NOT NULL DEFAULT 0,
+  `delivered_to` varchar(255) NOT NULL DEFAULT '',
+  `last_error` varchar(1024) NULL,
+  `next_attempt` DATETIME NULL,
+  `time_created` DATETIME NOT NULL,
+  `time_delivered` DATETIME NULL,
+  PRIMARY KEY (`id`),
+  KEY `outbox_status_idx` (`status`, `next_attempt`)
+) DEFAULT CHARSET=utf8;
@@ -0,0 +1,22 @@
+--
+-- Table structure for table `audit`
+--
+-- Rows are written in the same transaction as the change they describe. There
+-- are no foreign keys so the history of deleted guests and tables is kept.
+--
+
+CREATE TABLE `audit` (
+  `id` int NOT NULL AUTO_INCREMENT,
+  `event_id` int NOT NULL,
+  `actor` varchar(255) NOT NULL,
+  `action` varchar(32) NOT NULL,
+  `entity_type` varchar(16) NOT NULL,
+  `guest` varchar(255) NULL,
+  `table_id` int NULL,
+  `before_state` text NULL,
+  `after_state` text NULL,
+  `time_created` DATETIME NOT NULL,
+  PRIMARY KEY (`id`),
+  KEY `audit_guest_idx` (`event_id`, `guest`),
+  KEY `audit_table_idx` (`event_id`, `table_id`)
+) DEFAULT CHARSET=utf8;
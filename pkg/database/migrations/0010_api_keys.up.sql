@@ -0,0 +1,19 @@
+--
+-- Table structure for table `api_key`
+--
+-- Only the SHA-256 hash of each key is stored, the prefix finds the row
+-- a presented key belongs to.
+--
+
+CREATE TABLE `api_key` (
+  `id` int NOT NULL AUTO_INCREMENT,
+  `name` varchar(255) NOT NULL,
+  `role` varchar(16) NOT NULL,
+  `prefix` varchar(16) NOT NULL,
+  `hash` char(64) NOT NULL,
+  `time_created` DATETIME NOT NULL,
+  `time_revoked` DATETIME NULL,
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `api_key_name` (`name`),
+  UNIQUE KEY `api_key_prefix` (`prefix`)
+) DEFAULT CHARSET=utf8;
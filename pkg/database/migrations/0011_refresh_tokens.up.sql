@@ -0,0 +1,19 @@
+--
+-- Table structure for table `refresh_token`
+--
+-- Like API keys only the SHA-256 hash of each token is stored. Tokens are
+-- revoked once used, a new one being issued in their place.
+--
+
+CREATE TABLE `refresh_token` (
+  `id` int NOT NULL AUTO_INCREMENT,
+  `api_key_id` int NOT NULL,
+  `hash` char(64) NOT NULL,
+  `expires` DATETIME NOT NULL,
+  `time_created` DATETIME NOT NULL,
+  `time_revoked` DATETIME NULL,
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `refresh_token_hash` (`hash`),
+  KEY `refresh_token_api_key_idx` (`api_key_id`),
+  CONSTRAINT `refresh_token_api_key` FOREIGN KEY (`api_key_id`) REFERENCES `api_key` (`id`) ON DELETE CASCADE ON UPDATE CASCADE
+) DEFAULT CHARSET=utf8;
@@ -0,0 +1 @@
+ALTER TABLE `guest` DROP KEY `guest_event_arrival_idx`;
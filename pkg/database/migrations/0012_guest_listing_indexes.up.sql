@@ -0,0 +1,9 @@
+--
+-- Indexes for paging through guest listings
+--
+-- Listings are sorted by name or arrival time with the id breaking ties, the
+-- name already being covered by the `guest_event_name` unique key.
+--
+
+ALTER TABLE `guest`
+  ADD KEY `guest_event_arrival_idx` (`event_id`, `time_arrived`, `id`);
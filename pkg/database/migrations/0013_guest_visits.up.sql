@@ -0,0 +1,25 @@
+--
+-- Table structure for table `visit`
+--
+-- Every check-in starts a visit which the checkout ends, so guests coming
+-- back keep their earlier visits on record. There are no foreign keys so the
+-- visits of deleted guests and tables stay in the history.
+--
+
+CREATE TABLE `visit` (
+  `id` int NOT NULL AUTO_INCREMENT,
+  `event_id` int NOT NULL,
+  `name` varchar(255) NOT NULL,
+  `table_id` int NOT NULL,
+  `accompanying_guests` int NOT NULL,
+  `time_arrived` DATETIME NOT NULL,
+  `time_left` DATETIME NULL,
+  PRIMARY KEY (`id`),
+  KEY `visit_guest_idx` (`event_id`, `name`)
+) DEFAULT CHARSET=utf8;
+
+-- The last visit of guests who arrived is the only one on record
+INSERT INTO `visit` (`event_id`, `name`, `table_id`, `accompanying_guests`, `time_arrived`, `time_left`)
+  SELECT `event_id`, `name`, `table_id`, `accompanying_guests`, `time_arrived`, `time_left`
+  FROM `guest`
+  WHERE `time_arrived` IS NOT NULL;
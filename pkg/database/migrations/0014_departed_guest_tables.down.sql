@@ -0,0 +1,4 @@
+DELETE FROM `guest` WHERE `table_id` NOT IN (SELECT `id` FROM `table`);
+
+ALTER TABLE `guest`
+  ADD CONSTRAINT `guest_table` FOREIGN KEY (`table_id`) REFERENCES `table` (`id`) ON DELETE CASCADE ON UPDATE CASCADE;
@@ -0,0 +1,9 @@
+--
+-- Keep departed guests when their table is deleted
+--
+-- The service removes or moves the guests still expected or seated at a table
+-- it deletes. Departed guests keep the table they sat at, so the table of a
+-- guest has no foreign key.
+--
+
+ALTER TABLE `guest` DROP FOREIGN KEY `guest_table`;
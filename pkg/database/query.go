@@ -0,0 +1,338 @@
+package database
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+var (
+	// ErrInvalidQuery is returned when a Query doesn't hold together, like
+	// After values not matching the OrderBy columns.
+	ErrInvalidQuery = errors.New("invalid query")
+	// ErrUnknownIdentifier is returned when a statement names a table or a
+	// column missing from the schema.
+	ErrUnknownIdentifier = errors.New("unknown identifier")
+)
+
+// Comparison operators of conditions.
+const (
+	opEq        = "="
+	opNe        = "!="
+	opLt        = "<"
+	opLe        = "<="
+	opGt        = ">"
+	opGe        = ">="
+	opLike      = "LIKE"
+	opIsNull    = "IS NULL"
+	opIsNotNull = "IS NOT NULL"
+	opAnd       = "AND"
+	opOr        = "OR"
+)
+
+// Condition is a WHERE clause built with the functions below. Values are
+// always bound to placeholders and columns checked against the schema, so
+// conditions are safe to build from user input. Comparing a column with a
+// nil value never matches, like in SQL; use IsNull instead.
+type Condition struct {
+	op         string
+	column     string
+	value      interface{}
+	conditions []Condition
+}
+
+// Eq matches rows whose column equals value.
+func Eq(column string, value interface{}) Condition {
+	return Condition{op: opEq, column: column, value: value}
+}
+
+// Ne matches rows whose column differs from value.
+func Ne(column string, value interface{}) Condition {
+	return Condition{op: opNe, column: column, value: value}
+}
+
+// Lt matches rows whose column is less than value.
+func Lt(column string, value interface{}) Condition {
+	return Condition{op: opLt, column: column, value: value}
+}
+
+// Le matches rows whose column is at most value.
+func Le(column string, value interface{}) Condition {
+	return Condition{op: opLe, column: column, value: value}
+}
+
+// Gt matches rows whose column is greater than value.
+func Gt(column string, value interface{}) Condition {
+	return Condition{op: opGt, column: column, value: value}
+}
+
+// Ge matches rows whose column is at least value.
+func Ge(column string, value interface{}) Condition {
+	return Condition{op: opGe, column: column, value: value}
+}
+
+// Like matches rows whose column matches the LIKE pattern, where % matches
+// any run of characters, _ any single character and \ escapes either.
+func Like(column string, pattern string) Condition {
+	return Condition{op: opLike, column: column, value: pattern}
+}
+
+// IsNull matches rows whose column is NULL.
+func IsNull(column string) Condition {
+	return Condition{op: opIsNull, column: column}
+}
+
+// IsNotNull matches rows whose column isn't NULL.
+func IsNotNull(column string) Condition {
+	return Condition{op: opIsNotNull, column: column}
+}
+
+// And matches rows matching every condition, all of them when there are
+// none.
+func And(conditions ...Condition) Condition {
+	return Condition{op: opAnd, conditions: conditions}
+}
+
+// Or matches rows matching any of conditions, none when there are none.
+func Or(conditions ...Condition) Condition {
+	return Condition{op: opOr, conditions: conditions}
+}
+
+// Order sorts rows by Column, NULL first unless Desc reverses the order.
+type Order struct {
+	Column string
+	Desc   bool
+}
+
+// Asc sorts rows by column in ascending order.
+func Asc(column string) Order {
+	return Order{Column: column}
+}
+
+// Desc sorts rows by column in descending order.
+func Desc(column string) Order {
+	return Order{Column: column, Desc: true}
+}
+
+// Query selects the rows returned by FindMany. The zero Query returns every
+// row of the table in no particular order, the methods below narrow it
+// down and return the resulting Query.
+type Query struct {
+	where   []Condition
+	orderBy []Order
+	after   []interface{}
+	limit   int
+	offset  int
+}
+
+// Where returns a Query selecting the rows matching every condition.
+func Where(conditions ...Condition) Query {
+	return Query{}.Where(conditions...)
+}
+
+// Where selects the rows that also match every condition.
+func (q Query) Where(conditions ...Condition) Query {
+	q.where = append(q.where[:len(q.where):len(q.where)], conditions...)
+	return q
+}
+
+// OrderBy sorts the rows by each order in turn, after the orders already
+// set.
+func (q Query) OrderBy(orders ...Order) Query {
+	q.orderBy = append(q.orderBy[:len(q.orderBy):len(q.orderBy)], orders...)
+	return q
+}
+
+// After selects the rows sorting after a row whose OrderBy columns hold
+// values, which pages through results without offsets.
+func (q Query) After(values ...interface{}) Query {
+	q.after = values
+	return q
+}
+
+// Limit caps the number of rows returned when n is positive.
+func (q Query) Limit(n int) Query {
+	q.limit = n
+	return q
+}
+
+// Offset skips the first n rows.
+func (q Query) Offset(n int) Query {
+	q.offset = n
+	return q
+}
+
+// condition returns the condition matching the rows selected by the query.
+func (q Query) condition() (Condition, error) {
+	if q.after != nil && len(q.after) != len(q.orderBy) {
+		return Condition{}, fmt.Errorf("%w: %d After values for %d OrderBy columns", ErrInvalidQuery, len(q.after), len(q.orderBy))
+	}
+	if q.limit < 0 || q.offset < 0 {
+		return Condition{}, fmt.Errorf("%w: negative limit or offset", ErrInvalidQuery)
+	}
+
+	conditions := q.where
+	if len(q.after) > 0 {
+		conditions = append(conditions[:len(conditions):len(conditions)], after(q.orderBy, q.after))
+	}
+	return And(conditions...), nil
+}
+
+// after returns the condition matching the rows sorting after values, which
+// is the first column sorting after its value or being equal to it with the
+// remaining columns sorting after theirs. NULL sorts first.
+func after(orderBy []Order, values []interface{}) Condition {
+	order, value := orderBy[0], values[0]
+	null := isNull(value)
+
+	var next Condition
+	switch {
+	case null && order.Desc:
+		next = Or()
+	case null:
+		next = IsNotNull(order.Column)
+	case order.Desc:
+		next = Or(Lt(order.Column, value), IsNull(order.Column))
+	default:
+		next = Gt(order.Column, value)
+	}
+	if len(orderBy) == 1 {
+		return next
+	}
+
+	equal := Eq(order.Column, value)
+	if null {
+		equal = IsNull(order.Column)
+	}
+	return Or(next, And(equal, after(orderBy[1:], values[1:])))
+}
+
+// isNull reports whether v is stored as NULL, like nil pointers are.
+func isNull(v interface{}) bool {
+	value, err := normalizeValue(v)
+	return err == nil && value == nil
+}
+
+// quote quotes a table or column name. Names are checked against the schema
+// before, this only guards against quotes slipping through.
+func quote(name string) string {
+	return "`" + strings.ReplaceAll(name, "`", "``") + "`"
+}
+
+// statement renders the SQL of a statement on a table of the schema,
+// quoting identifiers and binding every value to a placeholder.
+type statement struct {
+	tableName string
+	table     *tableSchema
+	args      []interface{}
+}
+
+func newStatement(tableName string) (*statement, error) {
+	t, err := schemaTable(tableName)
+	if err != nil {
+		return nil, err
+	}
+	return &statement{tableName: tableName, table: t}, nil
+}
+
+// tableSQL returns the quoted name of the table.
+func (s *statement) tableSQL() string {
+	return quote(s.tableName)
+}
+
+// columns returns the quoted names of columns of the table.
+func (s *statement) columns(columns ...string) ([]string, error) {
+	if err := s.table.checkColumns(columns...); err != nil {
+		return nil, err
+	}
+	quoted := make([]string, len(columns))
+	for i, column := range columns {
+		quoted[i] = quote(column)
+	}
+	return quoted, nil
+}
+
+// bind binds value to a placeholder.
+func (s *statement) bind(value interface{}) string {
+	s.args = append(s.args, value)
+	return "?"
+}
+
+// where renders condition.
+func (s *statement) where(c Condition) (string, error) {
+	switch c.op {
+	case opAnd, opOr:
+		if len(c.conditions) == 0 {
+			if c.op == opAnd {
+				return "TRUE", nil
+			}
+			return "FALSE", nil
+		}
+		parts := make([]string, len(c.conditions))
+		for i, condition := range c.conditions {
+			part, err := s.where(condition)
+			if err != nil {
+				return "", err
+			}
+			parts[i] = part
+		}
+		if len(parts) == 1 {
+			return parts[0], nil
+		}
+		return "(" + strings.Join(parts, " "+c.op+" ") + ")", nil
+	case "":
+		return "", fmt.Errorf("%w: empty condition", ErrInvalidQuery)
+	}
+
+	columns, err := s.columns(c.column)
+	if err != nil {
+		return "", err
+	}
+	switch c.op {
+	case opIsNull, opIsNotNull:
+		return columns[0] + " " + c.op, nil
+	}
+	return columns[0] + " " + c.op + " " + s.bind(c.value), nil
+}
+
+// selectSQL renders a SELECT of the rows of query.
+func (s *statement) selectSQL(query Query) (string, error) {
+	condition, err := query.condition()
+	if err != nil {
+		return "", err
+	}
+	where, err := s.where(condition)
+	if err != nil {
+		return "", err
+	}
+
+	sql := "SELECT * FROM " + s.tableSQL()
+	if where != "TRUE" {
+		sql += " WHERE " + where
+	}
+	if len(query.orderBy) > 0 {
+		orders := make([]string, len(query.orderBy))
+		for i, order := range query.orderBy {
+			columns, err := s.columns(order.Column)
+			if err != nil {
+				return "", err
+			}
+			orders[i] = columns[0]
+			if order.Desc {
+				orders[i] += " DESC"
+			}
+		}
+		sql += " ORDER BY " + strings.Join(orders, ", ")
+	}
+	switch {
+	case query.limit > 0:
+		sql += fmt.Sprintf(" LIMIT %d", query.limit)
+	case query.offset > 0:
+		// MySQL only takes an offset after a limit
+		sql += " LIMIT 18446744073709551615"
+	}
+	if query.offset > 0 {
+		sql += fmt.Sprintf(" OFFSET %d", query.offset)
+	}
+	return sql, nil
+}
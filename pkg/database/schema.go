@@ -0,0 +1,129 @@
+package database
+
+import "fmt"
+
+// foreignKey references the refColumn of refTable from column. Rows are
+// deleted along with the row they reference.
+type foreignKey struct {
+	column    string
+	refTable  string
+	refColumn string
+}
+
+// tableSchema describes a table created by the migrations.
+type tableSchema struct {
+	columns     []string
+	defaults    map[string]interface{}
+	unique      [][]string
+	foreignKeys []foreignKey
+}
+
+// schema lists the tables and columns created by the migrations. Statements
+// may only name these, and the in-memory backend enforces their constraints.
+// Migrations changing tables must update it, which TestSchema checks against
+// MySQL.
+var schema = map[string]*tableSchema{
+	"event": {
+		columns:  []string{"id", "name", "venue", "start_time", "end_time"},
+		defaults: map[string]interface{}{"venue": ""},
+	},
+	"table": {
+		columns:  []string{"id", "event_id", "capacity", "reserved_seats", "attributes"},
+		defaults: map[string]interface{}{"reserved_seats": int64(0), "attributes": ""},
+		foreignKeys: []foreignKey{
+			{column: "event_id", refTable: "event", refColumn: "id"},
+		},
+	},
+	"guest": {
+		columns:  []string{"id", "event_id", "name", "table_id", "accompanying_guests", "status", "time_arrived", "time_left"},
+		defaults: map[string]interface{}{"status": "expected"},
+		unique:   [][]string{{"event_id", "name"}},
+		foreignKeys: []foreignKey{
+			{column: "event_id", refTable: "event", refColumn: "id"},
+		},
+	},
+	"waitlist": {
+		columns:  []string{"id", "event_id", "name", "accompanying_guests", "table_id", "status", "time_joined", "time_promoted"},
+		defaults: map[string]interface{}{"status": "waiting"},
+		unique:   [][]string{{"event_id", "name"}},
+		foreignKeys: []foreignKey{
+			{column: "event_id", refTable: "event", refColumn: "id"},
+			{column: "table_id", refTable: "table", refColumn: "id"},
+		},
+	},
+	"seating_constraint": {
+		columns: []string{"id", "event_id", "kind", "guest_id", "other_guest_id", "attribute"},
+		foreignKeys: []foreignKey{
+			{column: "event_id", refTable: "event", refColumn: "id"},
+			{column: "guest_id", refTable: "guest", refColumn: "id"},
+			{column: "other_guest_id", refTable: "guest", refColumn: "id"},
+		},
+	},
+	"webhook": {
+		columns: []string{"id", "event_id", "url", "secret", "event_types", "time_created"},
+		foreignKeys: []foreignKey{
+			{column: "event_id", refTable: "event", refColumn: "id"},
+		},
+	},
+	"webhook_delivery": {
+		columns:  []string{"id", "webhook_id", "event_type", "payload", "status", "attempts", "next_attempt", "time_created", "time_delivered"},
+		defaults: map[string]interface{}{"status": "pending", "attempts": int64(0)},
+		foreignKeys: []foreignKey{
+			{column: "webhook_id", refTable: "webhook", refColumn: "id"},
+		},
+	},
+	"webhook_attempt": {
+		columns: []string{"id", "delivery_id", "status_code", "error", "duration_ms", "time_attempted"},
+		foreignKeys: []foreignKey{
+			{column: "delivery_id", refTable: "webhook_delivery", refColumn: "id"},
+		},
+	},
+	"outbox": {
+		columns:  []string{"id", "event_id", "type", "guest", "table_id", "empty_seats", "status", "attempts", "delivered_to", "last_error", "next_attempt", "time_created", "time_delivered"},
+		defaults: map[string]interface{}{"status": "pending", "attempts": int64(0), "delivered_to": ""},
+	},
+	"audit": {
+		columns: []string{"id", "event_id", "actor", "action", "entity_type", "guest", "table_id", "before_state", "after_state", "time_created"},
+	},
+	"visit": {
+		columns: []string{"id", "event_id", "name", "table_id", "accompanying_guests", "time_arrived", "time_left"},
+	},
+	"api_key": {
+		columns: []string{"id", "name", "role", "prefix", "hash", "time_created", "time_revoked"},
+		unique:  [][]string{{"name"}, {"prefix"}},
+	},
+	"refresh_token": {
+		columns: []string{"id", "api_key_id", "hash", "expires", "time_created", "time_revoked"},
+		unique:  [][]string{{"hash"}},
+		foreignKeys: []foreignKey{
+			{column: "api_key_id", refTable: "api_key", refColumn: "id"},
+		},
+	},
+}
+
+// schemaTable returns the schema of the table called name.
+func schemaTable(name string) (*tableSchema, error) {
+	t, ok := schema[name]
+	if !ok {
+		return nil, fmt.Errorf("%w: table %s", ErrUnknownIdentifier, name)
+	}
+	return t, nil
+}
+
+func (t *tableSchema) hasColumn(column string) bool {
+	for _, c := range t.columns {
+		if c == column {
+			return true
+		}
+	}
+	return false
+}
+
+func (t *tableSchema) checkColumns(columns ...string) error {
+	for _, c := range columns {
+		if !t.hasColumn(c) {
+			return fmt.Errorf("%w: column %s", ErrUnknownIdentifier, c)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,57 @@
+package database
+
+import (
+	"context"
+	"os"
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSchema(t *testing.T) {
+	dsn := os.Getenv("TEST_MYSQL_DSN")
+	if dsn == "" {
+		t.Skip("TEST_MYSQL_DSN is not set")
+	}
+
+	ctx := context.Background()
+	db, err := Open(dsn)
+	assert.Nil(t, err)
+	defer db.Close()
+
+	migrator, err := NewMigrator(db)
+	assert.Nil(t, err)
+	_, err = migrator.Up(ctx)
+	assert.Nil(t, err, "Error while applying migrations, %v", err)
+
+	// Test the schema names every table and column of the migrated database
+	var rows []struct {
+		Table  string `db:"table_name"`
+		Column string `db:"column_name"`
+	}
+	err = db.SelectContext(ctx, &rows, `SELECT table_name AS table_name, column_name AS column_name
+		FROM information_schema.columns
+		WHERE table_schema = DATABASE() AND table_name != 'schema_migrations'`)
+	assert.Nil(t, err, "Error while reading information_schema, %v", err)
+
+	migrated := map[string][]string{}
+	for _, row := range rows {
+		migrated[row.Table] = append(migrated[row.Table], row.Column)
+	}
+
+	var migratedTables, schemaTables []string
+	for name := range migrated {
+		migratedTables = append(migratedTables, name)
+	}
+	for name := range schema {
+		schemaTables = append(schemaTables, name)
+	}
+	sort.Strings(migratedTables)
+	sort.Strings(schemaTables)
+	assert.Equal(t, migratedTables, schemaTables)
+
+	for name, table := range schema {
+		assert.ElementsMatchf(t, migrated[name], table.columns, "columns of table %s", name)
+	}
+}
@@ -0,0 +1,51 @@
+// Package problem writes HTTP error responses as RFC 7807 problem details.
+package problem
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+// ContentType is the media type of a problem details body.
+const ContentType = "application/problem+json"
+
+// Details is the RFC 7807 problem details object. Errors extends it with the
+// fields of the request that were refused, if any.
+type Details struct {
+	Type     string       `json:"type"`
+	Title    string       `json:"title"`
+	Status   int          `json:"status"`
+	Detail   string       `json:"detail,omitempty"`
+	Instance string       `json:"instance,omitempty"`
+	Errors   []FieldError `json:"errors,omitempty"`
+}
+
+// FieldError explains why a field of the request was refused. Field is the
+// name of the field as sent by the client.
+type FieldError struct {
+	Field  string `json:"field"`
+	Detail string `json:"detail"`
+}
+
+// New builds the problem details for status with a human readable detail.
+func New(r *http.Request, status int, detail string) Details {
+	return Details{
+		Type:     "about:blank",
+		Title:    http.StatusText(status),
+		Status:   status,
+		Detail:   detail,
+		Instance: r.URL.Path,
+	}
+}
+
+// Write sends p as the response.
+func Write(w http.ResponseWriter, p interface{}, status int) {
+	w.Header().Set("Content-Type", ContentType)
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(p)
+}
+
+// Error responds to r with a problem details body for status.
+func Error(w http.ResponseWriter, r *http.Request, status int, detail string) {
+	Write(w, New(r, status, detail), status)
+}
@@ -0,0 +1,31 @@
+package problem
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestError(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/guests/john", nil)
+	res := httptest.NewRecorder()
+
+	Error(res, req, http.StatusNotFound, "found no guest called `john`")
+
+	assert.Equal(t, http.StatusNotFound, res.Code)
+	assert.Equal(t, ContentType, res.Header().Get("Content-Type"))
+
+	var body Details
+	err := json.NewDecoder(res.Body).Decode(&body)
+	assert.Nil(t, err, "Error decoding response body, %v", err)
+	assert.Equal(t, Details{
+		Type:     "about:blank",
+		Title:    "Not Found",
+		Status:   http.StatusNotFound,
+		Detail:   "found no guest called `john`",
+		Instance: "/guests/john",
+	}, body)
+}
@@ -0,0 +1,123 @@
+package validate
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/getground/tech-tasks/backend/pkg/problem"
+)
+
+// MaxBodyBytes is the size limit of the JSON request bodies read by Decode.
+const MaxBodyBytes = 1 << 20
+
+// ErrBodyTooLarge is returned when reading more than the limit of a
+// LimitReader.
+var ErrBodyTooLarge = errors.New("request body too large")
+
+// LimitReader returns a reader failing with ErrBodyTooLarge once more than
+// max bytes were read from r.
+func LimitReader(r io.Reader, max int64) io.Reader {
+	return &limitedReader{r: io.LimitReader(r, max+1), max: max}
+}
+
+type limitedReader struct {
+	r    io.Reader
+	max  int64
+	read int64
+}
+
+func (l *limitedReader) Read(p []byte) (int, error) {
+	n, err := l.r.Read(p)
+	l.read += int64(n)
+	if l.read > l.max {
+		return n, ErrBodyTooLarge
+	}
+	return n, err
+}
+
+// Decode reads the JSON body of r into v and checks it with Struct. Bodies
+// larger than MaxBodyBytes are refused, as well as those DecodeReader
+// refuses.
+func Decode(r *http.Request, v interface{}) error {
+	return DecodeReader(LimitReader(r.Body, MaxBodyBytes), v)
+}
+
+// DecodeReader reads the JSON value in rd into v and checks it with Struct.
+// Values holding fields v doesn't have or followed by anything are refused.
+func DecodeReader(rd io.Reader, v interface{}) error {
+	decoder := json.NewDecoder(rd)
+	decoder.DisallowUnknownFields()
+	err := decoder.Decode(v)
+	if err != nil {
+		return decodeError(err)
+	}
+	if _, err := decoder.Token(); err != io.EOF {
+		return fmt.Errorf("request body must hold a single JSON value")
+	}
+	return Struct(v)
+}
+
+// unknownFieldPrefix starts the errors of json.Decoder about fields missing
+// from the value decoded into, which have no type of their own.
+const unknownFieldPrefix = "json: unknown field "
+
+// decodeError names the field at fault in JSON decoding errors.
+func decodeError(err error) error {
+	var typeError *json.UnmarshalTypeError
+	if errors.As(err, &typeError) && typeError.Field != "" {
+		return Errors{{Field: typeError.Field, Detail: "must be " + jsonType(typeError.Type.Kind())}}
+	}
+	if strings.HasPrefix(err.Error(), unknownFieldPrefix) {
+		field, unquoteErr := strconv.Unquote(strings.TrimPrefix(err.Error(), unknownFieldPrefix))
+		if unquoteErr == nil {
+			return Errors{{Field: field, Detail: "is not a known field"}}
+		}
+	}
+	if errors.Is(err, io.EOF) {
+		return fmt.Errorf("request body must not be empty")
+	}
+	return err
+}
+
+// jsonType names the JSON type Go values of kind are decoded from.
+func jsonType(kind reflect.Kind) string {
+	switch kind {
+	case reflect.Bool:
+		return "a boolean"
+	case reflect.String:
+		return "a string"
+	case reflect.Slice, reflect.Array:
+		return "an array"
+	case reflect.Struct, reflect.Map:
+		return "an object"
+	}
+	return "a number"
+}
+
+// Details returns the problem details reporting an error of Decode, Struct
+// or Var, or of reading a LimitReader.
+func Details(r *http.Request, err error) problem.Details {
+	if errors.Is(err, ErrBodyTooLarge) {
+		return problem.New(r, http.StatusRequestEntityTooLarge, err.Error())
+	}
+
+	details := problem.New(r, http.StatusBadRequest, err.Error())
+	var errs Errors
+	if errors.As(err, &errs) {
+		details.Detail = "the request has invalid fields"
+		details.Errors = errs
+	}
+	return details
+}
+
+// Error responds with the problem details reporting err.
+func Error(w http.ResponseWriter, r *http.Request, err error) {
+	details := Details(r, err)
+	problem.Write(w, details, details.Status)
+}
@@ -0,0 +1,191 @@
+// Package validate checks request bodies against the rules declared in the
+// validate tags of their fields and reports every field that breaks one.
+//
+// Rules are separated by commas:
+//
+//	required   strings, pointers and slices must not be empty or nil
+//	min=N      numbers must be at least N, strings and slices at least N long
+//	max=N      numbers must be at most N, strings and slices at most N long
+//	oneof=A B  strings must be one of the space separated values
+//
+// Rules other than required apply to the value pointers point to and are
+// skipped for nil pointers, so optional fields are only checked when sent.
+// Types implementing Validator can add rules spanning several fields.
+package validate
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/getground/tech-tasks/backend/pkg/problem"
+)
+
+// Validator is implemented by types with rules the tags can't express. It
+// is called once the tags of every field passed.
+type Validator interface {
+	Validate() Errors
+}
+
+// Errors lists the fields breaking their rules.
+type Errors []problem.FieldError
+
+func (e Errors) Error() string {
+	msgs := make([]string, len(e))
+	for i, fieldError := range e {
+		msgs[i] = fieldError.Field + " " + fieldError.Detail
+	}
+	return strings.Join(msgs, ", ")
+}
+
+// Struct checks the fields of the struct v points to. It returns Errors when
+// some fields break their rules, and panics on malformed rules.
+func Struct(v interface{}) error {
+	value := reflect.Indirect(reflect.ValueOf(v))
+	if value.Kind() != reflect.Struct {
+		panic(fmt.Sprintf("validate: %T is not a struct", v))
+	}
+
+	errs := checkStruct(value)
+	if len(errs) == 0 {
+		if validator, ok := v.(Validator); ok {
+			errs = validator.Validate()
+		}
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// Var checks a single value called field against rules.
+func Var(field string, v interface{}, rules string) error {
+	errs := checkValue(field, reflect.ValueOf(v), rules)
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+func checkStruct(value reflect.Value) Errors {
+	var errs Errors
+	t := value.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.Anonymous && field.Type.Kind() == reflect.Struct {
+			errs = append(errs, checkStruct(value.Field(i))...)
+			continue
+		}
+
+		rules, ok := field.Tag.Lookup("validate")
+		if !ok {
+			continue
+		}
+		errs = append(errs, checkValue(fieldName(field), value.Field(i), rules)...)
+	}
+	return errs
+}
+
+// fieldName returns the name of field in JSON bodies.
+func fieldName(field reflect.StructField) string {
+	name := strings.Split(field.Tag.Get("json"), ",")[0]
+	if name == "" || name == "-" {
+		return field.Name
+	}
+	return name
+}
+
+func checkValue(field string, value reflect.Value, rules string) Errors {
+	for _, rule := range strings.Split(rules, ",") {
+		name, arg := rule, ""
+		if i := strings.IndexByte(rule, '='); i >= 0 {
+			name, arg = rule[:i], rule[i+1:]
+		}
+
+		if name == "required" {
+			if isEmpty(value) {
+				return Errors{{Field: field, Detail: "is required"}}
+			}
+			continue
+		}
+
+		v := value
+		for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+			if v.IsNil() {
+				return nil
+			}
+			v = v.Elem()
+		}
+
+		detail := checkRule(name, arg, v)
+		if detail != "" {
+			return Errors{{Field: field, Detail: detail}}
+		}
+	}
+	return nil
+}
+
+func isEmpty(value reflect.Value) bool {
+	switch value.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		return value.IsNil()
+	case reflect.String:
+		return strings.TrimSpace(value.String()) == ""
+	case reflect.Slice, reflect.Map:
+		return value.Len() == 0
+	}
+	return false
+}
+
+// checkRule returns why v breaks the rule, or an empty string.
+func checkRule(name string, arg string, v reflect.Value) string {
+	switch name {
+	case "min", "max":
+		limit, err := strconv.ParseInt(arg, 10, 64)
+		if err != nil {
+			panic(fmt.Sprintf("validate: malformed rule %s=%s", name, arg))
+		}
+
+		var n int64
+		unit := ""
+		switch v.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			n = v.Int()
+		case reflect.String:
+			n, unit = int64(utf8.RuneCountInString(v.String())), " characters"
+		case reflect.Slice, reflect.Map:
+			n, unit = int64(v.Len()), " items"
+		default:
+			panic(fmt.Sprintf("validate: rule %s doesn't apply to %s", name, v.Kind()))
+		}
+
+		if name == "min" && n < limit {
+			if unit == "" {
+				return fmt.Sprintf("must be at least %d", limit)
+			}
+			return fmt.Sprintf("must be at least %d%s long", limit, unit)
+		}
+		if name == "max" && n > limit {
+			if unit == "" {
+				return fmt.Sprintf("must be at most %d", limit)
+			}
+			return fmt.Sprintf("must be at most %d%s long", limit, unit)
+		}
+	case "oneof":
+		if v.Kind() != reflect.String {
+			panic(fmt.Sprintf("validate: rule oneof doesn't apply to %s", v.Kind()))
+		}
+		values := strings.Fields(arg)
+		for _, allowed := range values {
+			if v.String() == allowed {
+				return ""
+			}
+		}
+		return "must be one of " + strings.Join(values, ", ")
+	default:
+		panic(fmt.Sprintf("validate: unknown rule %s", name))
+	}
+	return ""
+}
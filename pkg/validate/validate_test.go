@@ -0,0 +1,137 @@
+package validate
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/getground/tech-tasks/backend/pkg/problem"
+	"github.com/stretchr/testify/assert"
+)
+
+type party struct {
+	Name   string `json:"name" validate:"required,max=5"`
+	Size   int    `json:"size" validate:"min=0"`
+	Table  *int   `json:"table" validate:"min=1"`
+	Kind   string `json:"kind" validate:"oneof=vip regular"`
+	Guests []string
+}
+
+type reservation struct {
+	party
+	Seats int `json:"seats"`
+}
+
+func (r reservation) Validate() Errors {
+	if r.Seats < r.Size {
+		return Errors{{Field: "seats", Detail: "must fit the party"}}
+	}
+	return nil
+}
+
+func TestStruct(t *testing.T) {
+	zero, one := 0, 1
+
+	assert.Nil(t, Struct(&party{Name: "john", Table: &one, Kind: "vip"}))
+	assert.Nil(t, Struct(party{Name: "john", Kind: "regular"}), "optional fields are skipped when nil")
+
+	err := Struct(&party{Name: " ", Size: -1, Table: &zero, Kind: "friend"})
+	assert.Equal(t, Errors{
+		{Field: "name", Detail: "is required"},
+		{Field: "size", Detail: "must be at least 0"},
+		{Field: "table", Detail: "must be at least 1"},
+		{Field: "kind", Detail: "must be one of vip, regular"},
+	}, err)
+
+	err = Struct(&party{Name: "johnny", Kind: "vip"})
+	assert.Equal(t, Errors{{Field: "name", Detail: "must be at most 5 characters long"}}, err)
+
+	err = Struct(&reservation{party: party{Name: "john", Size: 3, Kind: "vip"}, Seats: 2})
+	assert.Equal(t, Errors{{Field: "seats", Detail: "must fit the party"}}, err)
+
+	err = Struct(&reservation{party: party{Kind: "vip"}, Seats: -1})
+	assert.Equal(t, Errors{{Field: "name", Detail: "is required"}}, err, "Validate is only called once the tags passed")
+
+	assert.Nil(t, Var("name", "john", "required,max=255"))
+	assert.Equal(t, Errors{{Field: "name", Detail: "is required"}}, Var("name", "", "required,max=255"))
+
+	assert.Panics(t, func() {
+		Struct(&struct {
+			Size int `validate:"min=one"`
+		}{})
+	})
+}
+
+func TestDecode(t *testing.T) {
+	tests := []struct {
+		name   string
+		body   string
+		status int
+		errors []problem.FieldError
+	}{
+		{
+			name:   "valid body",
+			body:   `{"name": "john", "kind": "vip", "size": 2}`,
+			status: http.StatusOK,
+		},
+		{
+			name:   "empty body",
+			body:   "",
+			status: http.StatusBadRequest,
+		},
+		{
+			name:   "unknown field",
+			body:   `{"name": "john", "kind": "vip", "colour": "red"}`,
+			status: http.StatusBadRequest,
+			errors: []problem.FieldError{{Field: "colour", Detail: "is not a known field"}},
+		},
+		{
+			name:   "wrong type",
+			body:   `{"name": "john", "kind": "vip", "size": "two"}`,
+			status: http.StatusBadRequest,
+			errors: []problem.FieldError{{Field: "size", Detail: "must be a number"}},
+		},
+		{
+			name:   "trailing data",
+			body:   `{"name": "john", "kind": "vip"} {}`,
+			status: http.StatusBadRequest,
+		},
+		{
+			name:   "broken rule",
+			body:   `{"name": "john", "kind": "vip", "size": -2}`,
+			status: http.StatusBadRequest,
+			errors: []problem.FieldError{{Field: "size", Detail: "must be at least 0"}},
+		},
+		{
+			name:   "too large",
+			body:   `{"name": "` + strings.Repeat("j", MaxBodyBytes) + `"}`,
+			status: http.StatusRequestEntityTooLarge,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/parties", strings.NewReader(tc.body))
+			res := httptest.NewRecorder()
+
+			var body party
+			err := Decode(req, &body)
+			if tc.status == http.StatusOK {
+				assert.Nil(t, err)
+				return
+			}
+			Error(res, req, err)
+
+			assert.Equal(t, tc.status, res.Code)
+			assert.Equal(t, problem.ContentType, res.Header().Get("Content-Type"))
+
+			var details problem.Details
+			err = json.NewDecoder(res.Body).Decode(&details)
+			assert.Nil(t, err, "Error decoding response body, %v", err)
+			assert.Equal(t, tc.status, details.Status)
+			assert.Equal(t, tc.errors, details.Errors)
+		})
+	}
+}